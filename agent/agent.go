@@ -19,6 +19,20 @@ type Request struct {
 
 	// SessionID is optional - if empty, agent creates new session
 	SessionID string
+
+	// ForkAtEntryID is optional - if set, the agent continues in a new session
+	// forked from SessionID that keeps history up to and including this entry.
+	// To edit or regenerate a message, fork at the entry preceding it.
+	// Requires a session store implementing session.SessionForker.
+	ForkAtEntryID string
+
+	// ParentSessionID is optional - if set, a new session is linked to this
+	// session with the MetadataParentAgentSession metadata. NewAgentTool sets it.
+	ParentSessionID string
+
+	// Resume continues the run interrupted on SessionID from its last
+	// checkpoint; Input may be empty. Requires an engine with checkpointing.
+	Resume bool
 }
 
 // Response represents the agent's response
@@ -37,6 +51,10 @@ type Response struct {
 
 	// Usage contains token and resource usage information
 	Usage Usage
+
+	// Partial is true when the run stopped at MaxIterations before the model
+	// finished (see MaxIterationsPartial)
+	Partial bool
 }
 
 // Usage represents resource usage information
@@ -49,6 +67,12 @@ type Usage struct {
 
 	// SessionWrites tracks session state modifications
 	SessionWrites int
+
+	// Cost of the run in USD, computed from EngineConfig.Pricing
+	Cost float64
+
+	// IterationCosts holds the cost in USD of each LLM call of the run
+	IterationCosts []float64
 }
 
 // TokenUsage tracks token consumption
@@ -56,6 +80,9 @@ type TokenUsage struct {
 	// PromptTokens used for input
 	PromptTokens int
 
+	// CachedPromptTokens is the part of PromptTokens served from the provider's cache
+	CachedPromptTokens int
+
 	// CompletionTokens generated in response
 	CompletionTokens int
 
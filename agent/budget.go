@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+)
+
+// Session state keys accumulating the usage of all runs on a session
+const (
+	StateKeyTotalTokens    = "total_tokens"
+	StateKeyTotalToolCalls = "total_tool_calls"
+	StateKeyTotalDuration  = "total_duration_seconds"
+)
+
+// ErrBudgetExceeded indicates a run was stopped by a budget.
+// The returned error is a *BudgetExceededError.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget limits the resources used by runs. Zero fields are unlimited.
+type Budget struct {
+	// MaxTokens limits the total LLM tokens
+	MaxTokens int
+
+	// MaxCost limits the cost in USD (requires EngineConfig.Pricing)
+	MaxCost float64
+
+	// MaxDuration limits the wall-clock time
+	MaxDuration time.Duration
+
+	// MaxToolCalls limits the number of tool calls
+	MaxToolCalls int
+}
+
+// IsZero reports whether the budget has no limits
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// Budget scopes
+const (
+	BudgetScopeRun     = "run"
+	BudgetScopeSession = "session"
+)
+
+// Budget limits
+const (
+	BudgetLimitTokens    = "tokens"
+	BudgetLimitCost      = "cost"
+	BudgetLimitDuration  = "duration"
+	BudgetLimitToolCalls = "tool_calls"
+)
+
+// BudgetExceededError reports which budget stopped a run
+type BudgetExceededError struct {
+	Scope string  // BudgetScopeRun or BudgetScopeSession
+	Limit string  // one of the BudgetLimit constants
+	Used  float64 // tokens, USD, seconds or tool calls
+	Max   float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s %s budget exceeded: used %g of %g", e.Scope, e.Limit, e.Used, e.Max)
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) match
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// budgetUsage is the usage counted against a budget
+type budgetUsage struct {
+	tokens    int
+	cost      float64
+	duration  time.Duration
+	toolCalls int
+}
+
+func (u budgetUsage) add(other budgetUsage) budgetUsage {
+	return budgetUsage{
+		tokens:    u.tokens + other.tokens,
+		cost:      u.cost + other.cost,
+		duration:  u.duration + other.duration,
+		toolCalls: u.toolCalls + other.toolCalls,
+	}
+}
+
+// budgetTracker enforces the run and session budgets of one run
+type budgetTracker struct {
+	run         Budget
+	session     Budget
+	sessionUsed budgetUsage // usage of earlier runs on the session
+	start       time.Time
+
+	// Largest LLM call so far, used to predict whether another round fits
+	largestCall   budgetUsage
+	largestPrompt int
+}
+
+func newBudgetTracker(run, sessionBudget Budget, agentSession session.Session) *budgetTracker {
+	return &budgetTracker{
+		run:         run,
+		session:     sessionBudget,
+		sessionUsed: sessionUsage(agentSession),
+		start:       time.Now(),
+	}
+}
+
+// observeCall records the size of one LLM call
+func (t *budgetTracker) observeCall(response *llm.Response, cost float64, duration time.Duration) {
+	t.largestCall.tokens = max(t.largestCall.tokens, response.Usage.TotalTokens)
+	t.largestCall.cost = max(t.largestCall.cost, cost)
+	t.largestCall.duration = max(t.largestCall.duration, duration)
+	t.largestPrompt = max(t.largestPrompt, response.Usage.PromptTokens)
+}
+
+// check is called before each LLM call. It returns an error if a budget is
+// used up, or finalAnswer if only one more call without tools fits.
+func (t *budgetTracker) check(usage Usage) (finalAnswer *BudgetExceededError, err error) {
+	runUsed := budgetUsage{
+		tokens:    usage.LLMTokens.TotalTokens,
+		cost:      usage.Cost,
+		duration:  time.Since(t.start),
+		toolCalls: usage.ToolCalls,
+	}
+
+	scopes := []struct {
+		name   string
+		budget Budget
+		used   budgetUsage
+	}{
+		{BudgetScopeRun, t.run, runUsed},
+		{BudgetScopeSession, t.session, t.sessionUsed.add(runUsed)},
+	}
+
+	for _, scope := range scopes {
+		exhausted, low := scope.budget.status(scope.used, t.largestCall)
+		if exhausted != nil {
+			exhausted.Scope = scope.name
+			return nil, exhausted
+		}
+		if low != nil && finalAnswer == nil {
+			low.Scope = scope.name
+			finalAnswer = low
+		}
+	}
+
+	return finalAnswer, nil
+}
+
+// remainingToolCalls returns how many more tool calls fit in the budgets,
+// and the limit that caps them; a negative count means unlimited
+func (t *budgetTracker) remainingToolCalls(usage Usage) (int, *BudgetExceededError) {
+	remaining, limit := -1, (*BudgetExceededError)(nil)
+	for _, scope := range []struct {
+		name string
+		max  int
+		used int
+	}{
+		{BudgetScopeRun, t.run.MaxToolCalls, usage.ToolCalls},
+		{BudgetScopeSession, t.session.MaxToolCalls, t.sessionUsed.toolCalls + usage.ToolCalls},
+	} {
+		if scope.max == 0 {
+			continue
+		}
+		left := max(scope.max-scope.used, 0)
+		if remaining < 0 || left < remaining {
+			remaining = left
+			limit = &BudgetExceededError{Scope: scope.name, Limit: BudgetLimitToolCalls, Used: float64(scope.used), Max: float64(scope.max)}
+		}
+	}
+	return remaining, limit
+}
+
+// deadline returns when the duration budgets run out, if they are limited
+func (t *budgetTracker) deadline() (time.Time, *BudgetExceededError, bool) {
+	var deadline time.Time
+	var limit *BudgetExceededError
+	for _, scope := range []struct {
+		name string
+		max  time.Duration
+		used time.Duration
+	}{
+		{BudgetScopeRun, t.run.MaxDuration, 0},
+		{BudgetScopeSession, t.session.MaxDuration, t.sessionUsed.duration},
+	} {
+		if scope.max == 0 {
+			continue
+		}
+		end := t.start.Add(scope.max - scope.used)
+		if limit == nil || end.Before(deadline) {
+			deadline = end
+			limit = &BudgetExceededError{Scope: scope.name, Limit: BudgetLimitDuration, Used: scope.max.Seconds(), Max: scope.max.Seconds()}
+		}
+	}
+	return deadline, limit, limit != nil
+}
+
+// completionTokens returns how many tokens the final answer may generate
+// within the token budgets, or 0 if they are unlimited
+func (t *budgetTracker) completionTokens(usage Usage) int {
+	remaining := 0
+	for _, limit := range []struct {
+		max  int
+		used int
+	}{
+		{t.run.MaxTokens, usage.LLMTokens.TotalTokens},
+		{t.session.MaxTokens, t.sessionUsed.tokens + usage.LLMTokens.TotalTokens},
+	} {
+		if limit.max == 0 {
+			continue
+		}
+		left := max(limit.max-limit.used-t.largestPrompt, 1)
+		if remaining == 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining
+}
+
+// status compares used against the budget. exhausted is set when a limit
+// is reached; low is set when a tool round would not fit, judged by the
+// largest call so far.
+func (b Budget) status(used, largestCall budgetUsage) (exhausted, low *BudgetExceededError) {
+	switch {
+	case b.MaxTokens > 0 && used.tokens >= b.MaxTokens:
+		return &BudgetExceededError{Limit: BudgetLimitTokens, Used: float64(used.tokens), Max: float64(b.MaxTokens)}, nil
+	case b.MaxCost > 0 && used.cost >= b.MaxCost:
+		return &BudgetExceededError{Limit: BudgetLimitCost, Used: used.cost, Max: b.MaxCost}, nil
+	case b.MaxDuration > 0 && used.duration >= b.MaxDuration:
+		return &BudgetExceededError{Limit: BudgetLimitDuration, Used: used.duration.Seconds(), Max: b.MaxDuration.Seconds()}, nil
+	}
+
+	// A tool round needs this call and the next one
+	switch {
+	case b.MaxToolCalls > 0 && used.toolCalls >= b.MaxToolCalls:
+		low = &BudgetExceededError{Limit: BudgetLimitToolCalls, Used: float64(used.toolCalls), Max: float64(b.MaxToolCalls)}
+	case b.MaxTokens > 0 && used.tokens+2*largestCall.tokens > b.MaxTokens:
+		low = &BudgetExceededError{Limit: BudgetLimitTokens, Used: float64(used.tokens), Max: float64(b.MaxTokens)}
+	case b.MaxCost > 0 && used.cost+2*largestCall.cost > b.MaxCost:
+		low = &BudgetExceededError{Limit: BudgetLimitCost, Used: used.cost, Max: b.MaxCost}
+	case b.MaxDuration > 0 && used.duration+2*largestCall.duration > b.MaxDuration:
+		low = &BudgetExceededError{Limit: BudgetLimitDuration, Used: used.duration.Seconds(), Max: b.MaxDuration.Seconds()}
+	}
+	return nil, low
+}
+
+// checkBudget enforces the budgets before an LLM call. It reports whether a
+// budget is running low, in which case the run should answer right away.
+func (e *engine) checkBudget(ctx context.Context, run *runState) (bool, error) {
+	budgetStop, err := run.budget.check(run.usage)
+	if err != nil {
+		run.logger.WarnContext(ctx, "budget exceeded", slog.Any("error", err))
+		return false, err
+	}
+	if budgetStop == nil {
+		return false, nil
+	}
+
+	if run.budgetStop == nil {
+		run.logger.InfoContext(ctx, "budget running low, asking for a final answer",
+			slog.String("scope", budgetStop.Scope),
+			slog.String("limit", budgetStop.Limit))
+	}
+	run.budgetStop = budgetStop
+	run.stopReason = StopReasonBudget
+	return true, nil
+}
+
+// sessionUsage reads the usage of earlier runs from the session state
+func sessionUsage(agentSession session.Session) budgetUsage {
+	return budgetUsage{
+		tokens:    int(stateNumber(agentSession, StateKeyTotalTokens)),
+		cost:      stateNumber(agentSession, StateKeyTotalCost),
+		duration:  time.Duration(stateNumber(agentSession, StateKeyTotalDuration) * float64(time.Second)),
+		toolCalls: int(stateNumber(agentSession, StateKeyTotalToolCalls)),
+	}
+}
+
+// addSessionUsage adds the usage of a run to the totals in the session state
+func addSessionUsage(agentSession session.Session, usage Usage, duration time.Duration, priced bool) {
+	previous := sessionUsage(agentSession)
+	agentSession.Set(StateKeyTotalTokens, previous.tokens+usage.LLMTokens.TotalTokens)
+	agentSession.Set(StateKeyTotalToolCalls, previous.toolCalls+usage.ToolCalls)
+	agentSession.Set(StateKeyTotalDuration, (previous.duration + duration).Seconds())
+	if priced {
+		agentSession.Set(StateKeyTotalCost, previous.cost+usage.Cost)
+	}
+}
+
+// stateNumber reads a number from the session state. Persistent stores
+// return numbers as float64 after a reload.
+func stateNumber(agentSession session.Session, key string) float64 {
+	value, exists := agentSession.Get(key)
+	if !exists {
+		return 0
+	}
+
+	switch n := value.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
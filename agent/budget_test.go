@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func lookupCall(usage llm.Usage) *llm.Response {
+	return &llm.Response{
+		ToolCalls: []tool.Call{{ID: "1", Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}}},
+		Usage:     usage,
+	}
+}
+
+func newBudgetEngine(t *testing.T, model llm.Model, config EngineConfig) Engine {
+	t.Helper()
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+
+	config.Model = model
+	config.ToolRegistry = registry
+	if config.MaxIterations == 0 {
+		config.MaxIterations = 10
+	}
+	engine, err := NewEngine(config)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	return engine
+}
+
+func TestBudget_ToolCallsAskForFinalAnswer(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		lookupCall(llm.Usage{TotalTokens: 10}),
+		// Tool calls in the final answer are ignored
+		{Content: "partial answer", ToolCalls: []tool.Call{{ID: "2"}}, FinishReason: "tool_calls"},
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{RunBudget: Budget{MaxToolCalls: 1}})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "partial answer" {
+		t.Errorf("Expected output 'partial answer', got %q", response.Output)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("Expected 2 LLM calls, got %d", len(model.requests))
+	}
+	final := model.requests[1]
+	if final.Tools != nil {
+		t.Errorf("Expected no tools in the final answer call, got %d", len(final.Tools))
+	}
+	if last := final.Messages[len(final.Messages)-1]; last.Role != "system" || last.Content != finalAnswerPrompt {
+		t.Errorf("Expected final answer instruction, got %+v", last)
+	}
+	if response.Metadata["stop_reason"] != "budget" || response.Metadata["budget_limit"] != BudgetLimitToolCalls {
+		t.Errorf("Expected budget stop metadata, got %v", response.Metadata)
+	}
+}
+
+func TestBudget_TokensLowCapsFinalAnswer(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		lookupCall(llm.Usage{PromptTokens: 300, CompletionTokens: 100, TotalTokens: 400}),
+		{Content: "short answer", FinishReason: "stop"},
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{RunBudget: Budget{MaxTokens: 1000}})
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// A tool round would need about 800 more tokens; 1000 - 400 used - 300 prompt remain
+	final := model.requests[1]
+	if final.MaxTokens == nil || *final.MaxTokens != 300 {
+		t.Errorf("Expected MaxTokens 300 for the final answer, got %v", final.MaxTokens)
+	}
+	if final.Tools != nil {
+		t.Error("Expected no tools in the final answer call")
+	}
+}
+
+func TestBudget_TokensExhausted(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		lookupCall(llm.Usage{TotalTokens: 150}),
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{RunBudget: Budget{MaxTokens: 100}})
+
+	_, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Expected *BudgetExceededError, got %T", err)
+	}
+	if budgetErr.Scope != BudgetScopeRun || budgetErr.Limit != BudgetLimitTokens || budgetErr.Used != 150 {
+		t.Errorf("Expected run tokens budget with 150 used, got %+v", budgetErr)
+	}
+}
+
+func TestBudget_SessionAcrossRuns(t *testing.T) {
+	store := memory.NewStore()
+	model := &scriptedModel{responses: []*llm.Response{
+		{Content: "first", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 120}},
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{
+		SessionStore:  store,
+		SessionBudget: Budget{MaxTokens: 100},
+	})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	if total, _ := sess.Get(StateKeyTotalTokens); total != 120 {
+		t.Errorf("Expected 120 session tokens, got %v", total)
+	}
+
+	_, err = engine.Execute(context.Background(), Request{Input: "again", SessionID: response.SessionID})
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Scope != BudgetScopeSession {
+		t.Fatalf("Expected session budget error, got %v", err)
+	}
+	if len(model.requests) != 1 {
+		t.Errorf("Expected no LLM call on an exhausted session, got %d calls", len(model.requests))
+	}
+}
+
+func TestBudget_CostRequiresPricing(t *testing.T) {
+	_, err := NewEngine(EngineConfig{
+		Model:     &MockModel{},
+		RunBudget: Budget{MaxCost: 1},
+	})
+	if err == nil {
+		t.Error("Expected error for cost budget without pricing")
+	}
+}
+
+func TestBudget_ToolCallsCapParallelCalls(t *testing.T) {
+	calls := make([]tool.Call, 3)
+	for i := range calls {
+		calls[i] = tool.Call{ID: fmt.Sprint(i), Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}}
+	}
+	model := &scriptedModel{responses: []*llm.Response{
+		{ToolCalls: calls, Usage: llm.Usage{TotalTokens: 10}},
+		{Content: "done", FinishReason: "stop"},
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{RunBudget: Budget{MaxToolCalls: 2}})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Usage.ToolCalls != 2 {
+		t.Errorf("Expected 2 tool calls within the budget, got %d", response.Usage.ToolCalls)
+	}
+	// The final answer instruction follows the tool results
+	messages := model.requests[1].Messages
+	skipped := messages[len(messages)-2]
+	if skipped.ToolCallID != "2" || !strings.Contains(skipped.Content, "tool_calls budget exceeded") {
+		t.Errorf("Expected the third call to report the budget, got %+v", skipped)
+	}
+}
+
+func TestBudget_DurationStopsHungCall(t *testing.T) {
+	// The model never answers
+	model := &blockingModel{started: make(chan struct{}, 1), release: make(chan struct{})}
+	engine := newBudgetEngine(t, model, EngineConfig{RunBudget: Budget{MaxDuration: 50 * time.Millisecond}})
+
+	start := time.Now()
+	_, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Limit != BudgetLimitDuration {
+		t.Fatalf("Expected duration budget error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the run to stop at its deadline, took %v", elapsed)
+	}
+}
@@ -2,14 +2,20 @@ package agent
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	agentcontext "github.com/davidleitw/go-agent/context"
+	"github.com/davidleitw/go-agent/guardrail"
 	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/pricing"
 	"github.com/davidleitw/go-agent/prompt"
+	"github.com/davidleitw/go-agent/redact"
 	"github.com/davidleitw/go-agent/session"
 	"github.com/davidleitw/go-agent/session/memory"
 	"github.com/davidleitw/go-agent/tool"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Builder provides a fluent interface for constructing agents
@@ -113,6 +119,37 @@ func (b *Builder) WithMaxIterations(max int) *Builder {
 	return b
 }
 
+// WithMaxIterationsPolicy sets what happens when the iteration limit is reached
+func (b *Builder) WithMaxIterationsPolicy(policy MaxIterationsPolicy) *Builder {
+	b.config.MaxIterationsPolicy = policy
+	return b
+}
+
+// WithStrategy sets the strategy that drives each run
+func (b *Builder) WithStrategy(strategy Strategy) *Builder {
+	b.config.Strategy = strategy
+	return b
+}
+
+// WithReflection reviews each answer against a rubric before it is
+// returned, revising it when the review finds problems
+func (b *Builder) WithReflection(reflection Reflection) *Builder {
+	b.config.Reflection = &reflection
+	return b
+}
+
+// WithCheckpointing saves each run to its session after every step so an
+// interrupted run can be resumed with Request.Resume
+func (b *Builder) WithCheckpointing() *Builder {
+	b.config.Checkpointing = true
+	return b
+}
+
+// WithPlanAndExecute is shorthand for WithStrategy(PlanAndExecute(maxRevisions))
+func (b *Builder) WithPlanAndExecute(maxRevisions int) *Builder {
+	return b.WithStrategy(PlanAndExecute(maxRevisions))
+}
+
 // WithTemperature sets the LLM temperature for response generation
 func (b *Builder) WithTemperature(temp float32) *Builder {
 	b.config.Temperature = &temp
@@ -131,6 +168,98 @@ func (b *Builder) WithSessionTTL(ttl time.Duration) *Builder {
 	return b
 }
 
+// WithSessionLocker sets the lock used to serialize executions on the same session
+func (b *Builder) WithSessionLocker(locker session.SessionLocker) *Builder {
+	b.config.SessionLocker = locker
+	return b
+}
+
+// WithSessionLockOptions sets the behavior when a session is already executing
+func (b *Builder) WithSessionLockOptions(opts session.LockOptions) *Builder {
+	b.config.SessionLockOptions = opts
+	return b
+}
+
+// WithRedactor redacts PII from the input, tool results and stored history
+func (b *Builder) WithRedactor(redactor *redact.Redactor) *Builder {
+	b.config.Redactor = redactor
+	return b
+}
+
+// WithLogger sets the logger for structured progress logs (silent by default)
+func (b *Builder) WithLogger(logger *slog.Logger) *Builder {
+	b.config.Logger = logger
+	return b
+}
+
+// WithLogRedaction sets functions rewriting tool arguments and results before
+// they are logged; nil keeps the default
+func (b *Builder) WithLogRedaction(arguments, results LogRedactFunc) *Builder {
+	b.config.LogRedactArguments = arguments
+	b.config.LogRedactResults = results
+	return b
+}
+
+// WithHooks adds lifecycle hooks, called in the order they are added
+func (b *Builder) WithHooks(hooks ...Hook) *Builder {
+	b.config.Hooks = append(b.config.Hooks, hooks...)
+	return b
+}
+
+// WithTracerProvider sets the OpenTelemetry provider used to trace runs
+func (b *Builder) WithTracerProvider(provider trace.TracerProvider) *Builder {
+	b.config.TracerProvider = provider
+	return b
+}
+
+// WithMetrics sets the recorder for LLM, tool, run and session store metrics
+func (b *Builder) WithMetrics(recorder metrics.Recorder) *Builder {
+	b.config.Metrics = recorder
+	return b
+}
+
+// WithPricing sets the price table used to compute the cost of runs
+func (b *Builder) WithPricing(registry *pricing.Registry) *Builder {
+	b.config.Pricing = registry
+	return b
+}
+
+// WithName sets the agent name recorded in history and used by teams
+func (b *Builder) WithName(name string) *Builder {
+	b.config.Name = name
+	return b
+}
+
+// WithHandoffs adds agents of a team this agent may hand the conversation to
+func (b *Builder) WithHandoffs(handoffs ...Handoff) *Builder {
+	b.config.Handoffs = append(b.config.Handoffs, handoffs...)
+	return b
+}
+
+// WithInputGuardrails adds guardrails that check the input before the first LLM call
+func (b *Builder) WithInputGuardrails(guardrails ...guardrail.Guardrail) *Builder {
+	b.config.InputGuardrails = append(b.config.InputGuardrails, guardrails...)
+	return b
+}
+
+// WithOutputGuardrails adds guardrails that check the final answer before it is returned
+func (b *Builder) WithOutputGuardrails(guardrails ...guardrail.Guardrail) *Builder {
+	b.config.OutputGuardrails = append(b.config.OutputGuardrails, guardrails...)
+	return b
+}
+
+// WithRunBudget sets the limits of each run
+func (b *Builder) WithRunBudget(budget Budget) *Builder {
+	b.config.RunBudget = budget
+	return b
+}
+
+// WithSessionBudget sets the limits of all runs on a session together
+func (b *Builder) WithSessionBudget(budget Budget) *Builder {
+	b.config.SessionBudget = budget
+	return b
+}
+
 // WithEngine is not needed in the new design since engine is built from config
 
 // Build constructs the final agent instance
@@ -148,12 +277,19 @@ func (b *Builder) Build() (Agent, error) {
 
 	return &BuiltAgent{
 		engine: engine,
+		name:   b.config.Name,
 	}, nil
 }
 
 // BuiltAgent implements the Agent interface using the configured engine
 type BuiltAgent struct {
 	engine Engine
+	name   string
+}
+
+// Name returns the name set with WithName
+func (a *BuiltAgent) Name() string {
+	return a.name
 }
 
 // Execute runs the agent using the configured engine
@@ -161,6 +297,19 @@ func (a *BuiltAgent) Execute(ctx context.Context, request Request) (*Response, e
 	return a.engine.Execute(ctx, request)
 }
 
+func (a *BuiltAgent) sessionLockerOf() session.SessionLocker {
+	if locked, ok := a.engine.(lockedAgent); ok {
+		return locked.sessionLockerOf()
+	}
+	return nil
+}
+
+func (a *BuiltAgent) useSessionLocker(locker session.SessionLocker) {
+	if locked, ok := a.engine.(lockedAgent); ok {
+		locked.useSessionLocker(locker)
+	}
+}
+
 // Quick builder functions for common patterns
 
 // NewSimpleAgent creates a basic agent with just an LLM
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// StateKeyCheckpoint is the session state key holding the Checkpoint of
+// the run in progress on the session
+const StateKeyCheckpoint = "checkpoint"
+
+// ErrNoCheckpoint indicates a resumed session has no run to resume
+var ErrNoCheckpoint = errors.New("no checkpoint to resume")
+
+// errCheckpointingReflection rejects reflection on an engine with
+// checkpointing, as a checkpoint does not record which draft was reviewed
+var errCheckpointingReflection = errors.New("checkpointing does not support reflection")
+
+// finalError marks an error that a resumed run would hit again
+type finalError struct {
+	error
+}
+
+func (e finalError) Unwrap() error {
+	return e.error
+}
+
+// Checkpoint is the in-flight state of a run, saved to the session after
+// each step so an interrupted run can be resumed with Request.Resume
+type Checkpoint struct {
+	// Input is the user input of the run, after redaction and guardrails
+	Input string `json:"input"`
+
+	// Messages is the conversation sent to the model so far; the first
+	// PromptLength messages are the rendered prompt
+	Messages     []llm.Message `json:"messages"`
+	PromptLength int           `json:"prompt_length"`
+
+	// Iteration is the number of LLM calls made
+	Iteration int `json:"iteration"`
+
+	// LoopIteration is the iteration of the tool loop to continue from. It
+	// differs from Iteration when the strategy made other LLM calls first.
+	LoopIteration int `json:"loop_iteration"`
+
+	// PendingToolCalls were requested by the model but have not completed;
+	// the results of completed calls are in Messages. They are run again on
+	// resume, so a call interrupted mid-way runs twice; tools with side
+	// effects can recognize it by tool.CallID.
+	PendingToolCalls []tool.Call `json:"pending_tool_calls,omitempty"`
+
+	// Usage of the run so far
+	Usage Usage `json:"usage"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetCheckpoint returns the checkpoint of the run in progress on the
+// session, if any
+func GetCheckpoint(sess session.Session) (*Checkpoint, bool) {
+	value, exists := sess.Get(StateKeyCheckpoint)
+	if !exists {
+		return nil, false
+	}
+	if checkpoint, ok := value.(Checkpoint); ok {
+		checkpoint.Messages = slices.Clone(checkpoint.Messages)
+		return &checkpoint, true
+	}
+
+	// Stores that serialize state return the checkpoint as generic JSON values
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, false
+	}
+	if checkpoint.PromptLength < 0 || checkpoint.PromptLength > len(checkpoint.Messages) {
+		return nil, false
+	}
+	return &checkpoint, true
+}
+
+// saveCheckpoint stores the state of the run in the session and persists
+// it. Only a version conflict fails the run; other errors are logged.
+func (e *engine) saveCheckpoint(ctx context.Context, run *runState, checkpoint Checkpoint) error {
+	if !e.checkpointing {
+		return nil
+	}
+
+	checkpoint.Input = run.request.Input
+	checkpoint.Messages = slices.Clone(checkpoint.Messages)
+	checkpoint.Usage = run.usage
+	checkpoint.UpdatedAt = time.Now()
+	run.session.Set(StateKeyCheckpoint, checkpoint)
+
+	saveStart := time.Now()
+	err := e.sessionStore.Save(ctx, run.session)
+	e.metrics.RecordStoreOperation(metrics.StoreSave, metrics.Status(err), time.Since(saveStart))
+	if errors.Is(err, session.ErrVersionConflict) {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err != nil {
+		run.logger.WarnContext(ctx, "failed to save checkpoint", slog.Any("error", err))
+		return nil
+	}
+	run.usage.SessionWrites++
+
+	run.logger.DebugContext(ctx, "checkpoint saved",
+		slog.Int("iteration", checkpoint.Iteration),
+		slog.Int("loop_iteration", checkpoint.LoopIteration),
+		slog.Int("messages", len(checkpoint.Messages)),
+		slog.Int("pending_tool_calls", len(checkpoint.PendingToolCalls)))
+	return nil
+}
+
+// runCheckpointedToolCalls runs the pending tool calls of checkpoint and
+// returns their result messages. With checkpointing, the calls run one at a
+// time and each finished call is checkpointed, so a resumed run only
+// repeats the call that was interrupted.
+func (e *engine) runCheckpointedToolCalls(ctx context.Context, run *runState, checkpoint Checkpoint) ([]llm.Message, error) {
+	if !e.checkpointing {
+		messages, _ := e.runToolCalls(ctx, run, checkpoint.PendingToolCalls)
+		return messages, nil
+	}
+
+	var messages []llm.Message
+	checkpoint.Messages = slices.Clone(checkpoint.Messages)
+	for len(checkpoint.PendingToolCalls) > 0 {
+		toolMessages, _ := e.runToolCalls(ctx, run, checkpoint.PendingToolCalls[:1])
+		messages = append(messages, toolMessages...)
+		checkpoint.Messages = append(checkpoint.Messages, toolMessages...)
+		checkpoint.PendingToolCalls = checkpoint.PendingToolCalls[1:]
+		if err := e.saveCheckpoint(ctx, run, checkpoint); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// resumable reports whether a run that failed with err can continue from
+// its checkpoint. Runs stopped by a budget, the iteration limit, a hook veto
+// or a guardrail would stop again.
+func resumable(err error) bool {
+	var final finalError
+	return !errors.Is(err, ErrBudgetExceeded) &&
+		!errors.Is(err, ErrMaxIterationsExceeded) &&
+		!errors.Is(err, ErrHookVetoed) &&
+		!errors.Is(err, ErrInvalidPlan) &&
+		!errors.As(err, &final)
+}
+
+// clearCheckpoint removes the checkpoint of a run that cannot be resumed
+// and persists the session. Errors are logged.
+func (e *engine) clearCheckpoint(ctx context.Context, run *runState) {
+	if !e.checkpointing {
+		return
+	}
+	if _, exists := run.session.Get(StateKeyCheckpoint); !exists {
+		return
+	}
+
+	run.session.Delete(StateKeyCheckpoint)
+	saveStart := time.Now()
+	err := e.sessionStore.Save(ctx, run.session)
+	e.metrics.RecordStoreOperation(metrics.StoreSave, metrics.Status(err), time.Since(saveStart))
+	if err != nil {
+		run.logger.WarnContext(ctx, "failed to clear checkpoint", slog.Any("error", err))
+		return
+	}
+	run.usage.SessionWrites++
+}
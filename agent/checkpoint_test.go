@@ -0,0 +1,329 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// crashingModel fails every call after the scripted responses run out
+type crashingModel struct {
+	scriptedModel
+}
+
+func (m *crashingModel) Complete(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	if len(m.responses) == 0 {
+		m.requests = append(m.requests, request)
+		return nil, errors.New("connection reset")
+	}
+	return m.scriptedModel.Complete(ctx, request)
+}
+
+// countingTool counts its calls
+type countingTool struct {
+	MockTool
+	calls int
+}
+
+func (c *countingTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	c.calls++
+	return c.MockTool.Execute(ctx, params)
+}
+
+// crashingTool records the call IDs it sees and crashes the process, by
+// panicking, the first time it runs the call crashOn
+type crashingTool struct {
+	MockTool
+	crashOn string
+	callIDs []string
+}
+
+func (c *crashingTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	id, _ := tool.CallID(ctx)
+	c.callIDs = append(c.callIDs, id)
+	if id == c.crashOn {
+		c.crashOn = ""
+		panic("process killed")
+	}
+	return c.MockTool.Execute(ctx, params)
+}
+
+func newCheckpointEngine(t *testing.T, model llm.Model, store session.SessionStore, lookup tool.Tool) Engine {
+	t.Helper()
+	registry := tool.NewRegistry()
+	registry.Register(lookup)
+	engine, err := NewEngine(EngineConfig{
+		Model:         model,
+		SessionStore:  store,
+		ToolRegistry:  registry,
+		Checkpointing: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	return engine
+}
+
+func TestCheckpoint_ResumeAfterCrash(t *testing.T) {
+	store := memory.NewStore()
+	lookup := &countingTool{MockTool: MockTool{name: "lookup"}}
+	model := &crashingModel{scriptedModel{responses: []*llm.Response{lookupCall(llm.Usage{TotalTokens: 10})}}}
+
+	sess := store.Create(context.Background())
+	engine := newCheckpointEngine(t, model, store, lookup)
+	if _, err := engine.Execute(context.Background(), Request{Input: "find it", SessionID: sess.ID()}); err == nil {
+		t.Fatal("Expected the crashed run to fail")
+	}
+
+	saved, _ := store.Get(context.Background(), sess.ID())
+	checkpoint, ok := GetCheckpoint(saved)
+	if !ok {
+		t.Fatal("Expected a checkpoint after the crash")
+	}
+	if checkpoint.Input != "find it" || checkpoint.Iteration != 1 || checkpoint.LoopIteration != 1 || len(checkpoint.PendingToolCalls) != 0 {
+		t.Errorf("Expected checkpoint after the tool call, got %+v", checkpoint)
+	}
+
+	model.responses = []*llm.Response{{Content: "found", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 5}}}
+	requests := len(model.requests)
+	response, err := engine.Execute(context.Background(), Request{SessionID: sess.ID(), Resume: true})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	if response.Output != "found" {
+		t.Errorf("Expected output 'found', got %q", response.Output)
+	}
+	if len(model.requests) != requests+1 {
+		t.Errorf("Expected 1 LLM call on resume, got %d", len(model.requests)-requests)
+	}
+	if lookup.calls != 1 {
+		t.Errorf("Expected the tool to run once, got %d", lookup.calls)
+	}
+	if last := model.requests[requests].Messages; last[len(last)-1].Role != "tool" {
+		t.Errorf("Expected the resumed call to see the tool result, got %+v", last)
+	}
+	if response.Usage.LLMTokens.TotalTokens != 15 {
+		t.Errorf("Expected usage of the whole run, got %+v", response.Usage)
+	}
+
+	saved, _ = store.Get(context.Background(), sess.ID())
+	if _, ok := GetCheckpoint(saved); ok {
+		t.Error("Expected the checkpoint to be cleared after the run")
+	}
+	history := saved.GetHistory(10)
+	if len(history) != 2 {
+		t.Fatalf("Expected user and assistant entries, got %d", len(history))
+	}
+	if content, _ := session.GetMessageContent(history[1]); content.Text != "find it" {
+		t.Errorf("Expected the original input in history, got %+v", content)
+	}
+}
+
+func TestCheckpoint_ResumeRunsPendingTools(t *testing.T) {
+	store := memory.NewStore()
+	lookup := &countingTool{MockTool: MockTool{name: "lookup"}}
+	model := &scriptedModel{responses: []*llm.Response{{Content: "found", FinishReason: "stop"}}}
+	call := lookupCall(llm.Usage{}).ToolCalls
+
+	sess := store.Create(context.Background())
+	sess.Set(StateKeyCheckpoint, Checkpoint{
+		Input:            "find it",
+		Messages:         []llm.Message{{Role: "user", Content: "find it"}, {Role: "assistant", Content: " ", ToolCalls: call}},
+		PromptLength:     1,
+		Iteration:        1,
+		PendingToolCalls: call,
+	})
+	if err := store.Save(context.Background(), sess); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	engine := newCheckpointEngine(t, model, store, lookup)
+	response, err := engine.Execute(context.Background(), Request{SessionID: sess.ID(), Resume: true})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	if response.Output != "found" {
+		t.Errorf("Expected output 'found', got %q", response.Output)
+	}
+	if lookup.calls != 1 {
+		t.Errorf("Expected the pending tool call to run, got %d calls", lookup.calls)
+	}
+	messages := model.requests[0].Messages
+	if len(messages) != 3 || messages[2].Role != "tool" {
+		t.Errorf("Expected prompt, tool call and result, got %+v", messages)
+	}
+}
+
+func TestCheckpoint_NoCheckpoint(t *testing.T) {
+	store := memory.NewStore()
+	sess := store.Create(context.Background())
+	engine := newCheckpointEngine(t, &scriptedModel{}, store, &MockTool{name: "lookup"})
+
+	_, err := engine.Execute(context.Background(), Request{SessionID: sess.ID(), Resume: true})
+	if !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("Expected ErrNoCheckpoint, got %v", err)
+	}
+
+	_, err = engine.Execute(context.Background(), Request{Resume: true})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput without SessionID, got %v", err)
+	}
+}
+
+func TestCheckpoint_ResumeSkipsFinishedToolCalls(t *testing.T) {
+	store := memory.NewStore()
+	lookup := &crashingTool{MockTool: MockTool{name: "lookup"}, crashOn: "2"}
+	model := &scriptedModel{responses: []*llm.Response{
+		{ToolCalls: []tool.Call{
+			{ID: "1", Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}},
+			{ID: "2", Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}},
+		}},
+		{Content: "found", FinishReason: "stop"},
+	}}
+
+	sess := store.Create(context.Background())
+	engine := newCheckpointEngine(t, model, store, lookup)
+	func() {
+		defer func() { _ = recover() }()
+		engine.Execute(context.Background(), Request{Input: "find it", SessionID: sess.ID()})
+	}()
+
+	saved, _ := store.Get(context.Background(), sess.ID())
+	checkpoint, ok := GetCheckpoint(saved)
+	if !ok {
+		t.Fatal("Expected a checkpoint after the crash")
+	}
+	if len(checkpoint.PendingToolCalls) != 1 || checkpoint.PendingToolCalls[0].ID != "2" {
+		t.Errorf("Expected only call 2 pending, got %+v", checkpoint.PendingToolCalls)
+	}
+	if last := checkpoint.Messages[len(checkpoint.Messages)-1]; last.Role != "tool" || last.ToolCallID != "1" {
+		t.Errorf("Expected the result of call 1 in the checkpoint, got %+v", last)
+	}
+
+	response, err := engine.Execute(context.Background(), Request{SessionID: sess.ID(), Resume: true})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	if response.Output != "found" {
+		t.Errorf("Expected output 'found', got %q", response.Output)
+	}
+	if fmt.Sprint(lookup.callIDs) != "[1 2 2]" {
+		t.Errorf("Expected only the interrupted call to run again, got calls %v", lookup.callIDs)
+	}
+	messages := model.requests[1].Messages
+	if len(messages) < 3 || messages[len(messages)-2].ToolCallID != "1" || messages[len(messages)-1].ToolCallID != "2" {
+		t.Errorf("Expected both tool results in order, got %+v", messages)
+	}
+}
+
+func TestCheckpoint_ResumesLoopIteration(t *testing.T) {
+	store := memory.NewStore()
+	model := &scriptedModel{responses: []*llm.Response{{Content: "found", FinishReason: "stop"}}}
+	call := lookupCall(llm.Usage{}).ToolCalls
+
+	// The strategy made more LLM calls than the loop has iterations
+	sess := store.Create(context.Background())
+	sess.Set(StateKeyCheckpoint, Checkpoint{
+		Input:            "find it",
+		Messages:         []llm.Message{{Role: "user", Content: "find it"}, {Role: "assistant", Content: " ", ToolCalls: call}},
+		PromptLength:     1,
+		Iteration:        6,
+		LoopIteration:    1,
+		PendingToolCalls: call,
+	})
+	if err := store.Save(context.Background(), sess); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	engine := newCheckpointEngine(t, model, store, &MockTool{name: "lookup"})
+	response, err := engine.Execute(context.Background(), Request{SessionID: sess.ID(), Resume: true})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if response.Output != "found" {
+		t.Errorf("Expected output 'found', got %q", response.Output)
+	}
+}
+
+func TestCheckpoint_RejectsReflection(t *testing.T) {
+	_, err := NewEngine(EngineConfig{
+		Model:         &scriptedModel{},
+		Checkpointing: true,
+		Reflection:    &Reflection{},
+	})
+	if err == nil {
+		t.Error("Expected checkpointing with reflection to be rejected")
+	}
+
+	_, err = NewEngine(EngineConfig{
+		Model:         &scriptedModel{},
+		Checkpointing: true,
+		Strategy:      Reflexion(ReAct(), 1),
+	})
+	if err == nil {
+		t.Error("Expected checkpointing with a Reflexion strategy to be rejected")
+	}
+}
+
+func TestCheckpoint_ClearedWhenBudgetStopsRun(t *testing.T) {
+	store := memory.NewStore()
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+	engine, err := NewEngine(EngineConfig{
+		Model:         &scriptedModel{responses: []*llm.Response{lookupCall(llm.Usage{TotalTokens: 10})}},
+		SessionStore:  store,
+		ToolRegistry:  registry,
+		Checkpointing: true,
+		RunBudget:     Budget{MaxTokens: 10},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	sess := store.Create(context.Background())
+	_, err = engine.Execute(context.Background(), Request{Input: "find it", SessionID: sess.ID()})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+
+	saved, _ := store.Get(context.Background(), sess.ID())
+	if checkpoint, ok := GetCheckpoint(saved); ok {
+		t.Errorf("Expected the checkpoint to be cleared after a budget stop, got %+v", checkpoint)
+	}
+}
+
+func TestCheckpoint_RejectsWrappedReflection(t *testing.T) {
+	store := memory.NewStore()
+	reflexion := Reflexion(nil, 1)
+	wrapped := StrategyFunc(func(ctx context.Context, run *Run) (string, error) {
+		return reflexion.Execute(ctx, run)
+	})
+	engine, err := NewEngine(EngineConfig{
+		Model:         &scriptedModel{},
+		SessionStore:  store,
+		Checkpointing: true,
+		Strategy:      wrapped,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	sess := store.Create(context.Background())
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello", SessionID: sess.ID()}); !errors.Is(err, errCheckpointingReflection) {
+		t.Errorf("Expected wrapped reflection to be rejected, got %v", err)
+	}
+
+	saved, _ := store.Get(context.Background(), sess.ID())
+	if _, ok := GetCheckpoint(saved); ok {
+		t.Error("Expected no checkpoint to be left for a rejected run")
+	}
+}
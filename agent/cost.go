@@ -0,0 +1,24 @@
+package agent
+
+import (
+	"github.com/davidleitw/go-agent/llm"
+)
+
+// StateKeyTotalCost is the session state key accumulating the cost in USD
+// of all runs on the session
+const StateKeyTotalCost = "total_cost_usd"
+
+// callCost returns the cost in USD of one LLM call to model. The model
+// reported in the response is priced first, then the name of model.
+func (e *engine) callCost(model llm.Model, response *llm.Response) (float64, bool) {
+	for _, model := range []string{response.Model, modelName(model)} {
+		if model == "" {
+			continue
+		}
+		if price, ok := e.pricing.Get(model); ok {
+			usage := response.Usage
+			return price.Cost(usage.PromptTokens, usage.CachedPromptTokens, usage.CompletionTokens), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/pricing"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func TestCost_PerIterationAndRun(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+
+	prices := pricing.NewRegistry()
+	prices.Set("test-model", pricing.Price{Prompt: 1_000, CachedPrompt: 500, Completion: 2_000})
+
+	model := &scriptedModel{responses: []*llm.Response{
+		{
+			ToolCalls: []tool.Call{{ID: "1", Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}}},
+			Usage:     llm.Usage{PromptTokens: 100, CachedPromptTokens: 40, CompletionTokens: 10, TotalTokens: 110},
+		},
+		{
+			Content:      "done",
+			FinishReason: "stop",
+			Model:        "test-model-2024-01-01", // dated snapshot priced by its base name
+			Usage:        llm.Usage{PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220},
+		},
+	}}
+
+	store := memory.NewStore()
+	engine, err := NewEngine(EngineConfig{
+		Model:        namedModel{model},
+		SessionStore: store,
+		ToolRegistry: registry,
+		Pricing:      prices,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// 60 uncached + 40 cached prompt tokens + 10 completion tokens
+	first := (60*1_000.0 + 40*500.0 + 10*2_000.0) / 1_000_000
+	second := (200*1_000.0 + 20*2_000.0) / 1_000_000
+
+	usage := response.Usage
+	if len(usage.IterationCosts) != 2 ||
+		math.Abs(usage.IterationCosts[0]-first) > 1e-9 ||
+		math.Abs(usage.IterationCosts[1]-second) > 1e-9 {
+		t.Errorf("Expected iteration costs [%v %v], got %v", first, second, usage.IterationCosts)
+	}
+	if math.Abs(usage.Cost-(first+second)) > 1e-9 {
+		t.Errorf("Expected run cost %v, got %v", first+second, usage.Cost)
+	}
+	if usage.LLMTokens.CachedPromptTokens != 40 {
+		t.Errorf("Expected 40 cached prompt tokens, got %d", usage.LLMTokens.CachedPromptTokens)
+	}
+
+	// A second run on the same session adds to the session total
+	model.responses = []*llm.Response{{Content: "again", FinishReason: "stop", Usage: llm.Usage{PromptTokens: 1000}}}
+	if _, err := engine.Execute(context.Background(), Request{Input: "again", SessionID: response.SessionID}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	total, _ := sess.Get(StateKeyTotalCost)
+	if want := first + second + 1.0; math.Abs(total.(float64)-want) > 1e-9 {
+		t.Errorf("Expected session cost %v, got %v", want, total)
+	}
+}
+
+func TestCost_WithoutPricing(t *testing.T) {
+	engine, err := NewEngine(EngineConfig{Model: &MockModel{}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Usage.Cost != 0 || response.Usage.IterationCosts != nil {
+		t.Errorf("Expected no cost without pricing, got %v / %v", response.Usage.Cost, response.Usage.IterationCosts)
+	}
+	if _, exists := response.Session.Get(StateKeyTotalCost); exists {
+		t.Error("Expected no session cost without pricing")
+	}
+}
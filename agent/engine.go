@@ -3,15 +3,24 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"time"
 
 	agentcontext "github.com/davidleitw/go-agent/context"
+	"github.com/davidleitw/go-agent/guardrail"
 	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/pricing"
 	"github.com/davidleitw/go-agent/prompt"
+	"github.com/davidleitw/go-agent/redact"
 	"github.com/davidleitw/go-agent/session"
 	"github.com/davidleitw/go-agent/session/memory"
 	"github.com/davidleitw/go-agent/tool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -30,9 +39,12 @@ type engine struct {
 	promptTemplate prompt.Template
 
 	// Configuration
-	maxIterations int
-	temperature   *float32
-	maxTokens     *int
+	maxIterations       int
+	maxIterationsPolicy MaxIterationsPolicy
+	strategy            Strategy
+	checkpointing       bool
+	temperature         *float32
+	maxTokens           *int
 
 	// History configuration
 	historyLimit       int
@@ -41,6 +53,38 @@ type engine struct {
 	// Session configuration
 	sessionTTL       time.Duration
 	cachedCreateOpts []session.CreateOption
+
+	// Concurrency control
+	sessionLocker session.SessionLocker
+	lockOptions   session.LockOptions
+
+	// Privacy
+	redactor *redact.Redactor
+
+	// Logging
+	logger             *slog.Logger
+	logRedactArguments LogRedactFunc
+	logRedactResults   LogRedactFunc
+
+	// Lifecycle hooks
+	hooks hookChain
+
+	// Tracing and metrics
+	tracer  trace.Tracer
+	metrics metrics.Recorder
+
+	// Multi-agent
+	name     string
+	handoffs []Handoff
+
+	// Guardrails
+	inputGuardrails  []guardrail.Guardrail
+	outputGuardrails []guardrail.Guardrail
+
+	// Cost accounting and budgets
+	pricing       *pricing.Registry
+	runBudget     Budget
+	sessionBudget Budget
 }
 
 // NewEngine creates a new engine with the provided configuration
@@ -63,6 +107,57 @@ func NewEngine(config EngineConfig) (Engine, error) {
 		config.MaxIterations = 5
 	}
 
+	if config.Strategy == nil {
+		config.Strategy = DefaultStrategy()
+	}
+	if config.Reflection != nil {
+		config.Strategy = Reflect(config.Strategy, *config.Reflection)
+	}
+	// A checkpoint does not record which draft a reflection pass was on.
+	// Reflection wrapped in a custom strategy is rejected when it runs.
+	if _, ok := config.Strategy.(reflectionStrategy); ok && config.Checkpointing {
+		return nil, errCheckpointingReflection
+	}
+
+	if config.SessionLocker == nil {
+		config.SessionLocker = defaultSessionLocker
+	}
+
+	if err := validateHooks(config.Hooks); err != nil {
+		return nil, err
+	}
+
+	if err := validateHandoffs(config.Handoffs); err != nil {
+		return nil, err
+	}
+
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if config.Metrics == nil {
+		config.Metrics = metrics.NopRecorder{}
+	}
+
+	if (config.RunBudget.MaxCost > 0 || config.SessionBudget.MaxCost > 0) && config.Pricing == nil {
+		return nil, fmt.Errorf("cost budget requires pricing")
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.New(discardHandler{})
+	}
+
+	// Keep redacted data out of the logs as well
+	if config.Redactor != nil {
+		mask := func(value string) string { return config.Redactor.Redact(nil, value) }
+		if config.LogRedactArguments == nil {
+			config.LogRedactArguments = mask
+		}
+		if config.LogRedactResults == nil {
+			config.LogRedactResults = mask
+		}
+	}
+
 	// Set default session TTL if not specified
 	sessionTTL := config.SessionTTL
 	if sessionTTL == 0 {
@@ -82,64 +177,213 @@ func NewEngine(config EngineConfig) (Engine, error) {
 	)
 
 	return &engine{
-		model:              config.Model,
-		sessionStore:       config.SessionStore,
-		toolRegistry:       config.ToolRegistry,
-		contextProviders:   config.ContextProviders,
-		promptTemplate:     config.PromptTemplate,
-		maxIterations:      config.MaxIterations,
-		temperature:        config.Temperature,
-		maxTokens:          config.MaxTokens,
-		historyLimit:       config.HistoryLimit,
-		historyInterceptor: config.HistoryInterceptor,
-		sessionTTL:         sessionTTL,
-		cachedCreateOpts:   createOpts,
+		model:               config.Model,
+		sessionStore:        config.SessionStore,
+		toolRegistry:        config.ToolRegistry,
+		contextProviders:    config.ContextProviders,
+		promptTemplate:      config.PromptTemplate,
+		maxIterations:       config.MaxIterations,
+		maxIterationsPolicy: config.MaxIterationsPolicy,
+		strategy:            config.Strategy,
+		checkpointing:       config.Checkpointing,
+		temperature:         config.Temperature,
+		maxTokens:           config.MaxTokens,
+		historyLimit:        config.HistoryLimit,
+		historyInterceptor:  config.HistoryInterceptor,
+		sessionTTL:          sessionTTL,
+		cachedCreateOpts:    createOpts,
+		sessionLocker:       config.SessionLocker,
+		lockOptions:         config.SessionLockOptions,
+		redactor:            config.Redactor,
+		logger:              config.Logger,
+		logRedactArguments:  config.LogRedactArguments,
+		logRedactResults:    config.LogRedactResults,
+		hooks:               hookChain(config.Hooks),
+		tracer:              config.TracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(version)),
+		metrics:             config.Metrics,
+		pricing:             config.Pricing,
+		name:                config.Name,
+		handoffs:            config.Handoffs,
+		inputGuardrails:     config.InputGuardrails,
+		outputGuardrails:    config.OutputGuardrails,
+		runBudget:           config.RunBudget,
+		sessionBudget:       config.SessionBudget,
 	}, nil
 }
 
+// defaultSessionLocker is shared by all engines without a SessionLocker, so
+// engines that share a store also serialize executions on a session
+var defaultSessionLocker = memory.NewLocker()
+
+// sessionLockerOf returns the locker serializing executions on a session
+func (e *engine) sessionLockerOf() session.SessionLocker {
+	return e.sessionLocker
+}
+
+// useSessionLocker replaces the locker serializing executions on a session
+func (e *engine) useSessionLocker(locker session.SessionLocker) {
+	e.sessionLocker = locker
+}
+
 // Execute implements the core agent execution logic
 func (e *engine) Execute(ctx context.Context, request Request) (*Response, error) {
+	ctx, span := e.tracer.Start(ctx, operationInvokeAgent,
+		trace.WithAttributes(attrOperationName.String(operationInvokeAgent)))
+	defer span.End()
+
 	// Validate input
-	if request.Input == "" {
-		return nil, ErrInvalidInput
+	if request.Input == "" && !request.Resume {
+		return e.fail(ctx, nil, ErrInvalidInput)
+	}
+	if request.ForkAtEntryID != "" && request.SessionID == "" {
+		return e.fail(ctx, nil, fmt.Errorf("%w: ForkAtEntryID requires SessionID", ErrInvalidInput))
 	}
+	if request.Resume && (request.SessionID == "" || request.ForkAtEntryID != "") {
+		return e.fail(ctx, nil, fmt.Errorf("%w: Resume requires SessionID and no ForkAtEntryID", ErrInvalidInput))
+	}
+
+	// Serialize executions that continue the same session.
+	// New and forked sessions get a fresh ID, so they need no lock.
+	if request.SessionID != "" && request.ForkAtEntryID == "" {
+		unlock, err := e.sessionLocker.Lock(ctx, request.SessionID, e.lockOptions)
+		if err != nil {
+			return e.fail(ctx, nil, fmt.Errorf("failed to lock session: %w", err))
+		}
+		defer unlock()
+	}
+
+	response, agentSession, err := e.run(ctx, request)
+	if err != nil {
+		return e.fail(ctx, agentSession, err)
+	}
+
+	span.SetAttributes(
+		attrInputTokens.Int(response.Usage.LLMTokens.PromptTokens),
+		attrOutputTokens.Int(response.Usage.LLMTokens.CompletionTokens),
+		attrToolCalls.Int(response.Usage.ToolCalls),
+	)
 
+	e.hooks.runFinished(ctx, agentSession, response)
+	return response, nil
+}
+
+// fail reports a failed run to the hooks and the trace
+func (e *engine) fail(ctx context.Context, agentSession session.Session, err error) (*Response, error) {
+	recordSpanError(trace.SpanFromContext(ctx), err)
+	e.hooks.runFailed(ctx, agentSession, err)
+	return nil, err
+}
+
+// run executes a validated request, returning the session once it is known
+func (e *engine) run(ctx context.Context, request Request) (*Response, session.Session, error) {
 	// Step 1: Session Management
 	agentSession, err := e.handleSession(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("session handling failed: %w", err)
+		return nil, nil, fmt.Errorf("session handling failed: %w", err)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrConversationID.String(agentSession.ID()))
+
+	created := request.SessionID == "" || request.ForkAtEntryID != ""
+	if err := e.hooks.session(ctx, agentSession, created); err != nil {
+		return nil, agentSession, err
+	}
+
+	// A resumed run continues from its checkpoint; its input was already
+	// redacted and checked, and its prompt rendered
+	if request.Resume {
+		checkpoint, ok := GetCheckpoint(agentSession)
+		if !ok {
+			return nil, agentSession, ErrNoCheckpoint
+		}
+		request.Input = checkpoint.Input
+		result, err := e.execute(ctx, request, nil, agentSession, checkpoint)
+		if err != nil {
+			return nil, agentSession, fmt.Errorf("execution failed: %w", err)
+		}
+		return e.response(agentSession, result), agentSession, nil
+	}
+
+	// Redact the input before it reaches the prompt or the history
+	if e.redactor != nil {
+		request.Input = e.redactor.Redact(agentSession, request.Input)
+	}
+
+	// Input guardrails may rewrite the input or answer it without the model
+	var inputRecords []guardrail.Record
+	if len(e.inputGuardrails) > 0 {
+		outcome, err := e.checkGuardrails(ctx, agentSession, guardrail.StageInput, e.inputGuardrails, request.Input)
+		if err != nil {
+			return nil, agentSession, err
+		}
+		if outcome.Blocked != nil {
+			return blockedResponse(agentSession, outcome), agentSession, nil
+		}
+		request.Input = outcome.Text
+		inputRecords = outcome.Records
 	}
 
 	// Step 2: Context Collection
-	contexts, err := e.gatherContexts(ctx, request, agentSession)
+	gatherCtx, gatherSpan := e.tracer.Start(ctx, "gather_contexts")
+	contexts, err := e.gatherContexts(gatherCtx, request, agentSession)
 	if err != nil {
-		return nil, fmt.Errorf("context gathering failed: %w", err)
+		recordSpanError(gatherSpan, err)
+		gatherSpan.End()
+		return nil, agentSession, fmt.Errorf("context gathering failed: %w", err)
+	}
+	gatherSpan.SetAttributes(attrContexts.Int(len(contexts)))
+	gatherSpan.End()
+	contexts, err = e.hooks.contextsGathered(ctx, agentSession, contexts)
+	if err != nil {
+		return nil, agentSession, err
 	}
 
 	// Step 3: Main Execution Loop
 	// TODO: Implement iterative agent thinking with tool calls
-	result, err := e.executeIterations(ctx, request, contexts, agentSession)
+	result, err := e.execute(ctx, request, contexts, agentSession, nil)
 	if err != nil {
-		return nil, fmt.Errorf("execution failed: %w", err)
+		return nil, agentSession, fmt.Errorf("execution failed: %w", err)
+	}
+
+	if inputRecords != nil {
+		outputRecords, _ := result.Metadata[MetadataGuardrails].([]guardrail.Record)
+		result.Metadata[MetadataGuardrails] = append(inputRecords, outputRecords...)
 	}
 
 	// Step 4: Finalize Response
-	response := &Response{
-		Output:    result.FinalOutput,
+	return e.response(agentSession, result), agentSession, nil
+}
+
+// response builds the Response of an executed run
+func (e *engine) response(agentSession session.Session, result *ExecutionResult) *Response {
+	output := result.FinalOutput
+	if e.redactor != nil {
+		// Give the caller back the values the model only saw as tokens
+		output = redact.Restore(agentSession, output)
+	}
+
+	return &Response{
+		Output:    output,
 		SessionID: result.SessionID,
 		Session:   result.Session,
 		Metadata:  result.Metadata,
 		Usage:     result.Usage,
+		Partial:   result.Partial,
 	}
-
-	return response, nil
 }
 
 // handleSession manages session creation/retrieval
 func (e *engine) handleSession(ctx context.Context, request Request) (session.Session, error) {
 	if request.SessionID == "" {
 		// Create new session with pre-cached options
-		newSession := e.sessionStore.Create(ctx, e.cachedCreateOpts...)
+		createOpts := e.cachedCreateOpts
+		if request.ParentSessionID != "" {
+			createOpts = append(createOpts[:len(createOpts):len(createOpts)],
+				session.WithMetadata(MetadataParentAgentSession, request.ParentSessionID))
+		}
+
+		start := time.Now()
+		newSession := e.sessionStore.Create(ctx, createOpts...)
+		e.metrics.RecordStoreOperation(metrics.StoreCreate, metrics.StatusOK, time.Since(start))
 
 		// Add some dynamic metadata based on request
 		newSession.Set("initial_input_length", len(request.Input))
@@ -148,8 +392,15 @@ func (e *engine) handleSession(ctx context.Context, request Request) (session.Se
 		return newSession, nil
 	}
 
+	// Fork existing session and continue in the branch
+	if request.ForkAtEntryID != "" {
+		return e.forkSession(ctx, request)
+	}
+
 	// Load existing session
+	start := time.Now()
 	existingSession, err := e.sessionStore.Get(ctx, request.SessionID)
+	e.metrics.RecordStoreOperation(metrics.StoreGet, metrics.Status(err), time.Since(start))
 	if err != nil {
 		return nil, ErrSessionNotFound
 	}
@@ -157,6 +408,26 @@ func (e *engine) handleSession(ctx context.Context, request Request) (session.Se
 	return existingSession, nil
 }
 
+// forkSession branches the requested session at request.ForkAtEntryID
+func (e *engine) forkSession(ctx context.Context, request Request) (session.Session, error) {
+	forker, ok := e.sessionStore.(session.SessionForker)
+	if !ok {
+		return nil, session.ErrForkNotSupported
+	}
+
+	start := time.Now()
+	forkedSession, err := forker.Fork(ctx, request.SessionID, request.ForkAtEntryID, e.cachedCreateOpts...)
+	e.metrics.RecordStoreOperation(metrics.StoreFork, metrics.Status(err), time.Since(start))
+	if errors.Is(err, session.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return forkedSession, nil
+}
+
 // gatherContexts collects context from all providers and history
 func (e *engine) gatherContexts(ctx context.Context, request Request, agentSession session.Session) ([]agentcontext.Context, error) {
 	var allContexts []agentcontext.Context
@@ -190,7 +461,7 @@ func (e *engine) gatherContexts(ctx context.Context, request Request, agentSessi
 // extractHistoryContexts extracts and processes history from session
 func (e *engine) extractHistoryContexts(ctx context.Context, agentSession session.Session) ([]agentcontext.Context, error) {
 	// 1. Get raw history entries from session
-	entries := agentSession.GetHistory(e.historyLimit)
+	entries := replayedHistory(agentSession, e.historyLimit)
 	if len(entries) == 0 {
 		return nil, nil
 	}
@@ -204,12 +475,36 @@ func (e *engine) extractHistoryContexts(ctx context.Context, agentSession sessio
 		entries = processedEntries
 	}
 
-	// 3. Convert entries to contexts
+	// 3. Convert entries to contexts, oldest first as they are replayed
+	// (GetHistory returns the newest first)
+	entries = slices.Clone(entries)
+	slices.Reverse(entries)
+
+	// The limit can cut between a tool call and its result; a result
+	// replayed without its call is rejected by the model
+	for len(entries) > 0 && entries[0].Type == session.EntryTypeToolResult {
+		entries = entries[1:]
+	}
 	contexts := e.convertEntriesToContexts(entries)
 
 	return contexts, nil
 }
 
+// replayedHistory returns the newest limit history entries that are
+// replayed to the model, newest first. Thinking entries are never
+// replayed, so they do not count against the limit.
+func replayedHistory(agentSession session.Session, limit int) []session.Entry {
+	for fetch := limit; ; fetch *= 2 {
+		entries := agentSession.GetHistory(fetch)
+		replayed := slices.DeleteFunc(slices.Clone(entries), func(entry session.Entry) bool {
+			return entry.Type == session.EntryTypeThinking
+		})
+		if len(replayed) >= limit || len(entries) < fetch {
+			return replayed[:min(limit, len(replayed))]
+		}
+	}
+}
+
 // convertEntriesToContexts converts session entries to context objects
 func (e *engine) convertEntriesToContexts(entries []session.Entry) []agentcontext.Context {
 	contexts := make([]agentcontext.Context, 0, len(entries))
@@ -273,175 +568,211 @@ type ExecutionResult struct {
 	Session     session.Session
 	Metadata    map[string]any
 	Usage       Usage
+	Partial     bool
 }
 
-// executeIterations runs the main agent thinking loop
-func (e *engine) executeIterations(ctx context.Context, request Request, contexts []agentcontext.Context, agentSession session.Session) (*ExecutionResult, error) {
-	// Initialize execution state
-	var totalUsage Usage
-	var conversationMessages []llm.Message
+// executeIterations runs the main agent thinking loop and returns the answer
+func (e *engine) executeIterations(ctx context.Context, run *runState, messages []llm.Message) (string, error) {
+	logger := run.logger
+	conversationMessages := slices.Clone(messages)
+	promptLength := len(messages)
 	var finalResponse string
+	completed := false
+
+	// Continue an interrupted run where its checkpoint left off. Only the
+	// first loop of the run resumes; a strategy may run the loop again.
+	firstIteration := 0
+	if checkpoint := run.checkpoint; checkpoint != nil {
+		run.checkpoint = nil
+		conversationMessages = slices.Clone(checkpoint.Messages)
+		promptLength = checkpoint.PromptLength
+		firstIteration = checkpoint.LoopIteration
+		if len(checkpoint.PendingToolCalls) > 0 {
+			logger.InfoContext(ctx, "running tool calls pending at the checkpoint",
+				slog.Int("tool_calls", len(checkpoint.PendingToolCalls)))
+			toolMessages, err := e.runCheckpointedToolCalls(ctx, run, Checkpoint{Messages: conversationMessages, PromptLength: promptLength, Iteration: run.iterations, LoopIteration: firstIteration, PendingToolCalls: checkpoint.PendingToolCalls})
+			if err != nil {
+				return "", err
+			}
+			conversationMessages = append(conversationMessages, toolMessages...)
+		}
+	}
 
-	// Step 1: Build initial messages from contexts and user input
-	messages := e.buildLLMMessages(contexts, request)
-	conversationMessages = append(conversationMessages, messages...)
-
-	// Step 2: Main iteration loop
-	for iteration := 0; iteration < e.maxIterations; iteration++ {
+	// Main iteration loop
+	for iteration := firstIteration; iteration < e.maxIterations; iteration++ {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return "", ctx.Err()
 		default:
 		}
 
-		fmt.Printf("🤖 [Iteration %d] Agent thinking...\n", iteration+1)
-
-		// Step 2a: Get available tools
-		tools := e.toolRegistry.GetDefinitions()
-		fmt.Printf("📚 Available tools: %d\n", len(tools))
+		// Enforce budgets
+		budgetStop, err := run.budget.check(run.usage)
+		if err != nil {
+			logger.WarnContext(ctx, "budget exceeded", slog.Any("error", err))
+			return "", err
+		}
+		run.budgetStop = budgetStop
+
+		// Prepare LLM request. Once a budget runs low, or on the last
+		// iteration when summarizing, the model gets no tools and must answer
+		// with what it has.
+		finalAnswer := false
+		switch {
+		case budgetStop != nil:
+			logger.InfoContext(ctx, "budget running low, asking for a final answer",
+				slog.String("scope", budgetStop.Scope),
+				slog.String("limit", budgetStop.Limit))
+			finalAnswer = true
+			run.stopReason = StopReasonBudget
+		case iteration == e.maxIterations-1 && e.maxIterationsPolicy == MaxIterationsSummarize:
+			logger.InfoContext(ctx, "maximum iterations reached, asking for a final answer",
+				slog.Int("max_iterations", e.maxIterations))
+			finalAnswer = true
+			run.stopReason = StopReasonMaxIterations
+		}
 
-		// Step 2b: Prepare LLM request
-		llmRequest := llm.Request{
-			Messages:    conversationMessages,
-			Tools:       tools,
-			Temperature: e.temperature,
-			MaxTokens:   e.maxTokens,
+		var llmRequest llm.Request
+		if finalAnswer {
+			llmRequest = e.finalAnswerRequest(run, conversationMessages)
+		} else {
+			llmRequest = e.toolRequest(conversationMessages)
 		}
 
-		// Step 2c: Call LLM
-		fmt.Printf("💭 Calling LLM with %d messages...\n", len(conversationMessages))
-		response, err := e.model.Complete(ctx, llmRequest)
+		response, err := e.complete(ctx, run, llmRequest)
 		if err != nil {
-			return nil, fmt.Errorf("LLM call failed at iteration %d: %w", iteration, err)
+			return "", err
 		}
 
-		// Step 2d: Update usage tracking
-		totalUsage.LLMTokens.PromptTokens += response.Usage.PromptTokens
-		totalUsage.LLMTokens.CompletionTokens += response.Usage.CompletionTokens
-		totalUsage.LLMTokens.TotalTokens += response.Usage.TotalTokens
-
-		fmt.Printf("📊 Token usage this iteration: %d tokens (prompt: %d, completion: %d)\n",
-			response.Usage.TotalTokens, response.Usage.PromptTokens, response.Usage.CompletionTokens)
-
-		// Step 2e: Process LLM response
-		if len(response.ToolCalls) > 0 {
-			fmt.Printf("🔧 Agent wants to use %d tool(s)\n", len(response.ToolCalls))
-			// Add assistant's response that includes tool calls
-			// Note: When there are tool calls, content might be empty, but we still need the assistant message
-			assistantContent := response.Content
-			if assistantContent == "" && len(response.ToolCalls) > 0 {
-				assistantContent = " " // OpenAI API requires non-empty content
+		// Process LLM response. Tool calls are ignored in the final
+		// answer, as there is no room left to run them.
+		if len(response.ToolCalls) > 0 && !finalAnswer {
+			// A handoff ends the run; the other agent answers the request
+			if call, ok := e.findHandoff(response.ToolCalls); ok {
+				logger.InfoContext(ctx, "handing off", slog.String("agent", call.agent))
+				run.handoff = call
+				break
 			}
-			conversationMessages = append(conversationMessages, llm.Message{
-				Role:      "assistant",
-				Content:   assistantContent,
-				ToolCalls: response.ToolCalls, // Include tool calls in the message
-			})
 
-			// Execute tools and get results
-			toolResults := e.executeTools(ctx, response.ToolCalls)
-			totalUsage.ToolCalls += len(response.ToolCalls)
+			// Checkpoint before and after each tool call, so a resumed run
+			// neither repeats the LLM call nor reruns or skips finished tools
+			conversationMessages = append(conversationMessages, assistantToolMessage(response))
+			checkpoint := Checkpoint{Messages: conversationMessages, PromptLength: promptLength, Iteration: run.iterations, LoopIteration: iteration + 1, PendingToolCalls: response.ToolCalls}
+			if err := e.saveCheckpoint(ctx, run, checkpoint); err != nil {
+				return "", err
+			}
+			toolMessages, err := e.runCheckpointedToolCalls(ctx, run, checkpoint)
+			if err != nil {
+				return "", err
+			}
+			conversationMessages = append(conversationMessages, toolMessages...)
 
-			// Add tool results to conversation
-			for _, result := range toolResults {
-				toolMessage := e.formatToolResult(result)
-				conversationMessages = append(conversationMessages, toolMessage)
+			if err := e.hooks.iterationComplete(ctx, run.session, run.iterations, conversationMessages); err != nil {
+				return "", err
 			}
 
 			// Continue iteration to let LLM process tool results
 			continue
 		}
 
-		// Step 2f: Handle completion (no tool calls)
-		if response.FinishReason == "stop" || response.FinishReason == "length" {
-			fmt.Printf("✅ Agent completed task (reason: %s)\n", response.FinishReason)
-			if response.Content != "" {
-				fmt.Printf("💬 Final response: %s\n", response.Content)
-			}
-			// Agent has completed the task
-			finalResponse = response.Content
-
+		// Handle completion (no tool calls)
+		// Agent has completed the task; no clear finish reason is treated as completion
+		finalResponse = response.Content
+		switch response.FinishReason {
+		case "stop", "length", "":
 			// Add final assistant response to conversation
 			conversationMessages = append(conversationMessages, llm.Message{
 				Role:    "assistant",
 				Content: response.Content,
 			})
-
-			break
 		}
 
-		// Handle other finish reasons
-		if response.FinishReason != "" {
-			finalResponse = response.Content
-			break
+		if err := e.hooks.iterationComplete(ctx, run.session, run.iterations, conversationMessages); err != nil {
+			return "", err
 		}
-
-		// If no clear finish reason, treat as completion
-		finalResponse = response.Content
-		conversationMessages = append(conversationMessages, llm.Message{
-			Role:    "assistant",
-			Content: response.Content,
-		})
+		completed = true
 		break
 	}
 
+	// Out of iterations, keep what the run has so far
+	if !completed && run.handoff == nil && e.maxIterationsPolicy == MaxIterationsPartial {
+		logger.WarnContext(ctx, "maximum iterations reached, returning partial result",
+			slog.Int("max_iterations", e.maxIterations))
+		run.stopReason = StopReasonMaxIterations
+		run.partial = true
+		run.transcript = conversationMessages[promptLength:]
+		finalResponse = lastAssistantText(run.transcript)
+	}
+
 	// Check if we exceeded max iterations
-	if finalResponse == "" {
-		return nil, ErrMaxIterationsExceeded
+	if finalResponse == "" && !run.partial && run.handoff == nil && run.budgetStop == nil {
+		logger.WarnContext(ctx, "maximum iterations exceeded",
+			slog.Int("max_iterations", e.maxIterations),
+			slog.Duration("duration", time.Since(run.start)))
+		return "", ErrMaxIterationsExceeded
 	}
 
-	// Step 3: Save conversation to session
-	err := e.saveConversationToSession(agentSession, request.Input, finalResponse)
-	if err != nil {
-		// Log error but don't fail the entire execution
-		fmt.Printf("Warning: failed to save conversation to session: %v\n", err)
-	} else {
-		totalUsage.SessionWrites = 1
-	}
-
-	// Step 4: Return execution result
-	return &ExecutionResult{
-		FinalOutput: finalResponse,
-		SessionID:   agentSession.ID(),
-		Session:     agentSession,
-		Usage:       totalUsage,
-		Metadata: map[string]any{
-			"total_iterations": len(conversationMessages),
-			"tools_called":     totalUsage.ToolCalls,
-			"completion_time":  time.Now(),
-		},
-	}, nil
+	return finalResponse, nil
 }
 
 // executeTools handles tool execution within an iteration
-func (e *engine) executeTools(ctx context.Context, toolCalls []tool.Call) []ToolResult {
+func (e *engine) executeTools(ctx context.Context, agentSession session.Session, toolCalls []tool.Call) []ToolResult {
 	var results []ToolResult
+	logger := e.logger.With(slog.String("session_id", agentSession.ID()))
 
 	// Execute each tool call
-	for i, call := range toolCalls {
-		fmt.Printf("  🛠️  [Tool %d/%d] Calling: %s\n", i+1, len(toolCalls), call.Function.Name)
-		fmt.Printf("  📋 Arguments: %s\n", call.Function.Arguments)
+	for _, call := range toolCalls {
+		if err := e.hooks.beforeToolCall(ctx, agentSession, &call); err != nil {
+			logger.WarnContext(ctx, "tool call vetoed",
+				slog.String("tool", call.Function.Name),
+				slog.String("tool_call_id", call.ID),
+				slog.Any("error", err))
+			e.metrics.RecordToolCall(call.Function.Name, metrics.OutcomeVetoed, 0)
+			results = append(results, ToolResult{Call: call, Error: err})
+			continue
+		}
+
+		toolLogger := logger.With(
+			slog.String("tool", call.Function.Name),
+			slog.String("tool_call_id", call.ID))
+		toolLogger.DebugContext(ctx, "calling tool",
+			slog.String("arguments", logValue(call.Function.Arguments, e.logRedactArguments)))
+
+		if e.redactor != nil {
+			// Tools need the real values behind redaction tokens
+			call.Function.Arguments = redact.RestoreJSON(agentSession, call.Function.Arguments)
+		}
 
 		// Execute tool using registry
-		result, err := e.toolRegistry.Execute(ctx, call)
+		toolStart := time.Now()
+		toolCtx, toolSpan := e.startToolSpan(ctx, call)
+		run := &toolRun{sessionID: agentSession.ID()}
+		result, err := e.toolRegistry.Execute(withToolRun(toolCtx, run), call)
+		if err != nil {
+			recordSpanError(toolSpan, err)
+		}
+		toolSpan.End()
+		e.recordToolCall(call, err, time.Since(toolStart))
 
 		if err != nil {
-			fmt.Printf("  ❌ Tool execution failed: %v\n", err)
+			toolLogger.WarnContext(ctx, "tool execution failed",
+				slog.Duration("duration", time.Since(toolStart)),
+				slog.Any("error", err))
 		} else {
-			// Truncate result if too long for display
-			resultStr := fmt.Sprintf("%v", result)
-			if len(resultStr) > 200 {
-				resultStr = resultStr[:200] + "..."
-			}
-			fmt.Printf("  ✅ Tool result: %s\n", resultStr)
+			toolLogger.DebugContext(ctx, "tool completed",
+				slog.Duration("duration", time.Since(toolStart)),
+				slog.String("result", logValue(fmt.Sprintf("%v", result), e.logRedactResults)))
 		}
 
 		// Create tool result
 		toolResult := ToolResult{
-			Call:   call,
-			Result: result,
-			Error:  err,
+			Call:            call,
+			Result:          result,
+			Error:           err,
+			Usage:           run.usage,
+			ChildSessionIDs: run.childSessions,
 		}
+		e.hooks.afterToolCall(ctx, agentSession, &toolResult)
 
 		results = append(results, toolResult)
 	}
@@ -462,7 +793,7 @@ func (e *engine) buildLLMMessages(contexts []agentcontext.Context, request Reque
 			return messages
 		}
 		// Fall back to hardcoded format if template fails
-		fmt.Printf("Warning: PromptTemplate render failed: %v, falling back to hardcoded format\n", err)
+		e.logger.Warn("prompt template render failed, falling back to hardcoded format", slog.Any("error", err))
 	}
 
 	// Fallback: hardcoded format (original logic)
@@ -587,12 +918,21 @@ func (e *engine) buildHistoryMessages(contexts []agentcontext.Context) []llm.Mes
 
 		case agentcontext.TypeToolCall:
 			// Tool call context - convert to assistant message with tool calls
-			// Note: This is a placeholder; actual tool call handling happens elsewhere
-			messages = append(messages, llm.Message{
+			message := llm.Message{
 				Role:    "assistant",
 				Content: " ", // OpenAI requires non-empty content
-				// ToolCalls would be populated from metadata if needed
-			})
+			}
+			// Entries saved by the engine carry the call, so the tool result
+			// that follows can be linked to it
+			if id, ok := ctx.Metadata["tool_call_id"].(string); ok {
+				name, _ := ctx.Metadata["tool_name"].(string)
+				arguments, _ := ctx.Metadata["arguments"].(string)
+				message.ToolCalls = []tool.Call{{
+					ID:       id,
+					Function: tool.FunctionCall{Name: name, Arguments: arguments},
+				}}
+			}
+			messages = append(messages, message)
 
 		case agentcontext.TypeToolResult:
 			// Tool result - convert to tool message
@@ -640,14 +980,43 @@ func (e *engine) formatToolResult(result ToolResult) llm.Message {
 	}
 }
 
-// saveConversationToSession saves the user input and agent response to session history
-func (e *engine) saveConversationToSession(agentSession session.Session, userInput, agentResponse string) error {
+// saveConversationToSession saves the user input, tool results and agent
+// response to session history. An empty response is not saved.
+func (e *engine) saveConversationToSession(ctx context.Context, agentSession session.Session, userInput string, toolResults []ToolResult, thinking []session.Entry, agentResponse string) error {
 	// Add user message entry
 	userEntry := session.NewMessageEntry("user", userInput)
 	agentSession.AddEntry(userEntry)
 
-	// Add assistant response entry
+	for _, entry := range e.toolEntries(agentSession, toolResults) {
+		agentSession.AddEntry(entry)
+	}
+
+	for _, entry := range thinking {
+		// Entries are stamped when saved, so history keeps its order
+		entry.Timestamp = time.Now()
+		if e.redactor != nil {
+			entry.Content = e.redactor.Redact(agentSession, entry.Content.(string))
+		}
+		agentSession.AddEntry(entry)
+	}
+
+	if agentResponse == "" {
+		return nil
+	}
+
+	// Add assistant response entry, redacting anything the model echoed back
+	if e.redactor != nil {
+		agentResponse = e.redactor.Redact(agentSession, agentResponse)
+	}
 	assistantEntry := session.NewMessageEntry("assistant", agentResponse)
+	if e.name != "" {
+		// Record which agent answered, and who handed it the conversation
+		assistantEntry.Metadata[MetadataAgent] = e.name
+		agentSession.Set(StateKeyActiveAgent, e.name)
+	}
+	if from, ok := handoffFromContext(ctx); ok {
+		assistantEntry.Metadata[MetadataHandoffFrom] = from.agent
+	}
 	agentSession.AddEntry(assistantEntry)
 
 	// Update session metadata
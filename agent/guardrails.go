@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/davidleitw/go-agent/guardrail"
+	"github.com/davidleitw/go-agent/session"
+)
+
+// Response.Metadata keys set by guardrails
+const (
+	// MetadataGuardrails holds the []guardrail.Record of the run
+	MetadataGuardrails = "guardrails"
+
+	// MetadataGuardrailBlocked holds the name of the guardrail that blocked
+	// the input or output
+	MetadataGuardrailBlocked = "guardrail_blocked"
+)
+
+// checkGuardrails runs guardrails on text, logging a block
+func (e *engine) checkGuardrails(ctx context.Context, agentSession session.Session, stage guardrail.Stage, guardrails []guardrail.Guardrail, text string) (guardrail.Outcome, error) {
+	outcome, err := guardrail.Run(ctx, stage, guardrails, text)
+	if err != nil {
+		return outcome, err
+	}
+
+	if outcome.Blocked != nil {
+		e.logger.InfoContext(ctx, "guardrail blocked "+string(stage),
+			slog.String("session_id", agentSession.ID()),
+			slog.String("guardrail", outcome.Blocked.Guardrail))
+	}
+	return outcome, nil
+}
+
+// blockedResponse answers a blocked input without calling the model
+func blockedResponse(agentSession session.Session, outcome guardrail.Outcome) *Response {
+	return &Response{
+		Output:    outcome.Blocked.Message,
+		SessionID: agentSession.ID(),
+		Session:   agentSession,
+		Metadata: map[string]any{
+			MetadataGuardrails:       outcome.Records,
+			MetadataGuardrailBlocked: outcome.Blocked.Guardrail,
+		},
+	}
+}
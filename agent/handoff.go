@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// StateKeyActiveAgent is the session state key holding the name of the
+// agent that answered last, so a Team continues with it on the next turn
+const StateKeyActiveAgent = "active_agent"
+
+// Response.Metadata and history entry metadata keys for multi-agent runs
+const (
+	// MetadataAgent holds the name of the agent that answered
+	MetadataAgent = "agent"
+
+	// MetadataHandoffTo holds the name of the agent the run handed off to
+	MetadataHandoffTo = "handoff_to"
+
+	// MetadataHandoffReason holds the reason the model gave for the handoff
+	MetadataHandoffReason = "handoff_reason"
+
+	// MetadataHandoffFrom holds the name of the agent that handed off
+	MetadataHandoffFrom = "handoff_from"
+)
+
+// handoffToolPrefix prefixes the synthetic tools that hand off to an agent
+const handoffToolPrefix = "transfer_to_"
+
+var (
+	// ErrUnknownAgent indicates a handoff to an agent that is not in the team
+	ErrUnknownAgent = errors.New("unknown agent")
+
+	// ErrTooManyHandoffs indicates agents kept handing a request to each other
+	ErrTooManyHandoffs = errors.New("too many handoffs")
+)
+
+// Handoff lets the model pass the conversation to another agent. It is
+// offered to the model as a tool named "transfer_to_<Agent>".
+type Handoff struct {
+	// Agent is the name of the agent to hand off to
+	Agent string
+
+	// Description tells the model when to hand off
+	Description string
+}
+
+// handoffCall is a handoff requested by the model
+type handoffCall struct {
+	agent  string
+	reason string
+}
+
+func validateHandoffs(handoffs []Handoff) error {
+	for _, handoff := range handoffs {
+		if handoff.Agent == "" {
+			return fmt.Errorf("handoff requires an agent name")
+		}
+	}
+	return nil
+}
+
+// handoffDefinitions returns the synthetic tools for the handoffs
+func handoffDefinitions(handoffs []Handoff) []tool.Definition {
+	definitions := make([]tool.Definition, 0, len(handoffs))
+	for _, handoff := range handoffs {
+		definitions = append(definitions, tool.Definition{
+			Type: "function",
+			Function: tool.Function{
+				Name:        handoffToolPrefix + handoff.Agent,
+				Description: handoff.Description,
+				Parameters: tool.Parameters{
+					Type: "object",
+					Properties: map[string]tool.Property{
+						"reason": {Type: "string", Description: "Why the conversation is handed off, for the next agent"},
+					},
+				},
+			},
+		})
+	}
+	return definitions
+}
+
+// findHandoff returns the first handoff among the tool calls
+func (e *engine) findHandoff(calls []tool.Call) (*handoffCall, bool) {
+	for _, call := range calls {
+		name, ok := strings.CutPrefix(call.Function.Name, handoffToolPrefix)
+		if !ok {
+			continue
+		}
+		for _, handoff := range e.handoffs {
+			if handoff.Agent != name {
+				continue
+			}
+			var args struct {
+				Reason string `json:"reason"`
+			}
+			_ = json.Unmarshal([]byte(call.Function.Arguments), &args)
+			return &handoffCall{agent: name, reason: args.Reason}, true
+		}
+	}
+	return nil, false
+}
+
+// handoffKey is the context key of the handoff that started a run
+type handoffKey struct{}
+
+// handoffFrom describes the handoff that started a run
+type handoffFrom struct {
+	agent  string
+	reason string
+}
+
+func withHandoffFrom(ctx context.Context, from handoffFrom) context.Context {
+	return context.WithValue(ctx, handoffKey{}, from)
+}
+
+func withoutHandoffFrom(ctx context.Context) context.Context {
+	return context.WithValue(ctx, handoffKey{}, nil)
+}
+
+func handoffFromContext(ctx context.Context) (handoffFrom, bool) {
+	from, ok := ctx.Value(handoffKey{}).(handoffFrom)
+	return from, ok
+}
+
+// handoffMessage tells the receiving agent why it got the conversation
+func handoffMessage(from handoffFrom) llm.Message {
+	content := fmt.Sprintf("The %s agent handed this conversation to you.", from.agent)
+	if from.reason != "" {
+		content += " Reason: " + from.reason
+	}
+	return llm.Message{Role: "system", Content: content}
+}
@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	agentcontext "github.com/davidleitw/go-agent/context"
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// ErrHookVetoed indicates a hook stopped the run or a tool call
+var ErrHookVetoed = errors.New("vetoed by hook")
+
+// Hook observes the engine lifecycle. A hook implements one or more of the
+// interfaces below; the engine detects them by type assertion and calls hooks
+// in the order they were configured. Hooks may mutate the values they are
+// given, and returning an error vetoes the step.
+//
+// The iteration passed to the LLM and iteration hooks is the number of the
+// LLM call within the run, counting from 1 across every strategy, critic
+// and plan call. An iteration hook gets the number of the last LLM call.
+type Hook any
+
+// SessionHook is called once the session is loaded or created.
+// Returning an error aborts the run.
+type SessionHook interface {
+	OnSession(ctx context.Context, sess session.Session, created bool) error
+}
+
+// ContextsHook is called after contexts are gathered and may replace them.
+// Returning an error aborts the run.
+type ContextsHook interface {
+	OnContextsGathered(ctx context.Context, sess session.Session, contexts []agentcontext.Context) ([]agentcontext.Context, error)
+}
+
+// PromptHook is called after the prompt is rendered into messages and may
+// replace them. Returning an error aborts the run.
+type PromptHook interface {
+	OnPromptRendered(ctx context.Context, sess session.Session, messages []llm.Message) ([]llm.Message, error)
+}
+
+// BeforeLLMCallHook is called before each LLM call and may modify the request.
+// Returning an error aborts the run.
+type BeforeLLMCallHook interface {
+	BeforeLLMCall(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error
+}
+
+// AfterLLMCallHook is called after each successful LLM call and may modify
+// the response. Returning an error aborts the run.
+type AfterLLMCallHook interface {
+	AfterLLMCall(ctx context.Context, sess session.Session, iteration int, response *llm.Response) error
+}
+
+// BeforeToolCallHook is called before each tool call and may modify the call.
+// Returning an error skips the tool; the model receives the error as the
+// tool result and the run continues.
+type BeforeToolCallHook interface {
+	BeforeToolCall(ctx context.Context, sess session.Session, call *tool.Call) error
+}
+
+// AfterToolCallHook is called after each tool call and may modify the result
+type AfterToolCallHook interface {
+	AfterToolCall(ctx context.Context, sess session.Session, result *ToolResult)
+}
+
+// IterationHook is called when an iteration completes, with the number of
+// its LLM call and the conversation so far. Returning an error aborts the run.
+type IterationHook interface {
+	OnIterationComplete(ctx context.Context, sess session.Session, iteration int, messages []llm.Message) error
+}
+
+// RunFinishedHook is called when a run succeeds and may modify the response
+type RunFinishedHook interface {
+	OnRunFinished(ctx context.Context, sess session.Session, response *Response)
+}
+
+// RunFailedHook is called when a run fails. sess is nil if the run failed
+// before a session was loaded or created.
+type RunFailedHook interface {
+	OnRunFailed(ctx context.Context, sess session.Session, err error)
+}
+
+// hookChain dispatches lifecycle events to the configured hooks
+type hookChain []Hook
+
+// validateHooks checks that every hook implements at least one hook interface
+func validateHooks(hooks []Hook) error {
+	for i, hook := range hooks {
+		switch hook.(type) {
+		case SessionHook, ContextsHook, PromptHook,
+			BeforeLLMCallHook, AfterLLMCallHook,
+			BeforeToolCallHook, AfterToolCallHook,
+			IterationHook, PlanHook, EventHook, RunFinishedHook, RunFailedHook:
+		default:
+			return fmt.Errorf("hook %d (%T) implements no hook interface", i, hook)
+		}
+	}
+	return nil
+}
+
+func vetoed(err error) error {
+	return fmt.Errorf("%w: %w", ErrHookVetoed, err)
+}
+
+func (c hookChain) session(ctx context.Context, sess session.Session, created bool) error {
+	for _, hook := range c {
+		if h, ok := hook.(SessionHook); ok {
+			if err := h.OnSession(ctx, sess, created); err != nil {
+				return vetoed(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c hookChain) contextsGathered(ctx context.Context, sess session.Session, contexts []agentcontext.Context) ([]agentcontext.Context, error) {
+	for _, hook := range c {
+		if h, ok := hook.(ContextsHook); ok {
+			var err error
+			if contexts, err = h.OnContextsGathered(ctx, sess, contexts); err != nil {
+				return nil, vetoed(err)
+			}
+		}
+	}
+	return contexts, nil
+}
+
+func (c hookChain) promptRendered(ctx context.Context, sess session.Session, messages []llm.Message) ([]llm.Message, error) {
+	for _, hook := range c {
+		if h, ok := hook.(PromptHook); ok {
+			var err error
+			if messages, err = h.OnPromptRendered(ctx, sess, messages); err != nil {
+				return nil, vetoed(err)
+			}
+		}
+	}
+	return messages, nil
+}
+
+func (c hookChain) beforeLLMCall(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error {
+	for _, hook := range c {
+		if h, ok := hook.(BeforeLLMCallHook); ok {
+			if err := h.BeforeLLMCall(ctx, sess, iteration, request); err != nil {
+				return vetoed(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c hookChain) afterLLMCall(ctx context.Context, sess session.Session, iteration int, response *llm.Response) error {
+	for _, hook := range c {
+		if h, ok := hook.(AfterLLMCallHook); ok {
+			if err := h.AfterLLMCall(ctx, sess, iteration, response); err != nil {
+				return vetoed(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c hookChain) beforeToolCall(ctx context.Context, sess session.Session, call *tool.Call) error {
+	for _, hook := range c {
+		if h, ok := hook.(BeforeToolCallHook); ok {
+			if err := h.BeforeToolCall(ctx, sess, call); err != nil {
+				return vetoed(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c hookChain) afterToolCall(ctx context.Context, sess session.Session, result *ToolResult) {
+	for _, hook := range c {
+		if h, ok := hook.(AfterToolCallHook); ok {
+			h.AfterToolCall(ctx, sess, result)
+		}
+	}
+}
+
+func (c hookChain) iterationComplete(ctx context.Context, sess session.Session, iteration int, messages []llm.Message) error {
+	for _, hook := range c {
+		if h, ok := hook.(IterationHook); ok {
+			if err := h.OnIterationComplete(ctx, sess, iteration, messages); err != nil {
+				return vetoed(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c hookChain) planUpdated(ctx context.Context, sess session.Session, plan Plan) {
+	for _, hook := range c {
+		if h, ok := hook.(PlanHook); ok {
+			h.OnPlanUpdated(ctx, sess, plan)
+		}
+	}
+}
+
+func (c hookChain) event(ctx context.Context, sess session.Session, event Event) {
+	for _, hook := range c {
+		if h, ok := hook.(EventHook); ok {
+			h.OnEvent(ctx, sess, event)
+		}
+	}
+}
+
+func (c hookChain) runFinished(ctx context.Context, sess session.Session, response *Response) {
+	for _, hook := range c {
+		if h, ok := hook.(RunFinishedHook); ok {
+			h.OnRunFinished(ctx, sess, response)
+		}
+	}
+}
+
+func (c hookChain) runFailed(ctx context.Context, sess session.Session, err error) {
+	for _, hook := range c {
+		if h, ok := hook.(RunFailedHook); ok {
+			h.OnRunFailed(ctx, sess, err)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	agentcontext "github.com/davidleitw/go-agent/context"
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// recordingHook implements every hook interface and records the events
+type recordingHook struct {
+	events    []string
+	failedErr error
+	failedOn  session.Session
+}
+
+func (h *recordingHook) OnSession(ctx context.Context, sess session.Session, created bool) error {
+	if created {
+		h.events = append(h.events, "session created")
+	} else {
+		h.events = append(h.events, "session loaded")
+	}
+	return nil
+}
+
+func (h *recordingHook) OnContextsGathered(ctx context.Context, sess session.Session, contexts []agentcontext.Context) ([]agentcontext.Context, error) {
+	h.events = append(h.events, "contexts")
+	return contexts, nil
+}
+
+func (h *recordingHook) OnPromptRendered(ctx context.Context, sess session.Session, messages []llm.Message) ([]llm.Message, error) {
+	h.events = append(h.events, "prompt")
+	return messages, nil
+}
+
+func (h *recordingHook) BeforeLLMCall(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error {
+	h.events = append(h.events, "before llm")
+	return nil
+}
+
+func (h *recordingHook) AfterLLMCall(ctx context.Context, sess session.Session, iteration int, response *llm.Response) error {
+	h.events = append(h.events, "after llm")
+	return nil
+}
+
+func (h *recordingHook) BeforeToolCall(ctx context.Context, sess session.Session, call *tool.Call) error {
+	h.events = append(h.events, "before tool "+call.Function.Name)
+	return nil
+}
+
+func (h *recordingHook) AfterToolCall(ctx context.Context, sess session.Session, result *ToolResult) {
+	h.events = append(h.events, "after tool "+result.Call.Function.Name)
+}
+
+func (h *recordingHook) OnIterationComplete(ctx context.Context, sess session.Session, iteration int, messages []llm.Message) error {
+	h.events = append(h.events, "iteration")
+	return nil
+}
+
+func (h *recordingHook) OnRunFinished(ctx context.Context, sess session.Session, response *Response) {
+	h.events = append(h.events, "finished")
+}
+
+func (h *recordingHook) OnRunFailed(ctx context.Context, sess session.Session, err error) {
+	h.events = append(h.events, "failed")
+	h.failedErr = err
+	h.failedOn = sess
+}
+
+// beforeLLMCallFunc adapts a function to BeforeLLMCallHook
+type beforeLLMCallFunc func(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error
+
+func (f beforeLLMCallFunc) BeforeLLMCall(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error {
+	return f(ctx, sess, iteration, request)
+}
+
+// toolVeto blocks one tool and rewrites results of the others
+type toolVeto struct {
+	blocked string
+}
+
+func (v toolVeto) BeforeToolCall(ctx context.Context, sess session.Session, call *tool.Call) error {
+	if call.Function.Name == v.blocked {
+		return errors.New("tool not allowed")
+	}
+	return nil
+}
+
+func (v toolVeto) AfterToolCall(ctx context.Context, sess session.Session, result *ToolResult) {
+	result.Result = "rewritten"
+}
+
+func TestHooks_Order(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+	hook := &recordingHook{}
+
+	engine, err := NewEngine(EngineConfig{
+		Model:        toolCallingModel(),
+		ToolRegistry: registry,
+		Hooks:        []Hook{hook},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := []string{
+		"session created", "contexts", "prompt",
+		"before llm", "after llm", "before tool lookup", "after tool lookup", "iteration",
+		"before llm", "after llm", "iteration",
+		"finished",
+	}
+	if !reflect.DeepEqual(hook.events, want) {
+		t.Errorf("Expected events %v, got %v", want, hook.events)
+	}
+}
+
+func TestHooks_VetoLLMCall(t *testing.T) {
+	recorder := &recordingHook{}
+	veto := beforeLLMCallFunc(func(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error {
+		return errors.New("budget exhausted")
+	})
+
+	engine, err := NewEngine(EngineConfig{
+		Model: &MockModel{},
+		Hooks: []Hook{veto, recorder},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	_, err = engine.Execute(context.Background(), Request{Input: "hello"})
+	if !errors.Is(err, ErrHookVetoed) {
+		t.Fatalf("Expected ErrHookVetoed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "budget exhausted") {
+		t.Errorf("Expected hook error in message, got %v", err)
+	}
+
+	// Later hooks are not called once a step is vetoed, but the failure is reported
+	for _, event := range recorder.events {
+		if event == "before llm" {
+			t.Error("Expected later BeforeLLMCall hooks to be skipped")
+		}
+	}
+	if !errors.Is(recorder.failedErr, ErrHookVetoed) || recorder.failedOn == nil {
+		t.Errorf("Expected OnRunFailed with the session, got err=%v session=%v", recorder.failedErr, recorder.failedOn)
+	}
+}
+
+func TestHooks_MutateRequest(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{{Content: "ok", FinishReason: "stop"}}}
+	lowTemperature := float32(0.1)
+	hook := beforeLLMCallFunc(func(ctx context.Context, sess session.Session, iteration int, request *llm.Request) error {
+		request.Temperature = &lowTemperature
+		return nil
+	})
+
+	engine, err := NewEngine(EngineConfig{Model: model, Hooks: []Hook{hook}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := model.requests[0].Temperature; got == nil || *got != lowTemperature {
+		t.Errorf("Expected temperature set by hook, got %v", got)
+	}
+}
+
+func TestHooks_VetoToolCall(t *testing.T) {
+	registry := tool.NewRegistry()
+	lookup := &recordingTool{MockTool: MockTool{name: "lookup"}}
+	registry.Register(lookup)
+	model := toolCallingModel()
+
+	engine, err := NewEngine(EngineConfig{
+		Model:        model,
+		ToolRegistry: registry,
+		Hooks:        []Hook{toolVeto{blocked: "lookup"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Expected run to continue after a vetoed tool, got %v", err)
+	}
+	if lookup.params != nil {
+		t.Error("Expected vetoed tool not to be executed")
+	}
+
+	last := model.requests[1].Messages
+	if content := last[len(last)-1].Content; !strings.Contains(content, "tool not allowed") {
+		t.Errorf("Expected veto reason in tool message, got %q", content)
+	}
+}
+
+func TestHooks_MutateToolResult(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+	model := toolCallingModel()
+
+	engine, err := NewEngine(EngineConfig{
+		Model:        model,
+		ToolRegistry: registry,
+		Hooks:        []Hook{toolVeto{}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	last := model.requests[1].Messages
+	if content := last[len(last)-1].Content; !strings.Contains(content, "rewritten") {
+		t.Errorf("Expected rewritten tool result, got %q", content)
+	}
+}
+
+func TestHooks_RunFailedWithoutSession(t *testing.T) {
+	hook := &recordingHook{}
+	engine, err := NewEngine(EngineConfig{Model: &MockModel{}, Hooks: []Hook{hook}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Expected ErrInvalidInput, got %v", err)
+	}
+	if !errors.Is(hook.failedErr, ErrInvalidInput) || hook.failedOn != nil {
+		t.Errorf("Expected OnRunFailed without a session, got err=%v session=%v", hook.failedErr, hook.failedOn)
+	}
+}
+
+func TestNewEngine_InvalidHook(t *testing.T) {
+	if _, err := NewEngine(EngineConfig{Model: &MockModel{}, Hooks: []Hook{"not a hook"}}); err == nil {
+		t.Error("Expected error for a value implementing no hook interface")
+	}
+}
+
+// iterationNumbers records the iteration passed to the LLM and iteration hooks
+type iterationNumbers struct {
+	events []string
+}
+
+func (h *iterationNumbers) AfterLLMCall(ctx context.Context, sess session.Session, iteration int, response *llm.Response) error {
+	h.events = append(h.events, fmt.Sprintf("llm %d", iteration))
+	return nil
+}
+
+func (h *iterationNumbers) OnIterationComplete(ctx context.Context, sess session.Session, iteration int, messages []llm.Message) error {
+	h.events = append(h.events, fmt.Sprintf("iteration %d", iteration))
+	return nil
+}
+
+func TestHooks_IterationMatchesLLMCall(t *testing.T) {
+	// The revision runs the loop again after the critic's call
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse("draft"),
+		textResponse("too short"),
+		textResponse("second draft"),
+		textResponse("still too short"),
+	}}
+	hook := &iterationNumbers{}
+	agent, err := NewBuilder().WithLLM(model).WithStrategy(Reflexion(nil, 1)).WithHooks(hook).Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	if _, err := agent.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	expected := []string{"llm 1", "iteration 1", "llm 2", "llm 3", "iteration 3", "llm 4"}
+	if fmt.Sprint(hook.events) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, hook.events)
+	}
+}
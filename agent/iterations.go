@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/redact"
+	"github.com/davidleitw/go-agent/session"
+)
+
+// MaxIterationsPolicy decides what a run does when it reaches MaxIterations
+type MaxIterationsPolicy int
+
+const (
+	// MaxIterationsFail returns ErrMaxIterationsExceeded and keeps nothing
+	// of the run (default)
+	MaxIterationsFail MaxIterationsPolicy = iota
+
+	// MaxIterationsSummarize makes the last iteration a call without tools
+	// that asks the model to answer with what it found so far
+	MaxIterationsSummarize
+
+	// MaxIterationsPartial returns the run so far with Response.Partial set
+	// and the messages of the run in Metadata["transcript"]
+	MaxIterationsPartial
+)
+
+// Values of Metadata["stop_reason"] for runs that stopped before the model finished
+const (
+	StopReasonBudget        = "budget"
+	StopReasonMaxIterations = "max_iterations"
+)
+
+// finalAnswerPrompt is added to the last LLM call of a run that has to stop
+const finalAnswerPrompt = "No more tools can be called for this request. " +
+	"Answer the user now with the information gathered so far, and say what is still incomplete."
+
+// toolEntries converts executed tool calls into session entries, so a run
+// that stops early keeps its work for the next one. Arguments and results
+// are stored redacted, like the rest of the history.
+func (e *engine) toolEntries(agentSession session.Session, results []ToolResult) []session.Entry {
+	entries := make([]session.Entry, 0, 2*len(results))
+	for _, result := range results {
+		arguments := result.Call.Function.Arguments
+		var output any = result.Result
+		if e.redactor != nil {
+			arguments = e.redactor.Redact(agentSession, arguments)
+			if output != nil {
+				output = e.redactor.Redact(agentSession, fmt.Sprintf("%v", output))
+			}
+		}
+
+		var params map[string]any
+		if err := json.Unmarshal([]byte(arguments), &params); err != nil {
+			params = map[string]any{"arguments": arguments}
+		}
+
+		name := result.Call.Function.Name
+		callEntry := session.NewToolCallEntry(name, params)
+		callEntry.Metadata["tool_call_id"] = result.Call.ID
+		callEntry.Metadata["arguments"] = arguments
+		resultEntry := session.NewToolResultEntry(name, output, result.Error)
+		resultEntry.Metadata["tool_call_id"] = result.Call.ID
+
+		entries = append(entries, callEntry, resultEntry)
+	}
+	return entries
+}
+
+// transcript returns the messages a run added after its prompt, with
+// redaction tokens restored
+func (e *engine) transcript(agentSession session.Session, messages []llm.Message) []llm.Message {
+	transcript := make([]llm.Message, len(messages))
+	copy(transcript, messages)
+	if e.redactor != nil {
+		for i := range transcript {
+			transcript[i].Content = redact.Restore(agentSession, transcript[i].Content)
+		}
+	}
+	return transcript
+}
+
+// lastAssistantText returns the last non-blank assistant message content
+func lastAssistantText(messages []llm.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && strings.TrimSpace(messages[i].Content) != "" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func newLimitedEngine(t *testing.T, model llm.Model, store session.SessionStore, policy MaxIterationsPolicy) Engine {
+	t.Helper()
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+
+	engine, err := NewEngine(EngineConfig{
+		Model:               model,
+		SessionStore:        store,
+		ToolRegistry:        registry,
+		MaxIterations:       2,
+		MaxIterationsPolicy: policy,
+		HistoryLimit:        20,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	return engine
+}
+
+func countEntries(entries []session.Entry, entryType session.EntryType) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Type == entryType {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMaxIterations_Summarize(t *testing.T) {
+	store := memory.NewStore()
+	model := &scriptedModel{responses: []*llm.Response{
+		lookupCall(llm.Usage{}),
+		{Content: "found part of it", FinishReason: "stop"},
+	}}
+	engine := newLimitedEngine(t, model, store, MaxIterationsSummarize)
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "found part of it" {
+		t.Errorf("Expected summary output, got %q", response.Output)
+	}
+	if response.Metadata["stop_reason"] != StopReasonMaxIterations {
+		t.Errorf("Expected stop reason %q, got %v", StopReasonMaxIterations, response.Metadata["stop_reason"])
+	}
+
+	final := model.requests[1]
+	if final.Tools != nil {
+		t.Errorf("Expected no tools in the last iteration, got %d", len(final.Tools))
+	}
+	if last := final.Messages[len(final.Messages)-1]; last.Content != finalAnswerPrompt {
+		t.Errorf("Expected final answer instruction, got %+v", last)
+	}
+
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	history := sess.GetHistory(100)
+	if countEntries(history, session.EntryTypeToolCall) != 1 || countEntries(history, session.EntryTypeToolResult) != 1 {
+		t.Errorf("Expected the tool call and result to be persisted, got %d entries", len(history))
+	}
+}
+
+func TestMaxIterations_Partial(t *testing.T) {
+	store := memory.NewStore()
+	first := lookupCall(llm.Usage{})
+	first.Content = "Looking it up"
+	model := &scriptedModel{responses: []*llm.Response{first, lookupCall(llm.Usage{})}}
+	engine := newLimitedEngine(t, model, store, MaxIterationsPartial)
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if !response.Partial {
+		t.Error("Expected a partial response")
+	}
+	if response.Output != "Looking it up" {
+		t.Errorf("Expected the last assistant text as output, got %q", response.Output)
+	}
+	transcript, ok := response.Metadata["transcript"].([]llm.Message)
+	if !ok || len(transcript) != 4 {
+		t.Fatalf("Expected 4 transcript messages, got %v", response.Metadata["transcript"])
+	}
+	if transcript[1].Role != "tool" {
+		t.Errorf("Expected a tool message in the transcript, got %q", transcript[1].Role)
+	}
+
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	history := sess.GetHistory(100)
+	if countEntries(history, session.EntryTypeToolResult) != 2 {
+		t.Errorf("Expected 2 persisted tool results, got %d", countEntries(history, session.EntryTypeToolResult))
+	}
+
+	// The next run sees the earlier tool results
+	model.responses = []*llm.Response{{Content: "continued", FinishReason: "stop"}}
+	if _, err := engine.Execute(context.Background(), Request{Input: "continue", SessionID: response.SessionID}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// Each replayed tool result follows the assistant message that called it
+	prompt := model.requests[len(model.requests)-1].Messages
+	results := 0
+	for i, message := range prompt {
+		if message.Role != "tool" || !strings.Contains(message.Content, "Tool: lookup") {
+			continue
+		}
+		results++
+		if previous := prompt[i-1]; len(previous.ToolCalls) != 1 || previous.ToolCalls[0].ID != message.ToolCallID {
+			t.Errorf("Expected tool result %q to follow its call, got %+v", message.ToolCallID, previous)
+		}
+	}
+	if results != 2 {
+		t.Errorf("Expected 2 persisted tool results in the next prompt, got %d", results)
+	}
+}
+
+func TestHistoryLimit_DoesNotSplitToolCalls(t *testing.T) {
+	store := memory.NewStore()
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+	second := lookupCall(llm.Usage{})
+	second.ToolCalls[0].ID = "2"
+	model := &scriptedModel{responses: []*llm.Response{lookupCall(llm.Usage{}), second}}
+	engine, err := NewEngine(EngineConfig{
+		Model:               model,
+		SessionStore:        store,
+		ToolRegistry:        registry,
+		MaxIterations:       2,
+		MaxIterationsPolicy: MaxIterationsPartial,
+		HistoryLimit:        3,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	// A partial run without text saves the input and two call/result pairs
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// The limit keeps the second pair and the first result; the result is
+	// dropped with its call
+	model.responses = []*llm.Response{{Content: "continued", FinishReason: "stop"}}
+	if _, err := engine.Execute(context.Background(), Request{Input: "continue", SessionID: response.SessionID}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	prompt := model.requests[len(model.requests)-1].Messages
+	calls := map[string]bool{}
+	results := 0
+	for _, message := range prompt {
+		for _, call := range message.ToolCalls {
+			calls[call.ID] = true
+		}
+		if message.Role == "tool" {
+			results++
+			if !calls[message.ToolCallID] {
+				t.Errorf("Expected tool result %q to follow its call, got %+v", message.ToolCallID, prompt)
+			}
+		}
+	}
+	if results != 1 {
+		t.Errorf("Expected 1 replayed tool result, got %d", results)
+	}
+}
+
+func TestMaxIterations_FailByDefault(t *testing.T) {
+	store := memory.NewStore()
+	model := &scriptedModel{responses: []*llm.Response{lookupCall(llm.Usage{}), lookupCall(llm.Usage{})}}
+	engine := newLimitedEngine(t, model, store, MaxIterationsFail)
+
+	_, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if !errors.Is(err, ErrMaxIterationsExceeded) {
+		t.Errorf("Expected ErrMaxIterationsExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"unicode/utf8"
+)
+
+// LogRedactFunc rewrites a value before it is logged, e.g. to remove PII
+// from tool arguments or results
+type LogRedactFunc func(value string) string
+
+// maxLoggedValueLength truncates logged tool arguments and results
+const maxLoggedValueLength = 200
+
+// discardHandler drops all records; it is the default so the engine stays
+// silent unless a logger is configured
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// logValue prepares a tool argument or result for logging
+func logValue(value string, redactFn LogRedactFunc) string {
+	if redactFn != nil {
+		value = redactFn(value)
+	}
+	if len(value) > maxLoggedValueLength {
+		// Cut at a rune boundary so the log stays valid UTF-8
+		end := maxLoggedValueLength
+		for end > 0 && !utf8.RuneStart(value[end]) {
+			end--
+		}
+		value = value[:end] + "..."
+	}
+	return value
+}
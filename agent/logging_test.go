@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func toolCallingModel() *scriptedModel {
+	return &scriptedModel{responses: []*llm.Response{
+		{
+			ToolCalls: []tool.Call{{
+				ID:       "call_1",
+				Function: tool.FunctionCall{Name: "lookup", Arguments: `{"input":"secret-arg"}`},
+			}},
+			Usage: llm.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		},
+		{Content: "done", FinishReason: "stop"},
+	}}
+}
+
+func TestExecute_StructuredLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup", result: "secret-result"})
+
+	engine, err := NewEngine(EngineConfig{
+		Model:              toolCallingModel(),
+		ToolRegistry:       registry,
+		Logger:             logger,
+		LogRedactArguments: func(string) string { return "<args>" },
+		LogRedactResults:   func(string) string { return "<result>" },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "secret-") {
+		t.Errorf("Expected tool values to be redacted in logs, got %s", buf.String())
+	}
+
+	records := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		if record["session_id"] != response.SessionID {
+			t.Errorf("Expected session_id %s on %q, got %v", response.SessionID, record["msg"], record["session_id"])
+		}
+		records[record["msg"].(string)] = record
+	}
+
+	toolCall, ok := records["calling tool"]
+	if !ok {
+		t.Fatal("Expected a 'calling tool' record")
+	}
+	if toolCall["tool"] != "lookup" || toolCall["arguments"] != "<args>" {
+		t.Errorf("Expected tool attributes, got %v", toolCall)
+	}
+	if records["tool completed"]["result"] != "<result>" {
+		t.Errorf("Expected redacted result, got %v", records["tool completed"]["result"])
+	}
+	if _, ok := records["tool completed"]["duration"]; !ok {
+		t.Error("Expected tool duration to be logged")
+	}
+	if records["agent run completed"]["total_tokens"] != float64(12) {
+		t.Errorf("Expected total_tokens 12, got %v", records["agent run completed"]["total_tokens"])
+	}
+}
+
+func TestExecute_SilentByDefault(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+
+	engine, err := NewEngine(EngineConfig{Model: toolCallingModel(), ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+	_, err = engine.Execute(context.Background(), Request{Input: "hello"})
+	os.Stdout = stdout
+	w.Close()
+
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	output, _ := io.ReadAll(r)
+	if len(output) != 0 {
+		t.Errorf("Expected no output on stdout, got %q", output)
+	}
+}
+
+func TestLogValue_TruncatesAtRuneBoundary(t *testing.T) {
+	// 3-byte runes never line up with the byte limit
+	value := logValue("a"+strings.Repeat("界", maxLoggedValueLength), nil)
+	if !utf8.ValidString(value) {
+		t.Errorf("Expected valid UTF-8, got %q", value)
+	}
+	if !strings.HasSuffix(value, "...") || len(value) > maxLoggedValueLength+len("...") {
+		t.Errorf("Expected value truncated to %d bytes, got %d", maxLoggedValueLength, len(value))
+	}
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"errors"
+	"time"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// recordLLMCall reports one LLM call to llmModel and its token usage
+func (e *engine) recordLLMCall(llmModel llm.Model, response *llm.Response, err error, duration time.Duration) {
+	model := modelName(llmModel)
+	e.metrics.RecordLLMCall(model, metrics.Status(err), duration)
+	if err != nil {
+		return
+	}
+
+	e.metrics.RecordTokens(model, metrics.TokenPrompt, response.Usage.PromptTokens)
+	e.metrics.RecordTokens(model, metrics.TokenCompletion, response.Usage.CompletionTokens)
+}
+
+// recordToolCall reports one executed tool call
+func (e *engine) recordToolCall(call tool.Call, err error, duration time.Duration) {
+	outcome := metrics.OutcomeSuccess
+	if err != nil {
+		outcome = metrics.OutcomeError
+	}
+	e.metrics.RecordToolCall(call.Function.Name, outcome, duration)
+}
+
+// recordRun reports the iterations of a finished run. limitReached is set
+// when a MaxIterationsPolicy turned the iteration limit into a result.
+func (e *engine) recordRun(iterations int, limitReached bool, err error) {
+	status := metrics.Status(err)
+	if limitReached || errors.Is(err, ErrMaxIterationsExceeded) {
+		status = metrics.StatusMaxIterations
+		e.metrics.RecordMaxIterationsExceeded()
+	}
+	e.metrics.RecordRun(status, iterations)
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// countingRecorder counts the measurements it receives
+type countingRecorder struct {
+	mu            sync.Mutex
+	llmCalls      map[string]int
+	tokens        map[string]int
+	toolCalls     map[string]int
+	runs          map[string]int
+	iterations    int
+	maxIterations int
+	storeOps      map[string]int
+}
+
+func newCountingRecorder() *countingRecorder {
+	return &countingRecorder{
+		llmCalls:  map[string]int{},
+		tokens:    map[string]int{},
+		toolCalls: map[string]int{},
+		runs:      map[string]int{},
+		storeOps:  map[string]int{},
+	}
+}
+
+func (r *countingRecorder) RecordLLMCall(model, status string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.llmCalls[model+"/"+status]++
+}
+
+func (r *countingRecorder) RecordTokens(model, tokenType string, count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[tokenType] += count
+}
+
+func (r *countingRecorder) RecordToolCall(tool, outcome string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.toolCalls[tool+"/"+outcome]++
+}
+
+func (r *countingRecorder) RecordRun(status string, iterations int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[status]++
+	r.iterations += iterations
+}
+
+func (r *countingRecorder) RecordMaxIterationsExceeded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxIterations++
+}
+
+func (r *countingRecorder) RecordStoreOperation(operation, status string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.storeOps[operation+"/"+status]++
+}
+
+func TestMetrics_Run(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup"})
+	recorder := newCountingRecorder()
+
+	engine, err := NewEngine(EngineConfig{
+		Model:        namedModel{toolCallingModel()},
+		ToolRegistry: registry,
+		Metrics:      recorder,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if recorder.llmCalls["test-model/"+metrics.StatusOK] != 2 {
+		t.Errorf("Expected 2 successful LLM calls, got %v", recorder.llmCalls)
+	}
+	if recorder.tokens[metrics.TokenPrompt] != 10 || recorder.tokens[metrics.TokenCompletion] != 2 {
+		t.Errorf("Expected 10 prompt and 2 completion tokens, got %v", recorder.tokens)
+	}
+	if recorder.toolCalls["lookup/"+metrics.OutcomeSuccess] != 1 {
+		t.Errorf("Expected 1 successful tool call, got %v", recorder.toolCalls)
+	}
+	if recorder.runs[metrics.StatusOK] != 1 || recorder.iterations != 2 {
+		t.Errorf("Expected 1 run with 2 iterations, got %v / %d", recorder.runs, recorder.iterations)
+	}
+	if recorder.storeOps["create/ok"] != 1 || recorder.storeOps["save/ok"] != 1 {
+		t.Errorf("Expected create and save operations, got %v", recorder.storeOps)
+	}
+
+	// Loading a session the store does not know is a failed get
+	model := &scriptedModel{responses: []*llm.Response{{Content: "again", FinishReason: "stop"}}}
+	engine, _ = NewEngine(EngineConfig{Model: model, Metrics: recorder})
+	if _, err := engine.Execute(context.Background(), Request{Input: "hi", SessionID: response.SessionID}); err == nil {
+		t.Fatal("Expected unknown session in a new store")
+	}
+	if recorder.storeOps["get/error"] != 1 {
+		t.Errorf("Expected failed get operation, got %v", recorder.storeOps)
+	}
+}
+
+func TestMetrics_MaxIterationsAndFailures(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup", err: errors.New("boom")})
+	recorder := newCountingRecorder()
+
+	// The model keeps calling the failing tool
+	loop := &llm.Response{ToolCalls: []tool.Call{{ID: "1", Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}}}}
+	engine, err := NewEngine(EngineConfig{
+		Model:         &MockModel{response: loop},
+		ToolRegistry:  registry,
+		MaxIterations: 2,
+		Metrics:       recorder,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); !errors.Is(err, ErrMaxIterationsExceeded) {
+		t.Fatalf("Expected ErrMaxIterationsExceeded, got %v", err)
+	}
+
+	if recorder.maxIterations != 1 || recorder.runs[metrics.StatusMaxIterations] != 1 {
+		t.Errorf("Expected max iterations to be recorded, got %d / %v", recorder.maxIterations, recorder.runs)
+	}
+	if recorder.toolCalls["lookup/"+metrics.OutcomeError] != 2 {
+		t.Errorf("Expected 2 failed tool calls, got %v", recorder.toolCalls)
+	}
+	if recorder.llmCalls["/"+metrics.StatusOK] != 2 {
+		t.Errorf("Expected LLM calls without a model name, got %v", recorder.llmCalls)
+	}
+}
@@ -0,0 +1,427 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// StateKeyPlan is the session state key holding the Plan of the last
+// plan-and-execute run
+const StateKeyPlan = "plan"
+
+// MetadataPlan is the Response.Metadata key holding the final Plan
+const MetadataPlan = "plan"
+
+// ErrInvalidPlan indicates the model did not reply with a usable plan
+var ErrInvalidPlan = errors.New("invalid plan")
+
+// StepStatus is the progress of a plan step
+type StepStatus string
+
+const (
+	StepPending StepStatus = "pending"
+	StepRunning StepStatus = "running"
+	StepDone    StepStatus = "done"
+	StepFailed  StepStatus = "failed"
+)
+
+// Plan is the list of steps a plan-and-execute run works through
+type Plan struct {
+	Goal  string     `json:"goal"`
+	Steps []PlanStep `json:"steps"`
+
+	// Revision counts the times the plan was revised after a failed step
+	Revision int `json:"revision"`
+}
+
+// PlanStep is one step of a Plan
+type PlanStep struct {
+	Description string `json:"description"`
+
+	// Tools the model expects to need for the step; a hint, all tools stay available
+	Tools []string `json:"tools,omitempty"`
+
+	Status StepStatus `json:"status"`
+
+	// Result is what the step found, or why it failed
+	Result string `json:"result,omitempty"`
+}
+
+// PlanHook is called when a plan-and-execute run creates its plan, starts
+// or finishes a step, or revises the plan
+type PlanHook interface {
+	OnPlanUpdated(ctx context.Context, sess session.Session, plan Plan)
+}
+
+// GetPlan returns the plan stored in the session by the last
+// plan-and-execute run
+func GetPlan(sess session.Session) (Plan, bool) {
+	value, exists := sess.Get(StateKeyPlan)
+	if !exists {
+		return Plan{}, false
+	}
+	if plan, ok := value.(Plan); ok {
+		return plan.clone(), true
+	}
+
+	// Stores that serialize state return the plan as generic JSON values
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Plan{}, false
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return Plan{}, false
+	}
+	return plan, true
+}
+
+func (p Plan) clone() Plan {
+	p.Steps = slices.Clone(p.Steps)
+	for i := range p.Steps {
+		p.Steps[i].Tools = slices.Clone(p.Steps[i].Tools)
+	}
+	return p
+}
+
+// String renders the plan as a numbered list with the status and result
+// of each step
+func (p Plan) String() string {
+	var b strings.Builder
+	if p.Goal != "" {
+		fmt.Fprintf(&b, "Goal: %s\n", p.Goal)
+	}
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "%d. [%s] %s\n", i+1, step.Status, step.Description)
+		if step.Result != "" {
+			fmt.Fprintf(&b, "   Result: %s\n", step.Result)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// planFormat describes the JSON reply expected for a plan
+const planFormat = `Reply with only a JSON object: {"goal": "...", "steps": [{"description": "...", "tools": ["tool_name"]}]}. ` +
+	"Keep each step to one piece of work and list the tools it will likely need."
+
+// planPrompt asks the model for a plan of the request
+func planPrompt(definitions []tool.Definition) string {
+	var b strings.Builder
+	b.WriteString("Before answering, plan the work needed for the user's request. ")
+	b.WriteString(planFormat)
+	if len(definitions) > 0 {
+		b.WriteString("\n\nAvailable tools:")
+		for _, definition := range definitions {
+			fmt.Fprintf(&b, "\n- %s: %s", definition.Function.Name, definition.Function.Description)
+		}
+	}
+	return b.String()
+}
+
+// stepPrompt asks the model to carry out one step of the plan
+func stepPrompt(plan Plan, index int) string {
+	step := plan.Steps[index]
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are carrying out this plan for the user's request:\n%s\n\n", plan)
+	fmt.Fprintf(&b, "Work on step %d now: %s\n", index+1, step.Description)
+	if len(step.Tools) > 0 {
+		fmt.Fprintf(&b, "Suggested tools: %s\n", strings.Join(step.Tools, ", "))
+	}
+	b.WriteString("When the step is finished, reply with DONE: followed by its result. " +
+		"If it cannot be done, reply with FAILED: followed by the reason.")
+	return b.String()
+}
+
+// stepResultPrompt asks for the outcome of a step that ran out of iterations
+const stepResultPrompt = "No more tools can be called for this step. " +
+	"Reply with DONE: followed by what the step found so far, or FAILED: followed by the reason."
+
+// revisePrompt asks the model for new steps after a step failed
+func revisePrompt(plan Plan, index int) string {
+	return fmt.Sprintf("Step %d of the plan failed:\n%s\n\n"+
+		"Revise the plan: list only the steps still to do, working around the failure. %s",
+		index+1, plan, planFormat)
+}
+
+// parsePlan reads a plan from a model reply, ignoring any text around the
+// JSON object
+func parsePlan(text string) (Plan, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return Plan{}, fmt.Errorf("%w: no JSON object in reply", ErrInvalidPlan)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal([]byte(text[start:end+1]), &plan); err != nil {
+		return Plan{}, fmt.Errorf("%w: %w", ErrInvalidPlan, err)
+	}
+	if len(plan.Steps) == 0 {
+		return Plan{}, fmt.Errorf("%w: no steps", ErrInvalidPlan)
+	}
+	for i := range plan.Steps {
+		plan.Steps[i].Status = StepPending
+		plan.Steps[i].Result = ""
+	}
+	return plan, nil
+}
+
+// stepOutcome reads the status and result of a step from the model's reply
+func stepOutcome(text string) (StepStatus, string) {
+	text = strings.TrimSpace(text)
+	upper := strings.ToUpper(text)
+	switch {
+	case strings.HasPrefix(upper, "FAILED:"):
+		return StepFailed, strings.TrimSpace(text[len("FAILED:"):])
+	case strings.HasPrefix(upper, "DONE:"):
+		return StepDone, strings.TrimSpace(text[len("DONE:"):])
+	}
+	// A reply without a marker still finishes the step
+	return StepDone, text
+}
+
+// PlanAndExecute returns a strategy where the model first plans the
+// request and then carries out the steps one at a time, each with up to
+// MaxIterations LLM calls. A failed step leads to a revised plan, at most
+// maxRevisions times (0 uses the default of 2, negative disables
+// revisions, as in Reflection). When no revision is left, or the revision
+// is not a valid plan, the final answer is written from the steps done so
+// far; otherwise it is written from all step results. MaxIterationsPolicy
+// applies to each step: MaxIterationsSummarize asks for the step result
+// without tools, MaxIterationsPartial ends the run with the step's messages,
+// and MaxIterationsFail fails the step.
+func PlanAndExecute(maxRevisions int) Strategy {
+	if maxRevisions == 0 {
+		maxRevisions = 2
+	}
+	return planStrategy{maxRevisions: maxRevisions}
+}
+
+type planStrategy struct {
+	maxRevisions int
+}
+
+func (s planStrategy) Execute(ctx context.Context, run *Run) (string, error) {
+	return run.engine.executePlan(ctx, run.state, run.messages, s.maxRevisions)
+}
+
+// executePlan plans the request, carries out the steps and answers from
+// the step results
+func (e *engine) executePlan(ctx context.Context, run *runState, messages []llm.Message, maxRevisions int) (string, error) {
+	low, err := e.checkBudget(ctx, run)
+	if err != nil {
+		return "", err
+	}
+
+	var plan Plan
+	if !low {
+		plan, err = e.requestPlan(ctx, run, messages, planPrompt(e.toolRegistry.GetDefinitions()))
+		if err != nil {
+			return "", err
+		}
+		e.updatePlan(ctx, run, plan)
+
+		if err := e.runPlan(ctx, run, messages, &plan, maxRevisions); err != nil {
+			return "", err
+		}
+	}
+	if run.handoff != nil {
+		return "", nil
+	}
+	if run.partial {
+		return lastAssistantText(run.transcript), nil
+	}
+
+	// Answer from the step results
+	if _, err := e.checkBudget(ctx, run); err != nil {
+		return "", err
+	}
+	answerMessages := slices.Clone(messages)
+	if len(plan.Steps) > 0 {
+		answerMessages = append(answerMessages, llm.Message{
+			Role:    "system",
+			Content: "The plan for this request was carried out:\n" + plan.String(),
+		})
+	}
+	response, err := e.complete(ctx, run, e.finalAnswerRequest(run, answerMessages))
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// runPlan carries out the steps of the plan in order
+func (e *engine) runPlan(ctx context.Context, run *runState, messages []llm.Message, plan *Plan, maxRevisions int) error {
+	revisions := 0
+	for i := 0; i < len(plan.Steps); i++ {
+		plan.Steps[i].Status = StepRunning
+		e.updatePlan(ctx, run, *plan)
+
+		if err := e.runStep(ctx, run, messages, plan, i); err != nil {
+			return err
+		}
+		step := plan.Steps[i]
+		e.updatePlan(ctx, run, *plan)
+
+		// The step was interrupted by a budget or a handoff
+		if step.Status == StepPending {
+			return nil
+		}
+
+		run.logger.InfoContext(ctx, "plan step finished",
+			slog.Int("step", i+1),
+			slog.Int("steps", len(plan.Steps)),
+			slog.String("status", string(step.Status)))
+		if step.Status != StepFailed {
+			continue
+		}
+
+		if revisions >= maxRevisions {
+			run.logger.WarnContext(ctx, "plan step failed with no revisions left, answering with what was done",
+				slog.Int("step", i+1))
+			return nil
+		}
+		low, err := e.checkBudget(ctx, run)
+		if err != nil || low {
+			return err
+		}
+		revised, err := e.requestPlan(ctx, run, messages, revisePrompt(*plan, i))
+		if errors.Is(err, ErrInvalidPlan) {
+			run.logger.WarnContext(ctx, "plan revision was invalid, answering with what was done",
+				slog.Int("step", i+1))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Keep the steps so far, including the failed one, and continue
+		// with the revised steps
+		revisions++
+		plan.Steps = append(plan.Steps[:i+1:i+1], revised.Steps...)
+		plan.Revision++
+		e.updatePlan(ctx, run, *plan)
+	}
+	return nil
+}
+
+// runStep carries out one step with the tool loop and records its outcome
+// in the plan. The step stays pending when a budget or a handoff stops it,
+// or when it runs out of iterations under MaxIterationsPartial.
+func (e *engine) runStep(ctx context.Context, run *runState, messages []llm.Message, plan *Plan, index int) error {
+	step := &plan.Steps[index]
+	conversation := append(slices.Clone(messages), llm.Message{
+		Role:    "system",
+		Content: stepPrompt(*plan, index),
+	})
+
+	for iteration := 0; iteration < e.maxIterations; iteration++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		low, err := e.checkBudget(ctx, run)
+		if err != nil {
+			return err
+		}
+		if low {
+			step.Status = StepPending
+			return nil
+		}
+
+		// The last iteration finishes the step without tools when summarizing
+		summarize := iteration == e.maxIterations-1 && e.maxIterationsPolicy == MaxIterationsSummarize
+		request := e.toolRequest(conversation)
+		if summarize {
+			run.logger.InfoContext(ctx, "maximum iterations reached, asking for the step result",
+				slog.Int("step", index+1),
+				slog.Int("max_iterations", e.maxIterations))
+			run.stopReason = StopReasonMaxIterations
+			request = e.textRequest(append(conversation[:len(conversation):len(conversation)], llm.Message{
+				Role:    "system",
+				Content: stepResultPrompt,
+			}))
+		}
+
+		response, err := e.complete(ctx, run, request)
+		if err != nil {
+			return err
+		}
+
+		if len(response.ToolCalls) > 0 && !summarize {
+			if call, ok := e.findHandoff(response.ToolCalls); ok {
+				run.logger.InfoContext(ctx, "handing off", slog.String("agent", call.agent))
+				run.handoff = call
+				step.Status = StepPending
+				return nil
+			}
+
+			toolMessages, _ := e.runTools(ctx, run, response)
+			conversation = append(conversation, toolMessages...)
+			if err := e.hooks.iterationComplete(ctx, run.session, run.iterations, conversation); err != nil {
+				return err
+			}
+			continue
+		}
+
+		conversation = append(conversation, llm.Message{Role: "assistant", Content: response.Content})
+		if err := e.hooks.iterationComplete(ctx, run.session, run.iterations, conversation); err != nil {
+			return err
+		}
+		step.Status, step.Result = stepOutcome(response.Content)
+		return nil
+	}
+
+	// Out of iterations, keep what the step has so far
+	if e.maxIterationsPolicy == MaxIterationsPartial {
+		run.logger.WarnContext(ctx, "maximum iterations reached, returning partial result",
+			slog.Int("step", index+1),
+			slog.Int("max_iterations", e.maxIterations))
+		run.stopReason = StopReasonMaxIterations
+		run.partial = true
+		run.transcript = conversation[len(messages)+1:]
+		step.Status = StepPending
+		return nil
+	}
+
+	step.Status = StepFailed
+	step.Result = fmt.Sprintf("no result after %d iterations", e.maxIterations)
+	return nil
+}
+
+// requestPlan asks the model for a plan without offering tools
+func (e *engine) requestPlan(ctx context.Context, run *runState, messages []llm.Message, instruction string) (Plan, error) {
+	response, err := e.complete(ctx, run, e.textRequest(append(messages[:len(messages):len(messages)], llm.Message{
+		Role:    "system",
+		Content: instruction,
+	})))
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan, err := parsePlan(response.Content)
+	if err != nil {
+		run.logger.WarnContext(ctx, "model replied without a valid plan", slog.Any("error", err))
+		return Plan{}, err
+	}
+	return plan, nil
+}
+
+// updatePlan stores the plan in the session and reports it to the hooks
+func (e *engine) updatePlan(ctx context.Context, run *runState, plan Plan) {
+	run.session.Set(StateKeyPlan, plan.clone())
+	run.metadata[MetadataPlan] = plan.clone()
+	e.hooks.planUpdated(ctx, run.session, plan.clone())
+	e.hooks.event(ctx, run.session, Event{Type: EventPlanUpdated, Data: plan.clone()})
+}
@@ -0,0 +1,273 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+)
+
+// planRecorder records every plan update
+type planRecorder struct {
+	updates []Plan
+}
+
+func (r *planRecorder) OnPlanUpdated(ctx context.Context, sess session.Session, plan Plan) {
+	r.updates = append(r.updates, plan)
+}
+
+func textResponse(content string) *llm.Response {
+	return &llm.Response{Content: content, FinishReason: "stop"}
+}
+
+func newPlanEngine(t *testing.T, model llm.Model, store session.SessionStore, hooks ...Hook) Engine {
+	t.Helper()
+	return newBudgetEngine(t, model, EngineConfig{
+		SessionStore: store,
+		Strategy:     PlanAndExecute(0),
+		Hooks:        hooks,
+	})
+}
+
+func TestPlan_ExecutesStepsInOrder(t *testing.T) {
+	store := memory.NewStore()
+	recorder := &planRecorder{}
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse("```json\n" + `{"goal": "answer", "steps": [{"description": "look it up", "tools": ["lookup"]}, {"description": "compare"}]}` + "\n```"),
+		lookupCall(llm.Usage{}),
+		textResponse("DONE: found x"),
+		textResponse("compared, x wins"),
+		textResponse("x is the answer"),
+	}}
+	engine := newPlanEngine(t, model, store, recorder)
+
+	response, err := engine.Execute(context.Background(), Request{Input: "which is best?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "x is the answer" {
+		t.Errorf("Expected final answer, got %q", response.Output)
+	}
+	if model.requests[0].Tools != nil {
+		t.Errorf("Expected no tools when planning, got %d", len(model.requests[0].Tools))
+	}
+	if !strings.Contains(model.requests[1].Messages[len(model.requests[1].Messages)-1].Content, "Work on step 1 now: look it up") {
+		t.Errorf("Expected step 1 instruction, got %+v", model.requests[1].Messages)
+	}
+	if len(model.requests[1].Tools) == 0 {
+		t.Error("Expected tools during a step")
+	}
+
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	plan, ok := GetPlan(sess)
+	if !ok {
+		t.Fatal("Expected plan in session state")
+	}
+	if plan.Steps[0].Status != StepDone || plan.Steps[0].Result != "found x" {
+		t.Errorf("Expected step 1 done with result, got %+v", plan.Steps[0])
+	}
+	if plan.Steps[1].Status != StepDone || plan.Steps[1].Result != "compared, x wins" {
+		t.Errorf("Expected step 2 done with result, got %+v", plan.Steps[1])
+	}
+	if _, ok := response.Metadata[MetadataPlan].(Plan); !ok {
+		t.Errorf("Expected plan in metadata, got %v", response.Metadata[MetadataPlan])
+	}
+
+	// Created, then running and finished for each step
+	if len(recorder.updates) != 5 {
+		t.Fatalf("Expected 5 plan updates, got %d", len(recorder.updates))
+	}
+	if recorder.updates[1].Steps[0].Status != StepRunning {
+		t.Errorf("Expected step 1 running, got %s", recorder.updates[1].Steps[0].Status)
+	}
+}
+
+func TestPlan_RevisesAfterFailedStep(t *testing.T) {
+	store := memory.NewStore()
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse(`{"goal": "answer", "steps": [{"description": "fetch the paper"}]}`),
+		textResponse("FAILED: paper is paywalled"),
+		textResponse(`{"steps": [{"description": "use the abstract"}]}`),
+		textResponse("DONE: abstract says x"),
+		textResponse("x, based on the abstract"),
+	}}
+	engine := newPlanEngine(t, model, store)
+
+	response, err := engine.Execute(context.Background(), Request{Input: "summarize the paper"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	plan := response.Metadata[MetadataPlan].(Plan)
+	if plan.Revision != 1 || len(plan.Steps) != 2 {
+		t.Fatalf("Expected 1 revision with 2 steps, got %+v", plan)
+	}
+	if plan.Steps[0].Status != StepFailed || plan.Steps[0].Result != "paper is paywalled" {
+		t.Errorf("Expected failed first step, got %+v", plan.Steps[0])
+	}
+	if plan.Steps[1].Status != StepDone {
+		t.Errorf("Expected revised step done, got %+v", plan.Steps[1])
+	}
+	if plan.Goal != "answer" {
+		t.Errorf("Expected the revised plan to keep its goal, got %q", plan.Goal)
+	}
+
+	// The final answer sees the step results
+	final := model.requests[4].Messages
+	if !strings.Contains(final[len(final)-2].Content, "abstract says x") {
+		t.Errorf("Expected step results before the final answer, got %+v", final[len(final)-2])
+	}
+}
+
+func TestPlan_AnswersWhenRevisionsRunOut(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse(`{"steps": [{"description": "fetch"}, {"description": "compare"}]}`),
+		textResponse("FAILED: offline"),
+		textResponse("I could not fetch the data"),
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{Strategy: PlanAndExecute(-1)})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "I could not fetch the data" {
+		t.Errorf("Expected final answer, got %q", response.Output)
+	}
+	plan := response.Metadata[MetadataPlan].(Plan)
+	if plan.Steps[1].Status != StepPending {
+		t.Errorf("Expected step 2 left pending, got %s", plan.Steps[1].Status)
+	}
+}
+
+func TestPlan_InvalidPlan(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{textResponse("I will just answer")}}
+	engine := newPlanEngine(t, model, memory.NewStore())
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("Expected ErrInvalidPlan, got %v", err)
+	}
+}
+
+func TestGetPlan_DecodesSerializedState(t *testing.T) {
+	created := memory.NewStore().Create(context.Background())
+	created.Set(StateKeyPlan, map[string]any{
+		"goal":  "answer",
+		"steps": []any{map[string]any{"description": "look", "status": "done"}},
+	})
+
+	plan, ok := GetPlan(created)
+	if !ok {
+		t.Fatal("Expected plan")
+	}
+	if plan.Goal != "answer" || plan.Steps[0].Status != StepDone {
+		t.Errorf("Expected decoded plan, got %+v", plan)
+	}
+}
+
+func TestPlan_DefaultRevisions(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse(`{"steps": [{"description": "fetch"}]}`),
+		textResponse("FAILED: offline"),
+		textResponse(`{"steps": [{"description": "fetch the mirror"}]}`),
+		textResponse("FAILED: mirror offline"),
+		textResponse(`{"steps": [{"description": "fetch the cache"}]}`),
+		textResponse("FAILED: cache empty"),
+		textResponse("I could not fetch the data"),
+	}}
+	engine := newPlanEngine(t, model, memory.NewStore())
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "I could not fetch the data" {
+		t.Errorf("Expected final answer, got %q", response.Output)
+	}
+	if plan := response.Metadata[MetadataPlan].(Plan); plan.Revision != 2 || len(plan.Steps) != 3 {
+		t.Errorf("Expected 2 revisions by default, got %d with %d steps", plan.Revision, len(plan.Steps))
+	}
+}
+
+func TestPlan_AnswersWhenRevisionIsInvalid(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse(`{"steps": [{"description": "fetch"}, {"description": "compare"}]}`),
+		textResponse("FAILED: offline"),
+		textResponse("There is nothing else to try"),
+		textResponse("I could not fetch the data"),
+	}}
+	engine := newPlanEngine(t, model, memory.NewStore())
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "I could not fetch the data" {
+		t.Errorf("Expected final answer, got %q", response.Output)
+	}
+	plan := response.Metadata[MetadataPlan].(Plan)
+	if plan.Revision != 0 || plan.Steps[0].Status != StepFailed || plan.Steps[1].Status != StepPending {
+		t.Errorf("Expected the original plan with step 1 failed, got %+v", plan)
+	}
+}
+
+func TestPlan_StepMaxIterationsSummarize(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse(`{"steps": [{"description": "fetch"}]}`),
+		lookupCall(llm.Usage{}),
+		textResponse("DONE: half of the data"),
+		textResponse("Here is half of the data"),
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{Strategy: PlanAndExecute(0), MaxIterations: 2, MaxIterationsPolicy: MaxIterationsSummarize})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if len(model.requests[2].Tools) != 0 {
+		t.Errorf("Expected the last step iteration without tools, got %d", len(model.requests[2].Tools))
+	}
+	plan := response.Metadata[MetadataPlan].(Plan)
+	if plan.Steps[0].Status != StepDone || plan.Steps[0].Result != "half of the data" {
+		t.Errorf("Expected the step to finish with its summary, got %+v", plan.Steps[0])
+	}
+	if response.Output != "Here is half of the data" {
+		t.Errorf("Expected final answer, got %q", response.Output)
+	}
+}
+
+func TestPlan_StepMaxIterationsPartial(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse(`{"steps": [{"description": "fetch"}, {"description": "compare"}]}`),
+		lookupCall(llm.Usage{}),
+		{Content: "Still fetching", ToolCalls: lookupCall(llm.Usage{}).ToolCalls},
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{Strategy: PlanAndExecute(0), MaxIterations: 2, MaxIterationsPolicy: MaxIterationsPartial})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if !response.Partial || response.Output != "Still fetching" {
+		t.Errorf("Expected a partial result, got %q (partial %v)", response.Output, response.Partial)
+	}
+	if len(model.requests) != 3 {
+		t.Errorf("Expected no answer call after the partial step, got %d calls", len(model.requests))
+	}
+	if transcript, _ := response.Metadata["transcript"].([]llm.Message); len(transcript) != 4 {
+		t.Errorf("Expected the step's tool calls and results in the transcript, got %+v", response.Metadata["transcript"])
+	}
+	if plan := response.Metadata[MetadataPlan].(Plan); plan.Steps[0].Status != StepPending {
+		t.Errorf("Expected the step left pending, got %s", plan.Steps[0].Status)
+	}
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/davidleitw/go-agent/llm"
+)
+
+// MetadataThoughts is the Response.Metadata key holding the thoughts of a
+// ReAct run, in order
+const MetadataThoughts = "thoughts"
+
+// reactPrompt asks the model to reason before each action
+const reactPrompt = `Think before you act. Start every reply with "Thought:" followed by what you know so far and what to do next. ` +
+	`Then either call a tool, or write "Answer:" followed by your final answer to the user.`
+
+// ReAct returns a strategy where the model writes an explicit thought
+// before each tool call and before its answer. Thoughts are emitted as
+// EventThought events, saved to the session as thinking entries and
+// returned in Response.Metadata["thoughts"]; the answer is returned
+// without them. MaxIterationsPolicy applies as in DefaultStrategy.
+func ReAct() Strategy {
+	return reactStrategy{}
+}
+
+type reactStrategy struct{}
+
+func (reactStrategy) Execute(ctx context.Context, run *Run) (string, error) {
+	messages := append(run.Messages(), llm.Message{Role: "system", Content: reactPrompt})
+	promptLength := len(messages)
+	var thoughts []string
+
+	for iteration := 0; iteration < run.MaxIterations(); iteration++ {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		low, err := run.CheckBudget(ctx)
+		if err != nil {
+			return "", err
+		}
+		// The last iteration answers without tools when summarizing
+		finalAnswer := low
+		if !low && iteration == run.MaxIterations()-1 && run.MaxIterationsPolicy() == MaxIterationsSummarize {
+			run.Logger().InfoContext(ctx, "maximum iterations reached, asking for a final answer",
+				slog.Int("max_iterations", run.MaxIterations()))
+			run.SetStopReason(StopReasonMaxIterations)
+			finalAnswer = true
+		}
+		request := run.ToolRequest(messages)
+		if finalAnswer {
+			request = run.FinalAnswerRequest(messages)
+		}
+
+		response, err := run.Complete(ctx, request)
+		if err != nil {
+			return "", err
+		}
+
+		thought, answer := splitThought(response.Content)
+		if thought != "" {
+			thoughts = append(thoughts, thought)
+			run.SetMetadata(MetadataThoughts, thoughts)
+			run.AddThinking(ThinkingThought, thought)
+			run.Emit(ctx, Event{Type: EventThought, Data: thought})
+		}
+
+		if len(response.ToolCalls) > 0 && !finalAnswer {
+			toolMessages := run.ExecuteTools(ctx, response)
+			if run.HandedOff() {
+				return "", nil
+			}
+			messages = append(messages, toolMessages...)
+			if err := run.IterationComplete(ctx, messages); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		messages = append(messages, llm.Message{Role: "assistant", Content: response.Content})
+		if err := run.IterationComplete(ctx, messages); err != nil {
+			return "", err
+		}
+		return answer, nil
+	}
+
+	// Out of iterations, keep what the run has so far
+	if run.MaxIterationsPolicy() == MaxIterationsPartial {
+		run.Logger().WarnContext(ctx, "maximum iterations reached, returning partial result",
+			slog.Int("max_iterations", run.MaxIterations()))
+		run.SetStopReason(StopReasonMaxIterations)
+		run.SetPartial(messages[promptLength:])
+		_, answer := splitThought(lastAssistantText(messages[promptLength:]))
+		return answer, nil
+	}
+	return "", ErrMaxIterationsExceeded
+}
+
+// splitThought separates the thought of a ReAct reply from its answer. A
+// reply without an answer marker is all answer, minus a leading thought
+// marker.
+func splitThought(content string) (thought, answer string) {
+	text := strings.TrimSpace(content)
+	hasThought := strings.HasPrefix(text, "Thought:")
+	text = strings.TrimSpace(strings.TrimPrefix(text, "Thought:"))
+
+	if i := strings.Index(text, "Answer:"); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+len("Answer:"):])
+	}
+	if hasThought {
+		return text, text
+	}
+	return "", text
+}
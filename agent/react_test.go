@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func TestReAct_RecordsThoughts(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		{
+			Content:   "Thought: I need to look this up",
+			ToolCalls: []tool.Call{{ID: "1", Function: tool.FunctionCall{Name: "lookup", Arguments: `{}`}}},
+		},
+		textResponse("Thought: the lookup says 42\nAnswer: It is 42."),
+	}}
+	recorder := &eventRecorder{}
+	agent, err := NewBuilder().WithLLM(model).WithTools(&MockTool{name: "lookup"}).WithStrategy(ReAct()).WithHooks(recorder).Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	response, err := agent.Execute(context.Background(), Request{Input: "what is it?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "It is 42." {
+		t.Errorf("Expected the answer without the thought, got %q", response.Output)
+	}
+	thoughts, _ := response.Metadata[MetadataThoughts].([]string)
+	if len(thoughts) != 2 || thoughts[1] != "the lookup says 42" {
+		t.Errorf("Expected 2 thoughts, got %v", response.Metadata[MetadataThoughts])
+	}
+	if len(recorder.events) != 2 || recorder.events[0].Type != EventThought {
+		t.Errorf("Expected 2 thought events, got %+v", recorder.events)
+	}
+	if last := model.requests[0].Messages[len(model.requests[0].Messages)-1]; last.Content != reactPrompt {
+		t.Errorf("Expected ReAct instruction, got %+v", last)
+	}
+}
+
+func TestSplitThought(t *testing.T) {
+	tests := []struct {
+		content, thought, answer string
+	}{
+		{"Thought: a\nAnswer: b", "a", "b"},
+		{"Thought: only thinking", "only thinking", "only thinking"},
+		{"plain answer", "", "plain answer"},
+	}
+	for _, tt := range tests {
+		thought, answer := splitThought(tt.content)
+		if thought != tt.thought || answer != tt.answer {
+			t.Errorf("Expected (%q, %q) for %q, got (%q, %q)", tt.thought, tt.answer, tt.content, thought, answer)
+		}
+	}
+}
+
+func TestReAct_MaxIterationsSummarize(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		{Content: "Thought: I need to look this up", ToolCalls: lookupCall(llm.Usage{}).ToolCalls},
+		textResponse("Thought: the lookup was enough\nAnswer: It is 42."),
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{Strategy: ReAct(), MaxIterations: 2, MaxIterationsPolicy: MaxIterationsSummarize})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "what is it?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "It is 42." {
+		t.Errorf("Expected the summarized answer, got %q", response.Output)
+	}
+	if len(model.requests[1].Tools) != 0 {
+		t.Errorf("Expected the last iteration without tools, got %d", len(model.requests[1].Tools))
+	}
+	if response.Metadata["stop_reason"] != StopReasonMaxIterations {
+		t.Errorf("Expected stop reason %q, got %v", StopReasonMaxIterations, response.Metadata["stop_reason"])
+	}
+}
+
+func TestReAct_MaxIterationsPartial(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		{Content: "Thought: I need to look this up", ToolCalls: lookupCall(llm.Usage{}).ToolCalls},
+		{Content: "Thought: the first lookup was not enough", ToolCalls: lookupCall(llm.Usage{}).ToolCalls},
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{Strategy: ReAct(), MaxIterations: 2, MaxIterationsPolicy: MaxIterationsPartial})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "what is it?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if !response.Partial || response.Output != "the first lookup was not enough" {
+		t.Errorf("Expected a partial result with the last thought, got %q (partial %v)", response.Output, response.Partial)
+	}
+	if transcript, _ := response.Metadata["transcript"].([]llm.Message); len(transcript) != 4 {
+		t.Errorf("Expected 2 tool calls and results in the transcript, got %+v", response.Metadata["transcript"])
+	}
+	if response.Metadata["stop_reason"] != StopReasonMaxIterations {
+		t.Errorf("Expected stop reason %q, got %v", StopReasonMaxIterations, response.Metadata["stop_reason"])
+	}
+}
+
+func TestReAct_ThoughtsDoNotCountAgainstHistoryLimit(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		{Content: "Thought: I need to look this up", ToolCalls: lookupCall(llm.Usage{}).ToolCalls},
+		textResponse("Thought: the lookup says 42\nAnswer: It is 42."),
+		textResponse("Thought: I already know\nAnswer: Still 42."),
+	}}
+	engine := newBudgetEngine(t, model, EngineConfig{Strategy: ReAct(), HistoryLimit: 2})
+
+	first, err := engine.Execute(context.Background(), Request{Input: "what is it?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, err := engine.Execute(context.Background(), Request{Input: "are you sure?", SessionID: first.SessionID}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// The two thoughts saved by the first run are not replayed and do not
+	// push its question out of the history
+	var replayed []string
+	for _, message := range model.requests[2].Messages {
+		if message.Role != "system" {
+			replayed = append(replayed, message.Role+": "+message.Content)
+		}
+	}
+	expected := []string{"user: what is it?", "assistant: It is 42.", "user: are you sure?"}
+	if fmt.Sprint(replayed) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, replayed)
+	}
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/redact"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// scriptedModel returns its responses in order and records every request
+type scriptedModel struct {
+	responses []*llm.Response
+	requests  []llm.Request
+}
+
+func (m *scriptedModel) Complete(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	m.requests = append(m.requests, request)
+	response := m.responses[0]
+	m.responses = m.responses[1:]
+	return response, nil
+}
+
+// recordingTool records the parameters it was called with
+type recordingTool struct {
+	MockTool
+	params map[string]any
+}
+
+func (r *recordingTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	r.params = params
+	return r.MockTool.Execute(ctx, params)
+}
+
+func TestExecute_RedactsPII(t *testing.T) {
+	store := memory.NewStore()
+	registry := tool.NewRegistry()
+	lookup := &recordingTool{MockTool: MockTool{name: "lookup", result: "ticket owner: carol@example.com"}}
+	registry.Register(lookup)
+
+	model := &scriptedModel{responses: []*llm.Response{
+		{
+			ToolCalls: []tool.Call{{
+				ID:       "call_1",
+				Function: tool.FunctionCall{Name: "lookup", Arguments: `{"input":"[EMAIL_1]"}`},
+			}},
+		},
+		{Content: "I emailed [EMAIL_1] and [EMAIL_2]", FinishReason: "stop"},
+	}}
+
+	engine, err := NewEngine(EngineConfig{
+		Model:        model,
+		SessionStore: store,
+		ToolRegistry: registry,
+		Redactor:     redact.New(redact.WithMode(redact.Tokenize)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	response, err := engine.Execute(context.Background(), Request{Input: "My email is bob@example.com"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// The model only sees tokens
+	for _, request := range model.requests {
+		for _, message := range request.Messages {
+			if strings.Contains(message.Content, "@example.com") {
+				t.Errorf("Expected no email in %s message, got %q", message.Role, message.Content)
+			}
+		}
+	}
+	last := model.requests[1].Messages
+	if toolMessage := last[len(last)-1]; !strings.Contains(toolMessage.Content, "[EMAIL_2]") {
+		t.Errorf("Expected tool result to be tokenized, got %q", toolMessage.Content)
+	}
+
+	// The tool gets the real value
+	if lookup.params["input"] != "bob@example.com" {
+		t.Errorf("Expected tool argument bob@example.com, got %v", lookup.params["input"])
+	}
+
+	// The caller gets the real values back
+	if response.Output != "I emailed bob@example.com and carol@example.com" {
+		t.Errorf("Expected restored output, got %q", response.Output)
+	}
+
+	// History only holds tokens
+	for _, entry := range response.Session.GetHistory(0) {
+		content, _ := session.GetMessageContent(entry)
+		if strings.Contains(content.Text, "@example.com") {
+			t.Errorf("Expected no email in stored %s entry, got %q", content.Role, content.Text)
+		}
+	}
+}
+
+func TestExecute_NoRedactorKeepsInput(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{{Content: "ok", FinishReason: "stop"}}}
+
+	engine, err := NewEngine(EngineConfig{Model: model})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if _, err := engine.Execute(context.Background(), Request{Input: "bob@example.com"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	messages := model.requests[0].Messages
+	if !strings.Contains(messages[len(messages)-1].Content, "bob@example.com") {
+		t.Errorf("Expected input unchanged without a redactor, got %q", messages[len(messages)-1].Content)
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/davidleitw/go-agent/llm"
+)
+
+// MetadataCritiques is the Response.Metadata key holding the critiques of
+// a reflecting run, in order
+const MetadataCritiques = "critiques"
+
+// defaultRubric is used when a Reflection has no rubric
+const defaultRubric = "The answer is correct, complete and clear, and addresses everything the user asked."
+
+// Reflection reviews the final answer against a rubric before it is
+// returned, and has the agent revise it when the review finds problems
+type Reflection struct {
+	// Critic reviews the answers (default the agent's model)
+	Critic llm.Model
+
+	// Rubric is what a good answer must satisfy (default: correct,
+	// complete and clear)
+	Rubric string
+
+	// MaxRevisions limits the revisions (default 2, negative to only review)
+	MaxRevisions int
+}
+
+// Critique is the review of an answer
+type Critique struct {
+	// Revision is the answer reviewed: 0 for the first answer, then 1, 2...
+	Revision int    `json:"revision"`
+	Approved bool   `json:"approved"`
+	Feedback string `json:"feedback,omitempty"`
+}
+
+// critiquePrompt asks the critic to review the last answer against rubric
+func critiquePrompt(rubric string) string {
+	return "Review the last assistant answer against the user's request and this rubric:\n" + rubric + "\n\n" +
+		"If the answer meets the rubric, reply with only APPROVED. " +
+		"Otherwise list the problems that must be fixed."
+}
+
+// Reflexion returns a strategy that runs inner, has the model critique the
+// answer and runs inner again with the critique until the answer is
+// approved, at most maxRevisions times (0 uses the default of 2). inner
+// defaults to DefaultStrategy. Use Reflect for a separate critic or a rubric.
+func Reflexion(inner Strategy, maxRevisions int) Strategy {
+	return Reflect(inner, Reflection{MaxRevisions: maxRevisions})
+}
+
+// Reflect returns a strategy that runs inner and reviews its answer as
+// configured by reflection. Each review is emitted as an EventCritique
+// event and returned in Response.Metadata["critiques"]; rejected drafts
+// and their critiques are saved to the session as thinking entries. inner
+// defaults to DefaultStrategy.
+func Reflect(inner Strategy, reflection Reflection) Strategy {
+	if inner == nil {
+		inner = DefaultStrategy()
+	}
+	if reflection.Rubric == "" {
+		reflection.Rubric = defaultRubric
+	}
+	if reflection.MaxRevisions == 0 {
+		reflection.MaxRevisions = 2
+	}
+	return reflectionStrategy{inner: inner, reflection: reflection}
+}
+
+type reflectionStrategy struct {
+	inner      Strategy
+	reflection Reflection
+}
+
+func (s reflectionStrategy) Execute(ctx context.Context, run *Run) (string, error) {
+	if run.engine.checkpointing {
+		return "", finalError{errCheckpointingReflection}
+	}
+
+	messages := run.Messages()
+	answer, err := s.inner.Execute(ctx, run)
+	if err != nil {
+		return "", err
+	}
+
+	var critiques []Critique
+	for revision := 0; ; revision++ {
+		if answer == "" || run.HandedOff() {
+			return answer, nil
+		}
+		low, err := run.CheckBudget(ctx)
+		if err != nil {
+			return "", err
+		}
+		if low {
+			return answer, nil
+		}
+
+		reviewed := append(messages[:len(messages):len(messages)], llm.Message{Role: "assistant", Content: answer})
+		request := run.TextRequest(append(reviewed[:len(reviewed):len(reviewed)], llm.Message{
+			Role:    "system",
+			Content: critiquePrompt(s.reflection.Rubric),
+		}))
+		var response *llm.Response
+		if s.reflection.Critic != nil {
+			response, err = run.engine.completeWith(ctx, run.state, s.reflection.Critic, request)
+		} else {
+			response, err = run.Complete(ctx, request)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		critique := parseCritique(response.Content)
+		critique.Revision = revision
+		critiques = append(critiques, critique)
+		run.SetMetadata(MetadataCritiques, critiques)
+		run.Emit(ctx, Event{Type: EventCritique, Data: critique})
+		if critique.Approved {
+			return answer, nil
+		}
+
+		// The rejected draft and its critique are kept out of the conversation
+		run.AddThinking(ThinkingCritique, fmt.Sprintf("Draft:\n%s\n\nCritique:\n%s", answer, critique.Feedback))
+		if revision >= s.reflection.MaxRevisions {
+			return answer, nil
+		}
+
+		revise := append(reviewed, llm.Message{
+			Role:    "system",
+			Content: "A review found these problems with your answer:\n" + critique.Feedback + "\n\nAnswer the request again, fixing them.",
+		})
+		answer, err = s.inner.Execute(ctx, run.WithMessages(revise))
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// parseCritique reads a critique from the reviewer's reply
+func parseCritique(text string) Critique {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(strings.ToUpper(text), "APPROVED") {
+		return Critique{Approved: true}
+	}
+	return Critique{Feedback: text}
+}
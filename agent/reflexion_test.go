@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+)
+
+func TestReflexion_RevisesUntilApproved(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse("Paris is in Germany"),
+		textResponse("Paris is in France, not Germany"),
+		textResponse("Paris is in France"),
+		textResponse("APPROVED"),
+	}}
+	agent, err := NewBuilder().WithLLM(model).WithStrategy(Reflexion(nil, 0)).Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	response, err := agent.Execute(context.Background(), Request{Input: "Where is Paris?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "Paris is in France" {
+		t.Errorf("Expected revised answer, got %q", response.Output)
+	}
+	critiques, _ := response.Metadata[MetadataCritiques].([]Critique)
+	if len(critiques) != 2 || critiques[0].Approved || !critiques[1].Approved {
+		t.Fatalf("Expected a rejection then an approval, got %+v", response.Metadata[MetadataCritiques])
+	}
+
+	// The revision sees the draft and the critique
+	revision := model.requests[2].Messages
+	if revision[len(revision)-2].Content != "Paris is in Germany" {
+		t.Errorf("Expected the draft before the critique, got %+v", revision[len(revision)-2])
+	}
+	if last := model.requests[1].Messages[len(model.requests[1].Messages)-1]; last.Content != critiquePrompt(defaultRubric) {
+		t.Errorf("Expected critique instruction, got %+v", last)
+	}
+}
+
+func TestReflexion_StopsAfterMaxRevisions(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse("draft"),
+		textResponse("too short"),
+		textResponse("second draft"),
+		textResponse("still too short"),
+	}}
+	agent, err := NewBuilder().WithLLM(model).WithStrategy(Reflexion(nil, 1)).Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	response, err := agent.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.Output != "second draft" {
+		t.Errorf("Expected the last revision, got %q", response.Output)
+	}
+	if len(model.requests) != 4 {
+		t.Errorf("Expected 4 LLM calls, got %d", len(model.requests))
+	}
+}
+
+func TestReflection_SeparateCriticAndRubric(t *testing.T) {
+	store := memory.NewStore()
+	model := &scriptedModel{responses: []*llm.Response{
+		textResponse("Revenue grew"),
+		textResponse("Revenue grew 12% year over year"),
+	}}
+	critic := &scriptedModel{responses: []*llm.Response{
+		{Content: "No numbers are given", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 7}},
+		textResponse("APPROVED"),
+	}}
+	agent, err := NewBuilder().
+		WithLLM(model).
+		WithSessionStore(store).
+		WithReflection(Reflection{Critic: critic, Rubric: "Every claim is backed by a number."}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	response, err := agent.Execute(context.Background(), Request{Input: "How did revenue do?"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "Revenue grew 12% year over year" {
+		t.Errorf("Expected revised answer, got %q", response.Output)
+	}
+	if len(model.requests) != 2 || len(critic.requests) != 2 {
+		t.Errorf("Expected 2 calls to each model, got %d and %d", len(model.requests), len(critic.requests))
+	}
+	if last := critic.requests[0].Messages[len(critic.requests[0].Messages)-1]; !strings.Contains(last.Content, "Every claim is backed by a number.") {
+		t.Errorf("Expected the rubric in the critique prompt, got %q", last.Content)
+	}
+	if response.Usage.LLMTokens.TotalTokens != 7 {
+		t.Errorf("Expected critic usage to be counted, got %d", response.Usage.LLMTokens.TotalTokens)
+	}
+
+	critiques, _ := response.Metadata[MetadataCritiques].([]Critique)
+	if len(critiques) != 2 || critiques[0].Feedback != "No numbers are given" || critiques[1].Revision != 1 {
+		t.Errorf("Expected 2 critiques, got %+v", response.Metadata[MetadataCritiques])
+	}
+
+	// The rejected draft is saved as thinking, between question and answer
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	history := sess.GetHistory(10)
+	if len(history) != 3 || history[1].Type != session.EntryTypeThinking {
+		t.Fatalf("Expected user, thinking and assistant entries, got %+v", history)
+	}
+	thinking, _ := session.GetThinkingContent(history[1])
+	if !strings.Contains(thinking, "Revenue grew") || !strings.Contains(thinking, "No numbers are given") {
+		t.Errorf("Expected draft and critique in thinking entry, got %q", thinking)
+	}
+	if history[1].Metadata["kind"] != ThinkingCritique {
+		t.Errorf("Expected critique thinking, got %v", history[1].Metadata["kind"])
+	}
+}
@@ -0,0 +1,377 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	agentcontext "github.com/davidleitw/go-agent/context"
+	"github.com/davidleitw/go-agent/guardrail"
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// runState is the state of one run, shared by the strategies
+type runState struct {
+	request Request
+	session session.Session
+	logger  *slog.Logger
+	start   time.Time
+	budget  *budgetTracker
+
+	usage         Usage
+	iterations    int // LLM calls made
+	executedTools []ToolResult
+	childSessions []string
+	thinking      []session.Entry
+
+	// checkpoint is the checkpoint a resumed run continues from
+	checkpoint *Checkpoint
+
+	// How the run ended, set by the strategy
+	stopReason string
+	budgetStop *BudgetExceededError
+	handoff    *handoffCall
+	partial    bool
+	transcript []llm.Message
+
+	// metadata holds strategy-specific Response.Metadata entries
+	metadata map[string]any
+}
+
+func (e *engine) newRunState(request Request, agentSession session.Session) *runState {
+	return &runState{
+		request:  request,
+		session:  agentSession,
+		logger:   e.logger.With(slog.String("session_id", agentSession.ID())),
+		start:    time.Now(),
+		budget:   newBudgetTracker(e.runBudget, e.sessionBudget, agentSession),
+		metadata: make(map[string]any),
+	}
+}
+
+// execute runs the configured strategy and saves its result. A resumed
+// run continues from checkpoint instead of rendering the prompt.
+func (e *engine) execute(ctx context.Context, request Request, contexts []agentcontext.Context, agentSession session.Session, checkpoint *Checkpoint) (result *ExecutionResult, err error) {
+	run := e.newRunState(request, agentSession)
+	defer func() {
+		// A run that would fail the same way again leaves nothing to resume
+		if err != nil && !resumable(err) {
+			e.clearCheckpoint(ctx, run)
+		}
+		e.recordRun(run.iterations, run.stopReason == StopReasonMaxIterations, err)
+		reportAgentRun(ctx, run.usage, agentSession.ID())
+	}()
+
+	var messages []llm.Message
+	if checkpoint != nil {
+		// The prompt was rendered when the run started
+		run.checkpoint = checkpoint
+		run.iterations = checkpoint.Iteration
+		run.usage = checkpoint.Usage
+		messages = checkpoint.Messages[:checkpoint.PromptLength]
+	} else {
+		// Build initial messages from contexts and user input
+		messages = e.buildLLMMessages(contexts, request)
+		if from, ok := handoffFromContext(ctx); ok {
+			messages = append(messages, handoffMessage(from))
+		}
+		messages, err = e.hooks.promptRendered(ctx, agentSession, messages)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.saveCheckpoint(ctx, run, Checkpoint{Messages: messages, PromptLength: len(messages)}); err != nil {
+			return nil, err
+		}
+	}
+
+	// The duration budgets are hard limits, even on a hung LLM or tool call
+	strategyCtx := ctx
+	if deadline, limit, ok := run.budget.deadline(); ok {
+		var cancel context.CancelFunc
+		strategyCtx, cancel = context.WithDeadlineCause(ctx, deadline, limit)
+		defer cancel()
+	}
+
+	output, err := e.strategy.Execute(strategyCtx, &Run{engine: e, state: run, messages: messages})
+	if err != nil {
+		if cause := context.Cause(strategyCtx); errors.Is(cause, ErrBudgetExceeded) && ctx.Err() == nil {
+			run.logger.WarnContext(ctx, "budget exceeded", slog.Any("error", cause))
+			return nil, cause
+		}
+		return nil, err
+	}
+
+	// A final answer that came back empty leaves only the budget error
+	if output == "" && run.budgetStop != nil && run.handoff == nil {
+		run.logger.WarnContext(ctx, "budget exceeded", slog.Any("error", run.budgetStop))
+		return nil, run.budgetStop
+	}
+
+	return e.finish(ctx, run, output)
+}
+
+// complete makes one LLM call to the agent's model
+func (e *engine) complete(ctx context.Context, run *runState, llmRequest llm.Request) (*llm.Response, error) {
+	return e.completeWith(ctx, run, e.model, llmRequest)
+}
+
+// completeWith makes one LLM call to model with hooks, tracing, metrics,
+// logging, usage and cost accounting
+func (e *engine) completeWith(ctx context.Context, run *runState, model llm.Model, llmRequest llm.Request) (*llm.Response, error) {
+	run.iterations++
+	iteration := run.iterations
+
+	if err := e.hooks.beforeLLMCall(ctx, run.session, iteration, &llmRequest); err != nil {
+		return nil, err
+	}
+
+	run.logger.DebugContext(ctx, "calling LLM",
+		slog.Int("iteration", iteration),
+		slog.Int("messages", len(llmRequest.Messages)),
+		slog.Int("tools", len(llmRequest.Tools)))
+
+	callStart := time.Now()
+	llmCtx, llmSpan := e.startLLMSpan(ctx, model, iteration, llmRequest)
+	response, err := model.Complete(llmCtx, llmRequest)
+	endLLMSpan(llmSpan, response, err)
+	e.recordLLMCall(model, response, err, time.Since(callStart))
+	if err != nil {
+		run.logger.ErrorContext(ctx, "LLM call failed",
+			slog.Int("iteration", iteration),
+			slog.Duration("duration", time.Since(callStart)),
+			slog.Any("error", err))
+		return nil, fmt.Errorf("LLM call failed at iteration %d: %w", iteration, err)
+	}
+	if err := e.hooks.afterLLMCall(ctx, run.session, iteration, response); err != nil {
+		return nil, err
+	}
+
+	// Update usage tracking
+	run.usage.LLMTokens.PromptTokens += response.Usage.PromptTokens
+	run.usage.LLMTokens.CachedPromptTokens += response.Usage.CachedPromptTokens
+	run.usage.LLMTokens.CompletionTokens += response.Usage.CompletionTokens
+	run.usage.LLMTokens.TotalTokens += response.Usage.TotalTokens
+
+	var cost float64
+	if e.pricing != nil {
+		var priced bool
+		cost, priced = e.callCost(model, response)
+		if !priced {
+			run.logger.WarnContext(ctx, "no price for model, counting cost as zero",
+				slog.String("model", cmp.Or(response.Model, modelName(model))))
+		}
+		run.usage.Cost += cost
+		run.usage.IterationCosts = append(run.usage.IterationCosts, cost)
+	}
+	run.budget.observeCall(response, cost, time.Since(callStart))
+
+	run.logger.DebugContext(ctx, "LLM call completed",
+		slog.Int("iteration", iteration),
+		slog.Duration("duration", time.Since(callStart)),
+		slog.String("finish_reason", response.FinishReason),
+		slog.Int("tool_calls", len(response.ToolCalls)),
+		slog.Int("prompt_tokens", response.Usage.PromptTokens),
+		slog.Int("completion_tokens", response.Usage.CompletionTokens),
+		slog.Int("total_tokens", response.Usage.TotalTokens))
+
+	return response, nil
+}
+
+// runTools executes the tool calls of a response and returns the messages
+// to add to the conversation: the assistant's calls and the tool results
+func (e *engine) runTools(ctx context.Context, run *runState, response *llm.Response) ([]llm.Message, []ToolResult) {
+	toolMessages, toolResults := e.runToolCalls(ctx, run, response.ToolCalls)
+	return append([]llm.Message{assistantToolMessage(response)}, toolMessages...), toolResults
+}
+
+// assistantToolMessage returns the assistant message carrying the tool
+// calls of a response
+func assistantToolMessage(response *llm.Response) llm.Message {
+	// When there are tool calls, content might be empty, but we still need the assistant message
+	assistantContent := response.Content
+	if assistantContent == "" {
+		assistantContent = " " // OpenAI API requires non-empty content
+	}
+	return llm.Message{
+		Role:      "assistant",
+		Content:   assistantContent,
+		ToolCalls: response.ToolCalls,
+	}
+}
+
+// runToolCalls executes tool calls and returns their result messages.
+// Calls beyond the tool-call budget are not run; their result reports the
+// exceeded budget.
+func (e *engine) runToolCalls(ctx context.Context, run *runState, calls []tool.Call) ([]llm.Message, []ToolResult) {
+	var skipped []ToolResult
+	if remaining, limit := run.budget.remainingToolCalls(run.usage); remaining >= 0 && len(calls) > remaining {
+		run.logger.InfoContext(ctx, "tool call budget reached, skipping tool calls",
+			slog.String("scope", limit.Scope),
+			slog.Int("skipped", len(calls)-remaining))
+		for _, call := range calls[remaining:] {
+			skipped = append(skipped, ToolResult{Call: call, Error: limit})
+		}
+		calls = calls[:remaining]
+	}
+
+	toolResults := append(e.executeTools(ctx, run.session, calls), skipped...)
+	run.usage.ToolCalls += len(calls)
+	run.executedTools = append(run.executedTools, toolResults...)
+
+	messages := make([]llm.Message, 0, len(toolResults))
+	for _, result := range toolResults {
+		run.usage = run.usage.add(result.Usage)
+		run.childSessions = append(run.childSessions, result.ChildSessionIDs...)
+
+		toolMessage := e.formatToolResult(result)
+		if e.redactor != nil {
+			toolMessage.Content = e.redactor.Redact(run.session, toolMessage.Content)
+		}
+		messages = append(messages, toolMessage)
+	}
+
+	return messages, toolResults
+}
+
+// finish runs the output guardrails, saves the conversation and builds the
+// result of a run
+func (e *engine) finish(ctx context.Context, run *runState, output string) (*ExecutionResult, error) {
+	agentSession := run.session
+	logger := run.logger
+
+	// Output guardrails see the answer before it is saved or returned
+	var outputOutcome guardrail.Outcome
+	if len(e.outputGuardrails) > 0 && output != "" {
+		var err error
+		outputOutcome, err = e.checkGuardrails(ctx, agentSession, guardrail.StageOutput, e.outputGuardrails, output)
+		if err != nil {
+			return nil, finalError{err}
+		}
+		output = outputOutcome.Text
+		if outputOutcome.Blocked != nil {
+			output = outputOutcome.Blocked.Message
+		}
+	}
+
+	// Save conversation to session and persist it; the run no longer
+	// needs its checkpoint
+	agentSession.Delete(StateKeyCheckpoint)
+	addSessionUsage(agentSession, run.usage, time.Since(run.start), e.pricing != nil)
+	// A run that stopped early keeps its tool results, so the user can
+	// ask it to continue
+	var persistedTools []ToolResult
+	if run.stopReason != "" {
+		persistedTools = run.executedTools
+	}
+	var err error
+	// A handoff leaves the conversation to the agent that takes over
+	if run.handoff == nil {
+		err = e.saveConversationToSession(ctx, agentSession, run.request.Input, persistedTools, run.thinking, output)
+	}
+	if err == nil {
+		saveStart := time.Now()
+		err = e.sessionStore.Save(ctx, agentSession)
+		e.metrics.RecordStoreOperation(metrics.StoreSave, metrics.Status(err), time.Since(saveStart))
+	}
+	if errors.Is(err, session.ErrVersionConflict) {
+		// Someone outside the session lock saved this session; our writes may be lost
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	if err != nil {
+		// Log error but don't fail the entire execution
+		logger.WarnContext(ctx, "failed to save conversation to session", slog.Any("error", err))
+	} else {
+		run.usage.SessionWrites++
+	}
+
+	logger.InfoContext(ctx, "agent run completed",
+		slog.Duration("duration", time.Since(run.start)),
+		slog.Int("tool_calls", run.usage.ToolCalls),
+		slog.Int("prompt_tokens", run.usage.LLMTokens.PromptTokens),
+		slog.Int("completion_tokens", run.usage.LLMTokens.CompletionTokens),
+		slog.Int("total_tokens", run.usage.LLMTokens.TotalTokens),
+		slog.Float64("cost_usd", run.usage.Cost))
+
+	// Return execution result
+	metadata := map[string]any{
+		"total_iterations": run.iterations, // LLM calls, whatever the strategy
+		"tools_called":     run.usage.ToolCalls,
+		"completion_time":  time.Now(),
+	}
+	for key, value := range run.metadata {
+		metadata[key] = value
+	}
+	if run.stopReason != "" {
+		metadata["stop_reason"] = run.stopReason
+	}
+	if e.name != "" {
+		metadata[MetadataAgent] = e.name
+	}
+	if run.childSessions != nil {
+		metadata[MetadataChildSessions] = run.childSessions
+	}
+	if run.handoff != nil {
+		metadata[MetadataHandoffTo] = run.handoff.agent
+		metadata[MetadataHandoffReason] = run.handoff.reason
+	}
+	if outputOutcome.Records != nil {
+		metadata[MetadataGuardrails] = outputOutcome.Records
+	}
+	if outputOutcome.Blocked != nil {
+		metadata[MetadataGuardrailBlocked] = outputOutcome.Blocked.Guardrail
+	}
+	if run.budgetStop != nil {
+		metadata["budget_scope"] = run.budgetStop.Scope
+		metadata["budget_limit"] = run.budgetStop.Limit
+	}
+	if run.partial {
+		metadata["transcript"] = e.transcript(agentSession, run.transcript)
+	}
+
+	return &ExecutionResult{
+		FinalOutput: output,
+		SessionID:   agentSession.ID(),
+		Session:     agentSession,
+		Usage:       run.usage,
+		Metadata:    metadata,
+		Partial:     run.partial,
+	}, nil
+}
+
+// toolRequest builds an LLM request that offers the agent's tools
+func (e *engine) toolRequest(messages []llm.Message) llm.Request {
+	return llm.Request{
+		Messages:    messages,
+		Tools:       append(e.toolRegistry.GetDefinitions(), handoffDefinitions(e.handoffs)...),
+		Temperature: e.temperature,
+		MaxTokens:   e.maxTokens,
+	}
+}
+
+// textRequest builds an LLM request without tools
+func (e *engine) textRequest(messages []llm.Message) llm.Request {
+	return llm.Request{
+		Messages:    messages,
+		Temperature: e.temperature,
+		MaxTokens:   e.maxTokens,
+	}
+}
+
+// finalAnswerRequest builds an LLM request without tools that asks the
+// model to answer with what it has, within the remaining budget
+func (e *engine) finalAnswerRequest(run *runState, messages []llm.Message) llm.Request {
+	llmRequest := e.textRequest(append(messages[:len(messages):len(messages)], llm.Message{
+		Role:    "system",
+		Content: finalAnswerPrompt,
+	}))
+	if remaining := run.budget.completionTokens(run.usage); remaining > 0 && (llmRequest.MaxTokens == nil || remaining < *llmRequest.MaxTokens) {
+		llmRequest.MaxTokens = &remaining
+	}
+	return llmRequest
+}
@@ -2,9 +2,14 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
 	"github.com/davidleitw/go-agent/session/memory"
 )
 
@@ -57,6 +62,17 @@ func TestHandleSession_CreateNew(t *testing.T) {
 	if startTime == "" {
 		t.Error("Expected session_start_time to be non-empty")
 	}
+
+	// Engine metadata and TTL are visible through the session
+	if createdBy, _ := sess.GetMetadata("created_by"); createdBy != "agent" {
+		t.Errorf("Expected created_by metadata to be agent, got %q", createdBy)
+	}
+	if _, exists := sess.GetMetadata("agent_version"); !exists {
+		t.Error("Expected agent_version metadata to be set")
+	}
+	if sess.ExpiresAt() == nil || time.Until(*sess.ExpiresAt()) > time.Hour {
+		t.Errorf("Expected session to expire within the configured TTL, got %v", sess.ExpiresAt())
+	}
 }
 
 func TestHandleSession_LoadExisting(t *testing.T) {
@@ -204,3 +220,316 @@ func TestBuilder_WithSessionTTL(t *testing.T) {
 		t.Errorf("Expected TTL %v, got %v", customTTL, engine.sessionTTL)
 	}
 }
+
+func TestExecute_ReplaysHistoryOldestFirst(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		{Content: "First answer", FinishReason: "stop"},
+		{Content: "Second answer", FinishReason: "stop"},
+		{Content: "Third answer", FinishReason: "stop"},
+	}}
+	agent, err := NewEngine(EngineConfig{Model: model, HistoryLimit: 10})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	first, err := agent.Execute(context.Background(), Request{Input: "First question"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, input := range []string{"Second question", "Third question"} {
+		if _, err := agent.Execute(context.Background(), Request{Input: input, SessionID: first.SessionID}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// GetHistory is newest first; the prompt replays it in conversation order
+	var conversation []string
+	for _, message := range model.requests[2].Messages {
+		if message.Role != "system" {
+			conversation = append(conversation, message.Role+": "+message.Content)
+		}
+	}
+	expected := []string{
+		"user: First question", "assistant: First answer",
+		"user: Second question", "assistant: Second answer",
+		"user: Third question",
+	}
+	if fmt.Sprint(conversation) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, conversation)
+	}
+}
+
+func TestExecute_ForkAndContinue(t *testing.T) {
+	model := &MockModel{}
+	store := memory.NewStore()
+
+	agent, err := NewBuilder().
+		WithLLM(model).
+		WithSessionStore(store).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	// Two turns in the original session
+	first, err := agent.Execute(context.Background(), Request{Input: "First question"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := agent.Execute(context.Background(), Request{Input: "Second question", SessionID: first.SessionID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	original, _ := store.Get(context.Background(), first.SessionID)
+	history := original.GetHistory(0)
+	if len(history) != 4 {
+		t.Fatalf("Expected 4 entries in original session, got %d", len(history))
+	}
+
+	// Fork after the first answer and ask something else instead
+	firstAnswer := history[2]
+	forked, err := agent.Execute(context.Background(), Request{
+		Input:         "Edited second question",
+		SessionID:     first.SessionID,
+		ForkAtEntryID: firstAnswer.ID,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if forked.SessionID == first.SessionID {
+		t.Error("Expected fork to continue in a new session")
+	}
+
+	forkedHistory := forked.Session.GetHistory(0)
+	if len(forkedHistory) != 4 {
+		t.Fatalf("Expected 4 entries in forked session, got %d", len(forkedHistory))
+	}
+	if content, _ := session.GetMessageContent(forkedHistory[1]); content.Text != "Edited second question" {
+		t.Errorf("Expected edited question in fork, got %q", content.Text)
+	}
+	if forkedHistory[2].ID != firstAnswer.ID {
+		t.Error("Expected fork to keep history up to the fork point")
+	}
+
+	// The original branch is untouched
+	if len(original.GetHistory(0)) != 4 {
+		t.Error("Expected original session history to be unchanged")
+	}
+}
+
+func TestExecute_ForkRequiresSessionID(t *testing.T) {
+	engine, err := NewEngine(EngineConfig{Model: &MockModel{}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	_, err = engine.Execute(context.Background(), Request{Input: "Hello", ForkAtEntryID: "entry"})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+}
+
+// nonForkingStore hides the Fork method of the wrapped store
+type nonForkingStore struct {
+	session.SessionStore
+}
+
+func TestHandleSession_ForkNotSupported(t *testing.T) {
+	store := memory.NewStore()
+	existing := store.Create(context.Background())
+
+	engineInterface, err := NewEngine(EngineConfig{
+		Model:        &MockModel{},
+		SessionStore: nonForkingStore{store},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	_, err = engineInterface.(*engine).handleSession(context.Background(), Request{
+		Input:         "Hello",
+		SessionID:     existing.ID(),
+		ForkAtEntryID: "entry",
+	})
+	if !errors.Is(err, session.ErrForkNotSupported) {
+		t.Errorf("Expected ErrForkNotSupported, got %v", err)
+	}
+}
+
+// blockingModel signals each call on started and answers once release is closed
+type blockingModel struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingModel) Complete(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	m.started <- struct{}{}
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &llm.Response{Content: "reply to " + request.Messages[len(request.Messages)-1].Content, FinishReason: "stop"}, nil
+}
+
+func TestExecute_SerializesSameSession(t *testing.T) {
+	store := memory.NewStore()
+	model := &blockingModel{started: make(chan struct{}, 10), release: make(chan struct{})}
+
+	engine, err := NewEngine(EngineConfig{Model: model, SessionStore: store})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	sess := store.Create(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := engine.Execute(context.Background(), Request{
+				Input:     fmt.Sprintf("message %d", i),
+				SessionID: sess.ID(),
+			})
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}(i)
+	}
+
+	// Only one execution may reach the model at a time
+	<-model.started
+	select {
+	case <-model.started:
+		t.Fatal("Expected second execution to wait for the session lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(model.release)
+	wg.Wait()
+
+	// Every user message is directly followed by its own reply
+	history := sess.GetHistory(0)
+	if len(history) != 6 {
+		t.Fatalf("Expected 6 history entries, got %d", len(history))
+	}
+	for i := len(history) - 1; i > 0; i -= 2 {
+		user, _ := session.GetMessageContent(history[i])
+		reply, _ := session.GetMessageContent(history[i-1])
+		if reply.Text != "reply to "+user.Text {
+			t.Errorf("Expected %q to be answered next, got %q", user.Text, reply.Text)
+		}
+	}
+}
+
+func TestExecute_SerializesSameSessionAcrossEngines(t *testing.T) {
+	store := memory.NewStore()
+	model := &blockingModel{started: make(chan struct{}, 10), release: make(chan struct{})}
+
+	// Engines sharing a store share the default session locker
+	var engines []Engine
+	for i := 0; i < 2; i++ {
+		engine, err := NewEngine(EngineConfig{Model: model, SessionStore: store})
+		if err != nil {
+			t.Fatalf("Failed to create engine: %v", err)
+		}
+		engines = append(engines, engine)
+	}
+
+	sess := store.Create(context.Background())
+
+	var wg sync.WaitGroup
+	for i, engine := range engines {
+		wg.Add(1)
+		go func(i int, engine Engine) {
+			defer wg.Done()
+			_, err := engine.Execute(context.Background(), Request{
+				Input:     fmt.Sprintf("message %d", i),
+				SessionID: sess.ID(),
+			})
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}(i, engine)
+	}
+
+	<-model.started
+	select {
+	case <-model.started:
+		t.Fatal("Expected the second engine to wait for the session lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(model.release)
+	wg.Wait()
+
+	saved, _ := store.Get(context.Background(), sess.ID())
+	history := saved.GetHistory(0)
+	if len(history) != 4 {
+		t.Fatalf("Expected 4 history entries, got %d", len(history))
+	}
+	for i := len(history) - 1; i > 0; i -= 2 {
+		user, _ := session.GetMessageContent(history[i])
+		reply, _ := session.GetMessageContent(history[i-1])
+		if reply.Text != "reply to "+user.Text {
+			t.Errorf("Expected %q to be answered next, got %q", user.Text, reply.Text)
+		}
+	}
+}
+
+func TestExecute_SessionLockFailFast(t *testing.T) {
+	store := memory.NewStore()
+	model := &blockingModel{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	agent, err := NewBuilder().
+		WithLLM(model).
+		WithSessionStore(store).
+		WithSessionLockOptions(session.LockOptions{Mode: session.LockFailFast}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+
+	sess := store.Create(context.Background())
+
+	done := make(chan error)
+	go func() {
+		_, err := agent.Execute(context.Background(), Request{Input: "first", SessionID: sess.ID()})
+		done <- err
+	}()
+	<-model.started
+
+	_, err = agent.Execute(context.Background(), Request{Input: "second", SessionID: sess.ID()})
+	if !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Expected ErrSessionLocked, got %v", err)
+	}
+
+	close(model.release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected first execution to succeed, got %v", err)
+	}
+}
+
+// conflictingStore reports every save as a version conflict
+type conflictingStore struct {
+	session.SessionStore
+}
+
+func (s conflictingStore) Save(ctx context.Context, sess session.Session) error {
+	return session.ErrVersionConflict
+}
+
+func TestExecute_VersionConflict(t *testing.T) {
+	engine, err := NewEngine(EngineConfig{
+		Model:        &MockModel{},
+		SessionStore: conflictingStore{memory.NewStore()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	_, err = engine.Execute(context.Background(), Request{Input: "Hello"})
+	if !errors.Is(err, session.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// Strategy drives a run: it decides which LLM calls and tool calls to make
+// and returns the final answer. The engine prepares the session and prompt
+// before, and runs the output guardrails and saves the conversation after.
+type Strategy interface {
+	Execute(ctx context.Context, run *Run) (string, error)
+}
+
+// StrategyFunc adapts a function to the Strategy interface
+type StrategyFunc func(ctx context.Context, run *Run) (string, error)
+
+// Execute calls f(ctx, run)
+func (f StrategyFunc) Execute(ctx context.Context, run *Run) (string, error) {
+	return f(ctx, run)
+}
+
+// DefaultStrategy returns the engine's tool loop: the model calls tools
+// until it answers, up to MaxIterations times
+func DefaultStrategy() Strategy {
+	return loopStrategy{}
+}
+
+type loopStrategy struct{}
+
+func (loopStrategy) Execute(ctx context.Context, run *Run) (string, error) {
+	return run.engine.executeIterations(ctx, run.state, run.messages)
+}
+
+// Event is a progress report emitted by a strategy
+type Event struct {
+	Type string
+	Data any
+}
+
+// Event types emitted by the built-in strategies
+const (
+	EventThought     = "thought"      // Data is the thought text
+	EventPlanUpdated = "plan_updated" // Data is the Plan
+	EventCritique    = "critique"     // Data is the Critique
+)
+
+// EventHook receives the events emitted by strategies
+type EventHook interface {
+	OnEvent(ctx context.Context, sess session.Session, event Event)
+}
+
+// Run gives a Strategy access to the run: the request, session, model and
+// tools, and the engine's instrumented LLM and tool calls
+type Run struct {
+	engine   *engine
+	state    *runState
+	messages []llm.Message
+}
+
+// Request returns the request being run
+func (r *Run) Request() Request {
+	return r.state.request
+}
+
+// Session returns the session of the run
+func (r *Run) Session() session.Session {
+	return r.state.session
+}
+
+// Model returns the agent's model. Call it through Complete to keep hooks,
+// tracing, usage and budgets working.
+func (r *Run) Model() llm.Model {
+	return r.engine.model
+}
+
+// Tools returns the agent's tool registry
+func (r *Run) Tools() *tool.Registry {
+	return r.engine.toolRegistry
+}
+
+// Logger returns the logger of the run
+func (r *Run) Logger() *slog.Logger {
+	return r.state.logger
+}
+
+// Messages returns the rendered prompt: system message, history and user input
+func (r *Run) Messages() []llm.Message {
+	return slices.Clone(r.messages)
+}
+
+// WithMessages returns a handle to the same run with a different prompt,
+// for strategies that run another strategy on a modified conversation
+func (r *Run) WithMessages(messages []llm.Message) *Run {
+	return &Run{engine: r.engine, state: r.state, messages: messages}
+}
+
+// MaxIterations returns the configured iteration limit
+func (r *Run) MaxIterations() int {
+	return r.engine.maxIterations
+}
+
+// MaxIterationsPolicy returns what the run should do when it reaches
+// MaxIterations. Strategies apply it themselves, see SetPartial.
+func (r *Run) MaxIterationsPolicy() MaxIterationsPolicy {
+	return r.engine.maxIterationsPolicy
+}
+
+// Iterations returns the number of LLM calls made so far
+func (r *Run) Iterations() int {
+	return r.state.iterations
+}
+
+// ToolRequest builds an LLM request that offers the agent's tools and handoffs
+func (r *Run) ToolRequest(messages []llm.Message) llm.Request {
+	return r.engine.toolRequest(messages)
+}
+
+// TextRequest builds an LLM request without tools
+func (r *Run) TextRequest(messages []llm.Message) llm.Request {
+	return r.engine.textRequest(messages)
+}
+
+// FinalAnswerRequest builds an LLM request without tools that asks the
+// model to answer with what it has, within the remaining budget
+func (r *Run) FinalAnswerRequest(messages []llm.Message) llm.Request {
+	return r.engine.finalAnswerRequest(r.state, messages)
+}
+
+// Complete calls the model with hooks, tracing, metrics, usage and cost
+// accounting
+func (r *Run) Complete(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	return r.engine.complete(ctx, r.state, request)
+}
+
+// ExecuteTools runs the tool calls of a response and returns the messages
+// to append to the conversation: the assistant's calls and the results.
+// A handoff call is not run; it ends the run, see HandedOff.
+func (r *Run) ExecuteTools(ctx context.Context, response *llm.Response) []llm.Message {
+	if call, ok := r.engine.findHandoff(response.ToolCalls); ok {
+		r.state.logger.InfoContext(ctx, "handing off", slog.String("agent", call.agent))
+		r.state.handoff = call
+		return nil
+	}
+	messages, _ := r.engine.runTools(ctx, r.state, response)
+	return messages
+}
+
+// HandedOff reports whether the model handed the conversation to another
+// agent. The strategy should then return without an answer.
+func (r *Run) HandedOff() bool {
+	return r.state.handoff != nil
+}
+
+// CheckBudget enforces the budgets before an LLM call. It returns a
+// *BudgetExceededError when a budget is used up, and reports whether one
+// is running low, in which case the strategy should ask for a final answer.
+func (r *Run) CheckBudget(ctx context.Context) (bool, error) {
+	return r.engine.checkBudget(ctx, r.state)
+}
+
+// IterationComplete reports the conversation so far to the iteration
+// hooks. An error means a hook vetoed the run.
+func (r *Run) IterationComplete(ctx context.Context, messages []llm.Message) error {
+	return r.engine.hooks.iterationComplete(ctx, r.state.session, r.state.iterations, messages)
+}
+
+// Emit sends an event to the EventHooks
+func (r *Run) Emit(ctx context.Context, event Event) {
+	r.state.logger.DebugContext(ctx, "strategy event", slog.String("type", event.Type))
+	r.engine.hooks.event(ctx, r.state.session, event)
+}
+
+// SetMetadata sets a Response.Metadata entry
+func (r *Run) SetMetadata(key string, value any) {
+	r.state.metadata[key] = value
+}
+
+// Kinds of thinking entries, in the entry's "kind" metadata
+const (
+	ThinkingThought  = "thought"
+	ThinkingCritique = "critique"
+)
+
+// AddThinking records reasoning that is not part of the conversation. It
+// is saved to the session as a thinking entry, with kind in the entry's
+// "kind" metadata, before the answer.
+func (r *Run) AddThinking(kind, text string) {
+	entry := session.NewThinkingEntry(text)
+	entry.Metadata["kind"] = kind
+	r.state.thinking = append(r.state.thinking, entry)
+}
+
+// SetPartial marks the answer as partial, as MaxIterationsPartial asks.
+// messages are the messages the run added after its prompt, returned in
+// Response.Metadata["transcript"].
+func (r *Run) SetPartial(messages []llm.Message) {
+	r.state.partial = true
+	r.state.transcript = messages
+}
+
+// SetStopReason records why the run stopped early, returned in
+// Response.Metadata["stop_reason"]. Tool results of such runs are saved to
+// the session.
+func (r *Run) SetStopReason(reason string) {
+	r.state.stopReason = reason
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+)
+
+// eventRecorder records every emitted event
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) OnEvent(ctx context.Context, sess session.Session, event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestStrategy_Custom(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{
+		lookupCall(llm.Usage{TotalTokens: 10}),
+		{Content: "done", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 5}},
+	}}
+	recorder := &eventRecorder{}
+
+	// Always call tools once, then answer
+	strategy := StrategyFunc(func(ctx context.Context, run *Run) (string, error) {
+		messages := run.Messages()
+		response, err := run.Complete(ctx, run.ToolRequest(messages))
+		if err != nil {
+			return "", err
+		}
+		messages = append(messages, run.ExecuteTools(ctx, response)...)
+		run.Emit(ctx, Event{Type: "tools_done"})
+
+		response, err = run.Complete(ctx, run.TextRequest(messages))
+		if err != nil {
+			return "", err
+		}
+		run.SetMetadata("custom", true)
+		return response.Content, nil
+	})
+
+	agent, err := NewBuilder().WithLLM(model).WithTools(&MockTool{name: "lookup"}).WithStrategy(strategy).WithHooks(recorder).Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+	response, err := agent.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "done" {
+		t.Errorf("Expected output 'done', got %q", response.Output)
+	}
+	if response.Usage.LLMTokens.TotalTokens != 15 || response.Usage.ToolCalls != 1 {
+		t.Errorf("Expected 15 tokens and 1 tool call, got %+v", response.Usage)
+	}
+	if response.Metadata["custom"] != true {
+		t.Errorf("Expected custom metadata, got %v", response.Metadata)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Type != "tools_done" {
+		t.Errorf("Expected tools_done event, got %+v", recorder.events)
+	}
+	if model.requests[1].Messages[len(model.requests[1].Messages)-1].Role != "tool" {
+		t.Errorf("Expected tool result before the answer, got %+v", model.requests[1].Messages)
+	}
+}
+
+func TestStrategy_HandoffFromCustomStrategy(t *testing.T) {
+	model := &scriptedModel{responses: []*llm.Response{handoffCallResponse("billing", "refund")}}
+	strategy := StrategyFunc(func(ctx context.Context, run *Run) (string, error) {
+		response, err := run.Complete(ctx, run.ToolRequest(run.Messages()))
+		if err != nil {
+			return "", err
+		}
+		if messages := run.ExecuteTools(ctx, response); messages != nil {
+			t.Errorf("Expected no messages for a handoff, got %+v", messages)
+		}
+		if !run.HandedOff() {
+			t.Error("Expected the run to be handed off")
+		}
+		return "", nil
+	})
+
+	agent, err := NewBuilder().WithName("triage").WithLLM(model).WithHandoffs(Handoff{Agent: "billing"}).WithStrategy(strategy).Build()
+	if err != nil {
+		t.Fatalf("Failed to build agent: %v", err)
+	}
+	response, err := agent.Execute(context.Background(), Request{Input: "refund please"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.Metadata[MetadataHandoffTo] != "billing" {
+		t.Errorf("Expected handoff to billing, got %v", response.Metadata[MetadataHandoffTo])
+	}
+}
+
+func TestStrategies_CountIterationsAsLLMCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		responses []*llm.Response
+	}{
+		{"default", DefaultStrategy(), []*llm.Response{lookupCall(llm.Usage{}), textResponse("done")}},
+		{"react", ReAct(), []*llm.Response{lookupCall(llm.Usage{}), textResponse("Answer: done")}},
+		{"plan", PlanAndExecute(0), []*llm.Response{
+			textResponse(`{"steps": [{"description": "fetch"}]}`),
+			lookupCall(llm.Usage{}),
+			textResponse("DONE: fetched"),
+			textResponse("done"),
+		}},
+	}
+	for _, tt := range tests {
+		model := &scriptedModel{responses: tt.responses}
+		engine := newBudgetEngine(t, model, EngineConfig{Strategy: tt.strategy})
+
+		response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+		if err != nil {
+			t.Fatalf("%s: Execute failed: %v", tt.name, err)
+		}
+		if got := response.Metadata["total_iterations"]; got != len(model.requests) {
+			t.Errorf("%s: Expected total_iterations %d, got %v", tt.name, len(model.requests), got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/davidleitw/go-agent/tool"
+)
+
+// MetadataParentAgentSession is the session metadata key linking a child
+// session to the session of the agent that started it. It differs from
+// session.MetadataParentSessionID, which links a fork to its source.
+const MetadataParentAgentSession = "parent_agent_session_id"
+
+// MetadataChildSessions is the Response.Metadata key holding the IDs of
+// the child sessions started by agent tools during the run
+const MetadataChildSessions = "child_sessions"
+
+// toolRunKey is the context key of the toolRun of a tool call
+type toolRunKey struct{}
+
+// toolRun collects what the agents run by a tool did, so the engine can
+// roll it up into the calling run
+type toolRun struct {
+	sessionID string
+
+	mu            sync.Mutex
+	usage         Usage
+	childSessions []string
+}
+
+// withToolRun marks ctx as the context of a tool call. Agents run by the
+// tool report to run, not to an agent tool further up.
+func withToolRun(ctx context.Context, run *toolRun) context.Context {
+	ctx = context.WithValue(ctx, agentRunKey{}, nil)
+	return context.WithValue(ctx, toolRunKey{}, run)
+}
+
+func toolRunFromContext(ctx context.Context) (*toolRun, bool) {
+	run, ok := ctx.Value(toolRunKey{}).(*toolRun)
+	return run, ok
+}
+
+func (r *toolRun) add(usage Usage, sessionIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.usage = r.usage.add(usage)
+	r.childSessions = append(r.childSessions, sessionIDs...)
+}
+
+// agentRunKey is the context key of the agentRun of an agent tool call
+type agentRunKey struct{}
+
+// agentRun collects the runs of the agent called by an agent tool. Engines
+// report every run to it, so failed runs that return no Response still
+// count.
+type agentRun struct {
+	mu         sync.Mutex
+	reported   bool
+	usage      Usage
+	sessionIDs []string
+}
+
+// reportAgentRun reports a finished or failed run to the agent tool that
+// started it, if any
+func reportAgentRun(ctx context.Context, usage Usage, sessionID string) {
+	r, ok := ctx.Value(agentRunKey{}).(*agentRun)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reported = true
+	r.usage = r.usage.add(usage)
+	// Team members continue the same session
+	if !slices.Contains(r.sessionIDs, sessionID) {
+		r.sessionIDs = append(r.sessionIDs, sessionID)
+	}
+}
+
+// agentTool runs an agent as a tool
+type agentTool struct {
+	agent       Agent
+	name        string
+	description string
+}
+
+// NewAgentTool wraps an agent as a tool, so a supervisor model can hand
+// work to a worker agent. Each call runs the agent with the model's input
+// in a new child session, linked to the caller's session with
+// MetadataParentAgentSession. The agent's output is the tool result and its
+// usage rolls up into the calling run's Usage, even if it fails. The agent
+// does not see a handoff that started the calling run.
+func NewAgentTool(agent Agent, name, description string) tool.Tool {
+	return &agentTool{
+		agent:       agent,
+		name:        name,
+		description: description,
+	}
+}
+
+func (t *agentTool) Definition() tool.Definition {
+	return tool.Definition{
+		Type: "function",
+		Function: tool.Function{
+			Name:        t.name,
+			Description: t.description,
+			Parameters: tool.Parameters{
+				Type: "object",
+				Properties: map[string]tool.Property{
+					"input": {Type: "string", Description: "The task or question for the agent, with all context it needs"},
+				},
+				Required: []string{"input"},
+			},
+		},
+	}
+}
+
+func (t *agentTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	input, _ := params["input"].(string)
+	if input == "" {
+		return nil, fmt.Errorf("%w: input is required", ErrInvalidInput)
+	}
+
+	request := Request{Input: input}
+	run, ok := toolRunFromContext(ctx)
+	if ok {
+		request.ParentSessionID = run.sessionID
+	}
+
+	// The handoff that started the calling run was not made to this agent
+	ctx = withoutHandoffFrom(ctx)
+	child := &agentRun{}
+	response, err := t.agent.Execute(context.WithValue(ctx, agentRunKey{}, child), request)
+	if ok {
+		switch {
+		case child.reported:
+			run.add(child.usage, child.sessionIDs)
+		case response != nil:
+			// Agents that are not engines only report through the response
+			run.add(response.Usage, []string{response.SessionID})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed: %w", t.name, err)
+	}
+	return response.Output, nil
+}
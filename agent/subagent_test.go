@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func TestAgentTool_RunsChildSession(t *testing.T) {
+	store := memory.NewStore()
+	workerModel := &scriptedModel{responses: []*llm.Response{
+		{Content: "worker answer", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 5}},
+	}}
+	worker, err := NewBuilder().WithLLM(workerModel).WithSessionStore(store).Build()
+	if err != nil {
+		t.Fatalf("Failed to build worker: %v", err)
+	}
+
+	supervisorModel := &scriptedModel{responses: []*llm.Response{
+		{
+			ToolCalls: []tool.Call{{ID: "1", Function: tool.FunctionCall{Name: "research", Arguments: `{"input": "find x"}`}}},
+			Usage:     llm.Usage{TotalTokens: 10},
+		},
+		{Content: "final", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 10}},
+	}}
+	supervisor, err := NewBuilder().
+		WithLLM(supervisorModel).
+		WithSessionStore(store).
+		WithTools(NewAgentTool(worker, "research", "Researches a question")).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build supervisor: %v", err)
+	}
+
+	response, err := supervisor.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if got := workerModel.requests[0].Messages; got[len(got)-1].Content != "find x" {
+		t.Errorf("Expected the worker to get the tool input, got %q", got[len(got)-1].Content)
+	}
+	toolMessage := supervisorModel.requests[1].Messages[len(supervisorModel.requests[1].Messages)-1]
+	if !strings.Contains(toolMessage.Content, "worker answer") {
+		t.Errorf("Expected worker output as tool result, got %q", toolMessage.Content)
+	}
+	if response.Usage.LLMTokens.TotalTokens != 25 {
+		t.Errorf("Expected 25 tokens including the worker, got %d", response.Usage.LLMTokens.TotalTokens)
+	}
+	if len(response.Usage.IterationCosts) != 0 {
+		t.Errorf("Expected no iteration costs without pricing, got %v", response.Usage.IterationCosts)
+	}
+
+	children, _ := response.Metadata[MetadataChildSessions].([]string)
+	if len(children) != 1 {
+		t.Fatalf("Expected 1 child session, got %v", response.Metadata[MetadataChildSessions])
+	}
+	child, err := store.Get(context.Background(), children[0])
+	if err != nil {
+		t.Fatalf("Failed to get child session: %v", err)
+	}
+	if parent, _ := child.GetMetadata(MetadataParentAgentSession); parent != response.SessionID {
+		t.Errorf("Expected child linked to %s, got %q", response.SessionID, parent)
+	}
+	if source, ok := child.GetMetadata(session.MetadataParentSessionID); ok {
+		t.Errorf("Expected the child not to be reported as a fork, got source %q", source)
+	}
+}
+
+func TestAgentTool_RequiresInput(t *testing.T) {
+	worker, _ := NewSimpleAgent(&MockModel{})
+	if _, err := NewAgentTool(worker, "worker", "").Execute(context.Background(), map[string]any{}); err == nil {
+		t.Error("Expected error without input")
+	}
+}
+
+func TestAgentTool_RollsUpUsageOnError(t *testing.T) {
+	workerModel := &crashingModel{scriptedModel{responses: []*llm.Response{lookupCall(llm.Usage{TotalTokens: 10})}}}
+	worker, err := NewBuilder().WithLLM(workerModel).WithTools(&MockTool{name: "lookup"}).Build()
+	if err != nil {
+		t.Fatalf("Failed to build worker: %v", err)
+	}
+
+	run := &toolRun{sessionID: "parent"}
+	_, err = NewAgentTool(worker, "research", "").Execute(withToolRun(context.Background(), run), map[string]any{"input": "find x"})
+	if err == nil {
+		t.Fatal("Expected the worker to fail")
+	}
+
+	if run.usage.LLMTokens.TotalTokens != 10 || run.usage.ToolCalls != 1 {
+		t.Errorf("Expected the failed worker's usage to roll up, got %+v", run.usage)
+	}
+	if len(run.childSessions) != 1 {
+		t.Errorf("Expected 1 child session, got %v", run.childSessions)
+	}
+}
+
+func TestAgentTool_ClearsHandoff(t *testing.T) {
+	workerModel := &scriptedModel{responses: []*llm.Response{{Content: "worker answer", FinishReason: "stop"}}}
+	worker, err := NewBuilder().WithLLM(workerModel).Build()
+	if err != nil {
+		t.Fatalf("Failed to build worker: %v", err)
+	}
+
+	ctx := withHandoffFrom(context.Background(), handoffFrom{agent: "triage", reason: "refund request"})
+	if _, err := NewAgentTool(worker, "research", "").Execute(ctx, map[string]any{"input": "find x"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for _, message := range workerModel.requests[0].Messages {
+		if strings.Contains(message.Content, "handed this conversation") {
+			t.Errorf("Expected the worker not to see the caller's handoff, got %+v", message)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// NamedAgent is an agent with a name, like the agents built with
+// Builder.WithName
+type NamedAgent interface {
+	Agent
+	Name() string
+}
+
+// Team routes a conversation between agents that hand off to each other.
+// Members share the session store, so history carries over; each keeps
+// its own model, tools and system prompt.
+type Team struct {
+	store       session.SessionStore
+	members     map[string]Agent
+	entry       string
+	maxHandoffs int
+}
+
+// TeamOption configures a Team
+type TeamOption func(*Team)
+
+// WithMaxHandoffs limits the handoffs within one request (default 3)
+func WithMaxHandoffs(max int) TeamOption {
+	return func(t *Team) {
+		t.maxHandoffs = max
+	}
+}
+
+// lockedAgent is an agent that serializes executions with a SessionLocker
+type lockedAgent interface {
+	sessionLockerOf() session.SessionLocker
+	useSessionLocker(locker session.SessionLocker)
+}
+
+// NewTeam creates a team. Members must implement NamedAgent and use store
+// as their session store. The first member answers new conversations.
+// Members built with the Builder all take the session locker of the first
+// one, so executions on a session are serialized across the team.
+func NewTeam(store session.SessionStore, members []Agent, opts ...TeamOption) (*Team, error) {
+	if store == nil {
+		return nil, fmt.Errorf("session store is required")
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("team requires at least one agent")
+	}
+
+	team := &Team{
+		store:       store,
+		members:     make(map[string]Agent, len(members)),
+		maxHandoffs: 3,
+	}
+	for _, member := range members {
+		named, ok := member.(NamedAgent)
+		if !ok || named.Name() == "" {
+			return nil, fmt.Errorf("team members must have a name")
+		}
+		if _, exists := team.members[named.Name()]; exists {
+			return nil, fmt.Errorf("duplicate team member %q", named.Name())
+		}
+		team.members[named.Name()] = member
+		if team.entry == "" {
+			team.entry = named.Name()
+		}
+	}
+
+	// Members take turns on the same sessions, so they must share a lock
+	var locker session.SessionLocker
+	for _, member := range members {
+		locked, ok := member.(lockedAgent)
+		if !ok {
+			continue
+		}
+		if locker == nil {
+			locker = locked.sessionLockerOf()
+			continue
+		}
+		locked.useSessionLocker(locker)
+	}
+
+	for _, opt := range opts {
+		opt(team)
+	}
+	return team, nil
+}
+
+// Execute runs the request with the agent that answered last on the
+// session, following handoffs until an agent answers. Usage covers all
+// agents that took part, except IterationCosts, which are those of the
+// calls of the agent that answered.
+func (t *Team) Execute(ctx context.Context, request Request) (*Response, error) {
+	active, err := t.activeAgent(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage Usage
+	runCtx := ctx
+	for handoffs := 0; ; handoffs++ {
+		response, err := t.members[active].Execute(runCtx, request)
+		if err != nil {
+			return nil, err
+		}
+		usage = usage.add(response.Usage)
+
+		target, ok := response.Metadata[MetadataHandoffTo].(string)
+		if !ok {
+			usage.IterationCosts = response.Usage.IterationCosts
+			response.Usage = usage
+			return response, nil
+		}
+		if handoffs == t.maxHandoffs {
+			return nil, fmt.Errorf("%w: stopped after %d", ErrTooManyHandoffs, handoffs)
+		}
+		if _, exists := t.members[target]; !exists {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAgent, target)
+		}
+
+		// Continue in the session the first run created or forked
+		request.SessionID = response.SessionID
+		request.ForkAtEntryID = ""
+
+		reason, _ := response.Metadata[MetadataHandoffReason].(string)
+		runCtx = withHandoffFrom(ctx, handoffFrom{agent: active, reason: reason})
+		active = target
+	}
+}
+
+// activeAgent returns the member that answered last on the session
+func (t *Team) activeAgent(ctx context.Context, request Request) (string, error) {
+	if request.SessionID == "" {
+		return t.entry, nil
+	}
+
+	sess, err := t.store.Get(ctx, request.SessionID)
+	if err != nil {
+		// Let the member report a missing session
+		return t.entry, nil
+	}
+	name, _ := sess.Get(StateKeyActiveAgent)
+	if name, ok := name.(string); ok {
+		if _, exists := t.members[name]; exists {
+			return name, nil
+		}
+	}
+	return t.entry, nil
+}
+
+// add returns the sum of two usages. IterationCosts are those of u: the
+// calls of other belong to another run.
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		LLMTokens: TokenUsage{
+			PromptTokens:       u.LLMTokens.PromptTokens + other.LLMTokens.PromptTokens,
+			CachedPromptTokens: u.LLMTokens.CachedPromptTokens + other.LLMTokens.CachedPromptTokens,
+			CompletionTokens:   u.LLMTokens.CompletionTokens + other.LLMTokens.CompletionTokens,
+			TotalTokens:        u.LLMTokens.TotalTokens + other.LLMTokens.TotalTokens,
+		},
+		ToolCalls:      u.ToolCalls + other.ToolCalls,
+		SessionWrites:  u.SessionWrites + other.SessionWrites,
+		Cost:           u.Cost + other.Cost,
+		IterationCosts: u.IterationCosts,
+	}
+}
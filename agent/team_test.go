@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/pricing"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+	"github.com/davidleitw/go-agent/tool"
+)
+
+func handoffCallResponse(agent, reason string) *llm.Response {
+	return &llm.Response{
+		ToolCalls: []tool.Call{{
+			ID:       "handoff",
+			Function: tool.FunctionCall{Name: "transfer_to_" + agent, Arguments: `{"reason": "` + reason + `"}`},
+		}},
+		Usage: llm.Usage{TotalTokens: 10},
+	}
+}
+
+func newTeamMember(t *testing.T, store session.SessionStore, name string, model llm.Model, handoffs ...Handoff) Agent {
+	t.Helper()
+	member, err := NewBuilder().
+		WithName(name).
+		WithLLM(model).
+		WithSessionStore(store).
+		WithHandoffs(handoffs...).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build %s: %v", name, err)
+	}
+	return member
+}
+
+func TestTeam_Handoff(t *testing.T) {
+	store := memory.NewStore()
+	triageModel := &scriptedModel{responses: []*llm.Response{handoffCallResponse("billing", "refund request")}}
+	billingModel := &scriptedModel{responses: []*llm.Response{
+		{Content: "Refund issued", FinishReason: "stop", Usage: llm.Usage{TotalTokens: 20}},
+		{Content: "You're welcome", FinishReason: "stop"},
+	}}
+
+	team, err := NewTeam(store, []Agent{
+		newTeamMember(t, store, "triage", triageModel, Handoff{Agent: "billing", Description: "Billing questions"}),
+		newTeamMember(t, store, "billing", billingModel, Handoff{Agent: "triage", Description: "Anything else"}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+
+	response, err := team.Execute(context.Background(), Request{Input: "I want my money back"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if response.Output != "Refund issued" || response.Metadata[MetadataAgent] != "billing" {
+		t.Errorf("Expected billing to answer, got %q from %v", response.Output, response.Metadata[MetadataAgent])
+	}
+	if response.Usage.LLMTokens.TotalTokens != 30 {
+		t.Errorf("Expected usage of both agents (30 tokens), got %d", response.Usage.LLMTokens.TotalTokens)
+	}
+
+	// Triage sees the handoff tool, billing sees why it got the conversation
+	tools := triageModel.requests[0].Tools
+	if len(tools) != 1 || tools[0].Function.Name != "transfer_to_billing" || tools[0].Function.Description != "Billing questions" {
+		t.Errorf("Expected transfer_to_billing tool, got %+v", tools)
+	}
+	billingPrompt := billingModel.requests[0].Messages
+	if last := billingPrompt[len(billingPrompt)-1]; last.Role != "system" || last.Content != "The triage agent handed this conversation to you. Reason: refund request" {
+		t.Errorf("Expected handoff note, got %+v", last)
+	}
+
+	// History records one user turn, answered by billing
+	sess, _ := store.Get(context.Background(), response.SessionID)
+	var users, answers int
+	for _, entry := range sess.GetHistory(10) {
+		content, _ := session.GetMessageContent(entry)
+		switch content.Role {
+		case "user":
+			users++
+		case "assistant":
+			answers++
+			if entry.Metadata[MetadataAgent] != "billing" || entry.Metadata[MetadataHandoffFrom] != "triage" {
+				t.Errorf("Expected answer by billing from triage, got %v", entry.Metadata)
+			}
+		}
+	}
+	if users != 1 || answers != 1 {
+		t.Errorf("Expected 1 user and 1 assistant entry, got %d and %d", users, answers)
+	}
+
+	// The next turn goes straight to billing
+	if _, err := team.Execute(context.Background(), Request{Input: "Thanks", SessionID: response.SessionID}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(triageModel.requests) != 1 || len(billingModel.requests) != 2 {
+		t.Errorf("Expected the second turn to go to billing, got %d triage and %d billing calls",
+			len(triageModel.requests), len(billingModel.requests))
+	}
+}
+
+func TestTeam_TooManyHandoffs(t *testing.T) {
+	store := memory.NewStore()
+	pingModel := &scriptedModel{responses: []*llm.Response{handoffCallResponse("pong", ""), handoffCallResponse("pong", "")}}
+	pongModel := &scriptedModel{responses: []*llm.Response{handoffCallResponse("ping", "")}}
+
+	team, err := NewTeam(store, []Agent{
+		newTeamMember(t, store, "ping", pingModel, Handoff{Agent: "pong"}),
+		newTeamMember(t, store, "pong", pongModel, Handoff{Agent: "ping"}),
+	}, WithMaxHandoffs(2))
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+
+	_, err = team.Execute(context.Background(), Request{Input: "hello"})
+	if !errors.Is(err, ErrTooManyHandoffs) {
+		t.Errorf("Expected ErrTooManyHandoffs, got %v", err)
+	}
+}
+
+func TestTeam_IterationCostsOfAnsweringAgent(t *testing.T) {
+	store := memory.NewStore()
+	prices := pricing.NewRegistry()
+	prices.Set("test-model", pricing.Price{Completion: 1_000_000})
+
+	newPricedMember := func(name string, model *scriptedModel, handoffs ...Handoff) Agent {
+		member, err := NewBuilder().
+			WithName(name).
+			WithLLM(namedModel{model}).
+			WithSessionStore(store).
+			WithPricing(prices).
+			WithHandoffs(handoffs...).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to build %s: %v", name, err)
+		}
+		return member
+	}
+
+	handoff := handoffCallResponse("billing", "")
+	handoff.Usage = llm.Usage{CompletionTokens: 1, TotalTokens: 1}
+	triageModel := &scriptedModel{responses: []*llm.Response{handoff}}
+	billingModel := &scriptedModel{responses: []*llm.Response{
+		{Content: "Refund issued", FinishReason: "stop", Usage: llm.Usage{CompletionTokens: 2, TotalTokens: 2}},
+	}}
+
+	team, err := NewTeam(store, []Agent{
+		newPricedMember("triage", triageModel, Handoff{Agent: "billing"}),
+		newPricedMember("billing", billingModel),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+
+	response, err := team.Execute(context.Background(), Request{Input: "I want my money back"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// Cost covers both agents, IterationCosts only the calls of billing
+	if response.Usage.Cost != 3 {
+		t.Errorf("Expected cost of both agents (3), got %v", response.Usage.Cost)
+	}
+	if costs := response.Usage.IterationCosts; len(costs) != 1 || costs[0] != 2 {
+		t.Errorf("Expected the cost of the billing call only, got %v", costs)
+	}
+}
+
+func TestNewTeam_RequiresNames(t *testing.T) {
+	unnamed, _ := NewSimpleAgent(&MockModel{})
+	if _, err := NewTeam(memory.NewStore(), []Agent{unnamed}); err == nil {
+		t.Error("Expected error for unnamed member")
+	}
+}
+
+func TestNewTeam_SharesSessionLocker(t *testing.T) {
+	store := memory.NewStore()
+	locker := memory.NewLocker()
+	triage, err := NewBuilder().WithName("triage").WithLLM(&scriptedModel{}).WithSessionStore(store).WithSessionLocker(locker).Build()
+	if err != nil {
+		t.Fatalf("Failed to build triage: %v", err)
+	}
+	billing := newTeamMember(t, store, "billing", &scriptedModel{})
+
+	if _, err := NewTeam(store, []Agent{triage, billing}); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+
+	if got := billing.(lockedAgent).sessionLockerOf(); got != locker {
+		t.Errorf("Expected members to share the first member's locker, got %v", got)
+	}
+}
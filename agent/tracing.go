@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/tool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer of this package
+const instrumentationName = "github.com/davidleitw/go-agent/agent"
+
+// Span attributes following the OpenTelemetry GenAI semantic conventions
+const (
+	attrOperationName    = attribute.Key("gen_ai.operation.name")
+	attrConversationID   = attribute.Key("gen_ai.conversation.id")
+	attrRequestModel     = attribute.Key("gen_ai.request.model")
+	attrRequestTemp      = attribute.Key("gen_ai.request.temperature")
+	attrRequestMaxTokens = attribute.Key("gen_ai.request.max_tokens")
+	attrResponseModel    = attribute.Key("gen_ai.response.model")
+	attrFinishReasons    = attribute.Key("gen_ai.response.finish_reasons")
+	attrInputTokens      = attribute.Key("gen_ai.usage.input_tokens")
+	attrOutputTokens     = attribute.Key("gen_ai.usage.output_tokens")
+	attrToolName         = attribute.Key("gen_ai.tool.name")
+	attrToolCallID       = attribute.Key("gen_ai.tool.call.id")
+
+	// Attributes specific to this framework
+	attrIteration = attribute.Key("go_agent.iteration")
+	attrContexts  = attribute.Key("go_agent.contexts")
+	attrToolCalls = attribute.Key("go_agent.tool_calls")
+)
+
+// GenAI operation names
+const (
+	operationInvokeAgent = "invoke_agent"
+	operationChat        = "chat"
+	operationExecuteTool = "execute_tool"
+)
+
+// modelName returns the name of model if it implements llm.ModelNamer
+func modelName(model llm.Model) string {
+	if namer, ok := model.(llm.ModelNamer); ok {
+		return namer.ModelName()
+	}
+	return ""
+}
+
+// startLLMSpan starts a client span for one LLM call to llmModel
+func (e *engine) startLLMSpan(ctx context.Context, llmModel llm.Model, iteration int, request llm.Request) (context.Context, trace.Span) {
+	name := operationChat
+	attrs := []attribute.KeyValue{
+		attrOperationName.String(operationChat),
+		attrIteration.Int(iteration),
+	}
+
+	if model := modelName(llmModel); model != "" {
+		name += " " + model
+		attrs = append(attrs, attrRequestModel.String(model))
+	}
+	if request.Temperature != nil {
+		attrs = append(attrs, attrRequestTemp.Float64(float64(*request.Temperature)))
+	}
+	if request.MaxTokens != nil {
+		attrs = append(attrs, attrRequestMaxTokens.Int(*request.MaxTokens))
+	}
+
+	return e.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endLLMSpan records the outcome of an LLM call and ends its span
+func endLLMSpan(span trace.Span, response *llm.Response, err error) {
+	defer span.End()
+
+	if err != nil {
+		recordSpanError(span, err)
+		return
+	}
+
+	span.SetAttributes(
+		attrFinishReasons.StringSlice([]string{response.FinishReason}),
+		attrInputTokens.Int(response.Usage.PromptTokens),
+		attrOutputTokens.Int(response.Usage.CompletionTokens),
+	)
+	if response.Model != "" {
+		span.SetAttributes(attrResponseModel.String(response.Model))
+	}
+}
+
+// startToolSpan starts a span for one tool execution
+func (e *engine) startToolSpan(ctx context.Context, call tool.Call) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, operationExecuteTool+" "+call.Function.Name,
+		trace.WithAttributes(
+			attrOperationName.String(operationExecuteTool),
+			attrToolName.String(call.Function.Name),
+			attrToolCallID.String(call.ID),
+		))
+}
+
+// recordSpanError marks span as failed
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
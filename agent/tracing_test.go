@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidleitw/go-agent/tool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// namedModel adds llm.ModelNamer to a scripted model
+type namedModel struct {
+	*scriptedModel
+}
+
+func (namedModel) ModelName() string { return "test-model" }
+
+// spanContextTool records the span context it was called with
+type spanContextTool struct {
+	MockTool
+	spanContext trace.SpanContext
+}
+
+func (s *spanContextTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	s.spanContext = trace.SpanContextFromContext(ctx)
+	return s.MockTool.Execute(ctx, params)
+}
+
+func newTracedEngine(t *testing.T, config EngineConfig) (Engine, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	engine, err := NewEngine(config)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	return engine, exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_Spans(t *testing.T) {
+	registry := tool.NewRegistry()
+	lookup := &spanContextTool{MockTool: MockTool{name: "lookup"}}
+	registry.Register(lookup)
+
+	engine, exporter := newTracedEngine(t, EngineConfig{
+		Model:        namedModel{toolCallingModel()},
+		ToolRegistry: registry,
+	})
+
+	response, err := engine.Execute(context.Background(), Request{Input: "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+
+	roots := byName["invoke_agent"]
+	if len(roots) != 1 {
+		t.Fatalf("Expected 1 invoke_agent span, got %d of %d spans", len(roots), len(spans))
+	}
+	root := roots[0]
+	if got := spanAttr(root, attrConversationID).AsString(); got != response.SessionID {
+		t.Errorf("Expected conversation id %s, got %s", response.SessionID, got)
+	}
+	if got := spanAttr(root, attrInputTokens).AsInt64(); got != 10 {
+		t.Errorf("Expected 10 input tokens on run span, got %d", got)
+	}
+
+	if len(byName["gather_contexts"]) != 1 {
+		t.Errorf("Expected 1 gather_contexts span, got %d", len(byName["gather_contexts"]))
+	}
+
+	chats := byName["chat test-model"]
+	if len(chats) != 2 {
+		t.Fatalf("Expected 2 chat spans, got %d", len(chats))
+	}
+	first := chats[0]
+	if first.SpanKind != trace.SpanKindClient {
+		t.Errorf("Expected client span kind, got %v", first.SpanKind)
+	}
+	if got := spanAttr(first, attrRequestModel).AsString(); got != "test-model" {
+		t.Errorf("Expected request model test-model, got %q", got)
+	}
+	if got := spanAttr(first, attrOutputTokens).AsInt64(); got != 2 {
+		t.Errorf("Expected 2 output tokens, got %d", got)
+	}
+	if got := spanAttr(chats[1], attrFinishReasons).AsStringSlice(); len(got) != 1 || got[0] != "stop" {
+		t.Errorf("Expected finish reason stop, got %v", got)
+	}
+
+	tools := byName["execute_tool lookup"]
+	if len(tools) != 1 {
+		t.Fatalf("Expected 1 execute_tool span, got %d", len(tools))
+	}
+	if got := spanAttr(tools[0], attrToolName).AsString(); got != "lookup" {
+		t.Errorf("Expected tool name lookup, got %q", got)
+	}
+
+	// Every span belongs to the run, and tools see their span through ctx
+	for _, span := range spans {
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("Expected span %s in the run trace", span.Name)
+		}
+		if span.Name != "invoke_agent" && span.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("Expected span %s to be a child of the run span", span.Name)
+		}
+	}
+	if lookup.spanContext.SpanID() != tools[0].SpanContext.SpanID() {
+		t.Error("Expected tool ctx to carry the execute_tool span")
+	}
+}
+
+func TestTracing_RecordsErrors(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(&MockTool{name: "lookup", err: errors.New("backend down")})
+
+	engine, exporter := newTracedEngine(t, EngineConfig{
+		Model:        toolCallingModel(),
+		ToolRegistry: registry,
+	})
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "execute_tool lookup" && span.Status.Code != codes.Error {
+			t.Errorf("Expected failed tool span to have error status, got %v", span.Status)
+		}
+	}
+
+	// A failing LLM call fails both the chat span and the run span
+	engine, exporter = newTracedEngine(t, EngineConfig{Model: &MockModel{err: errors.New("rate limited")}})
+	if _, err := engine.Execute(context.Background(), Request{Input: "hello"}); err == nil {
+		t.Fatal("Expected error from failing model")
+	}
+
+	failed := 0
+	for _, span := range exporter.GetSpans() {
+		if span.Status.Code == codes.Error {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Errorf("Expected chat and run spans to be marked failed, got %d", failed)
+	}
+}
@@ -3,13 +3,19 @@ package agent
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"time"
 
 	agentcontext "github.com/davidleitw/go-agent/context"
+	"github.com/davidleitw/go-agent/guardrail"
 	"github.com/davidleitw/go-agent/llm"
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/davidleitw/go-agent/pricing"
 	"github.com/davidleitw/go-agent/prompt"
+	"github.com/davidleitw/go-agent/redact"
 	"github.com/davidleitw/go-agent/session"
 	"github.com/davidleitw/go-agent/tool"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Engine interface defines the core execution engine
@@ -45,6 +51,24 @@ type EngineConfig struct {
 	// MaxIterations limits agent thinking/tool loops
 	MaxIterations int
 
+	// MaxIterationsPolicy decides what happens when MaxIterations is
+	// reached (default MaxIterationsFail)
+	MaxIterationsPolicy MaxIterationsPolicy
+
+	// Strategy drives the LLM and tool calls of each run (default
+	// DefaultStrategy)
+	Strategy Strategy
+
+	// Reflection reviews the answer of the Strategy before it is returned
+	// (optional, see Reflect)
+	Reflection *Reflection
+
+	// Checkpointing saves the state of each run to its session after every
+	// step, so an interrupted run can be resumed with Request.Resume. The
+	// default strategy resumes mid-loop; others restart from the prompt.
+	// It cannot be combined with Reflection, even inside a custom strategy.
+	Checkpointing bool
+
 	// Temperature for LLM calls
 	Temperature *float32
 
@@ -59,6 +83,59 @@ type EngineConfig struct {
 
 	// HistoryInterceptor for advanced history processing (optional)
 	HistoryInterceptor HistoryInterceptor
+
+	// SessionLocker serializes executions on the same session
+	// (defaults to an in-process memory.Locker shared by all engines)
+	SessionLocker session.SessionLocker
+
+	// SessionLockOptions selects the behavior when a session is busy
+	// (defaults to waiting until ctx is done)
+	SessionLockOptions session.LockOptions
+
+	// Redactor removes PII from the input, tool results and stored history
+	// (optional, nothing is redacted when nil)
+	Redactor *redact.Redactor
+
+	// Logger receives structured progress logs (optional, silent when nil)
+	Logger *slog.Logger
+
+	// LogRedactArguments and LogRedactResults rewrite tool arguments and
+	// results before they are logged (default: masked by Redactor, if set)
+	LogRedactArguments LogRedactFunc
+	LogRedactResults   LogRedactFunc
+
+	// Hooks observe and may modify or veto steps of each run (optional)
+	Hooks []Hook
+
+	// TracerProvider creates spans for runs, LLM calls and tool calls
+	// (defaults to the global OpenTelemetry provider, a no-op unless set)
+	TracerProvider trace.TracerProvider
+
+	// Metrics receives LLM, tool, run and session store measurements
+	// (optional, nothing is recorded when nil)
+	Metrics metrics.Recorder
+
+	// Pricing computes the cost of LLM calls (optional, cost is 0 when nil)
+	Pricing *pricing.Registry
+
+	// Name identifies the agent in history entries, responses and teams
+	Name string
+
+	// Handoffs are offered to the model as tools that pass the conversation
+	// to another agent of a Team (optional)
+	Handoffs []Handoff
+
+	// InputGuardrails check Request.Input before the first LLM call and
+	// OutputGuardrails check the final answer before it is returned. Each
+	// may allow, rewrite or block; a block answers with its message.
+	InputGuardrails  []guardrail.Guardrail
+	OutputGuardrails []guardrail.Guardrail
+
+	// RunBudget limits each run; SessionBudget limits all runs on a session.
+	// When a budget runs low the model is asked for a final answer without
+	// tools; when one is used up Execute returns a *BudgetExceededError.
+	RunBudget     Budget
+	SessionBudget Budget
 }
 
 // Common errors
@@ -107,5 +184,11 @@ type ToolResult struct {
 
 	// Error if tool execution failed
 	Error error
+
+	// Usage of agents run by the tool, rolled up into the run's Usage
+	Usage Usage
+
+	// ChildSessionIDs are the sessions of agents run by the tool
+	ChildSessionIDs []string
 }
 
@@ -10,6 +10,11 @@ replace github.com/davidleitw/go-agent => ../../
 require github.com/davidleitw/go-agent v0.0.0-00010101000000-000000000000
 
 require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/sashabaranov/go-openai v1.40.5 // indirect
+	go.opentelemetry.io/otel v1.32.0 // indirect
+	go.opentelemetry.io/otel/metric v1.32.0 // indirect
+	go.opentelemetry.io/otel/trace v1.32.0 // indirect
 )
@@ -288,7 +288,8 @@ func createResearchAgent(client *openai.Client, workflowType string) (agent.Agen
 		WithMemorySessionStore().
 		WithPromptTemplate(template).
 		WithHistoryLimit(8).
-		WithMaxIterations(15).
+		WithMaxIterations(6).  // LLM calls per plan step
+		WithPlanAndExecute(2). // Plan the research, then work through it step by step
 		WithContextProviders(
 			NewResearchContextProvider(workflowType),
 		).
@@ -0,0 +1,91 @@
+package guardrail
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Topics blocks text that mentions none of the topics. Matching is a
+// case-insensitive substring search, so list the words users actually use.
+func Topics(message string, topics ...string) Guardrail {
+	lowered := make([]string, len(topics))
+	for i, topic := range topics {
+		lowered[i] = strings.ToLower(topic)
+	}
+
+	return Func("topics", func(ctx context.Context, text string) (Result, error) {
+		text = strings.ToLower(text)
+		for _, topic := range lowered {
+			if strings.Contains(text, topic) {
+				return Allow(), nil
+			}
+		}
+		return Block(message), nil
+	})
+}
+
+// injectionPatterns match common attempts to override the agent's instructions
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,30}\b(previous|prior|above|earlier|all|your|system)\b.{0,20}\b(instructions?|prompts?|rules|guidelines)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|show|print|repeat|output)\b.{0,30}\b(system prompt|initial prompt|your (prompt|instructions))\b`),
+	regexp.MustCompile(`(?i)\byou are (now|no longer)\b`),
+	regexp.MustCompile(`(?i)\b(pretend|act as if) (you have|there are) no (rules|restrictions|guidelines)\b`),
+	regexp.MustCompile(`(?i)\b(jailbreak|developer mode|DAN mode)\b`),
+	regexp.MustCompile(`(?i)^\s*(system|assistant)\s*:`),
+}
+
+// PromptInjection blocks text that looks like an attempt to override the
+// agent's instructions. It is a heuristic: it catches common phrasings,
+// not every attack. extra adds patterns to the built-in ones.
+func PromptInjection(message string, extra ...*regexp.Regexp) Guardrail {
+	patterns := append(injectionPatterns[:len(injectionPatterns):len(injectionPatterns)], extra...)
+
+	return Func("prompt_injection", func(ctx context.Context, text string) (Result, error) {
+		for _, pattern := range patterns {
+			if pattern.MatchString(text) {
+				return Block(message), nil
+			}
+		}
+		return Allow(), nil
+	})
+}
+
+// MaxLength blocks text longer than max characters
+func MaxLength(max int, message string) Guardrail {
+	return Func("max_length", func(ctx context.Context, text string) (Result, error) {
+		if utf8.RuneCountInString(text) > max {
+			return Block(message), nil
+		}
+		return Allow(), nil
+	})
+}
+
+// Truncate rewrites text longer than max characters to its first max characters
+func Truncate(max int) Guardrail {
+	return Func("truncate", func(ctx context.Context, text string) (Result, error) {
+		if utf8.RuneCountInString(text) <= max {
+			return Allow(), nil
+		}
+		return Rewrite(string([]rune(text)[:max])), nil
+	})
+}
+
+// codeFence matches text wrapped in a Markdown code block, as models often return JSON
+var codeFence = regexp.MustCompile("(?s)^\\s*```[a-zA-Z]*\\s*\\n(.*?)\\n?```\\s*$")
+
+// JSON blocks text that is not valid JSON. JSON wrapped in a Markdown code
+// block is rewritten to the bare JSON.
+func JSON(message string) Guardrail {
+	return Func("json", func(ctx context.Context, text string) (Result, error) {
+		if json.Valid([]byte(text)) {
+			return Allow(), nil
+		}
+		if match := codeFence.FindStringSubmatch(text); match != nil && json.Valid([]byte(match[1])) {
+			return Rewrite(strings.TrimSpace(match[1])), nil
+		}
+		return Block(message), nil
+	})
+}
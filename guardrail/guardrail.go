@@ -0,0 +1,144 @@
+// Package guardrail checks agent input and output against policies.
+//
+// A Guardrail looks at a piece of text and allows it, blocks it with a
+// message for the user, or rewrites it. The agent engine runs input
+// guardrails on Request.Input before the first LLM call and output
+// guardrails on the final answer before it is returned, so policy is
+// enforced in one place instead of around every Execute call.
+package guardrail
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action is the decision of a guardrail
+type Action string
+
+const (
+	// ActionAllow lets the text through unchanged
+	ActionAllow Action = "allow"
+
+	// ActionBlock stops the text; Result.Message is shown instead
+	ActionBlock Action = "block"
+
+	// ActionRewrite replaces the text with Result.Text
+	ActionRewrite Action = "rewrite"
+)
+
+// Stage is where a guardrail runs
+type Stage string
+
+const (
+	StageInput  Stage = "input"
+	StageOutput Stage = "output"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Action Action
+
+	// Message is shown to the user when blocked, and explains the decision otherwise
+	Message string
+
+	// Text replaces the checked text when rewriting
+	Text string
+}
+
+// Allow lets the text through
+func Allow() Result {
+	return Result{Action: ActionAllow}
+}
+
+// Block stops the text and answers with message instead
+func Block(message string) Result {
+	return Result{Action: ActionBlock, Message: message}
+}
+
+// Rewrite replaces the text
+func Rewrite(text string) Result {
+	return Result{Action: ActionRewrite, Text: text}
+}
+
+// Guardrail checks a piece of text
+type Guardrail interface {
+	// Name identifies the guardrail in results
+	Name() string
+
+	// Check decides whether text is allowed, blocked or rewritten.
+	// An error fails the agent run.
+	Check(ctx context.Context, text string) (Result, error)
+}
+
+// Func adapts a function to the Guardrail interface
+func Func(name string, check func(ctx context.Context, text string) (Result, error)) Guardrail {
+	return &funcGuardrail{name: name, check: check}
+}
+
+type funcGuardrail struct {
+	name  string
+	check func(ctx context.Context, text string) (Result, error)
+}
+
+func (g *funcGuardrail) Name() string {
+	return g.name
+}
+
+func (g *funcGuardrail) Check(ctx context.Context, text string) (Result, error) {
+	return g.check(ctx, text)
+}
+
+// Record is the decision of one guardrail, as reported in Response.Metadata
+type Record struct {
+	Guardrail string `json:"guardrail"`
+	Stage     Stage  `json:"stage"`
+	Action    Action `json:"action"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Outcome is the result of running a list of guardrails
+type Outcome struct {
+	// Text after all rewrites
+	Text string
+
+	// Records holds one entry per guardrail that ran
+	Records []Record
+
+	// Blocked is the record of the guardrail that blocked the text, if any
+	Blocked *Record
+}
+
+// Run checks text against guardrails in order. Each guardrail sees the text
+// as rewritten by the previous ones; the first block stops the run.
+func Run(ctx context.Context, stage Stage, guardrails []Guardrail, text string) (Outcome, error) {
+	outcome := Outcome{Text: text}
+
+	for _, guardrail := range guardrails {
+		result, err := guardrail.Check(ctx, outcome.Text)
+		if err != nil {
+			return outcome, fmt.Errorf("guardrail %s failed: %w", guardrail.Name(), err)
+		}
+
+		record := Record{
+			Guardrail: guardrail.Name(),
+			Stage:     stage,
+			Action:    result.Action,
+			Message:   result.Message,
+		}
+
+		switch result.Action {
+		case ActionAllow:
+		case ActionRewrite:
+			outcome.Text = result.Text
+		case ActionBlock:
+			outcome.Records = append(outcome.Records, record)
+			outcome.Blocked = &outcome.Records[len(outcome.Records)-1]
+			return outcome, nil
+		default:
+			return outcome, fmt.Errorf("guardrail %s returned unknown action %q", guardrail.Name(), result.Action)
+		}
+		outcome.Records = append(outcome.Records, record)
+	}
+
+	return outcome, nil
+}
@@ -0,0 +1,48 @@
+package guardrail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/davidleitw/go-agent/llm"
+)
+
+const judgePrompt = `You review text for an AI assistant against these criteria:
+
+%s
+
+Reply with ALLOW if the text meets the criteria. Otherwise reply with BLOCK: followed by a short reason.`
+
+// Judge asks a model whether text meets criteria written in plain
+// language. A blocked text is answered with message, or with the model's
+// reason if message is empty. Every check costs one LLM call, made directly
+// on model: it is not traced, and it does not count towards the agent's
+// metrics, usage, cost or budgets. Wrap model to account for it.
+func Judge(model llm.Model, criteria, message string) Guardrail {
+	return Func("judge", func(ctx context.Context, text string) (Result, error) {
+		temperature := float32(0)
+		response, err := model.Complete(ctx, llm.Request{
+			Messages: []llm.Message{
+				{Role: "system", Content: fmt.Sprintf(judgePrompt, criteria)},
+				{Role: "user", Content: text},
+			},
+			Temperature: &temperature,
+		})
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to call judge model: %w", err)
+		}
+
+		verdict := strings.TrimSpace(response.Content)
+		switch upper := strings.ToUpper(verdict); {
+		case strings.HasPrefix(upper, "ALLOW"):
+			return Allow(), nil
+		case strings.HasPrefix(upper, "BLOCK"):
+			if message != "" {
+				return Block(message), nil
+			}
+			return Block(strings.TrimSpace(strings.TrimLeft(verdict[len("BLOCK"):], ":"))), nil
+		}
+		return Result{}, fmt.Errorf("judge model returned an unexpected verdict: %q", verdict)
+	})
+}
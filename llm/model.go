@@ -10,3 +10,9 @@ type Model interface {
 	// TODO: Future implementation for streaming
 	// Stream(ctx context.Context, request Request) (<-chan StreamEvent, error)
 }
+
+// ModelNamer is implemented by models that know the name of the model they
+// call, e.g. "gpt-4o". It is used to label traces and metrics.
+type ModelNamer interface {
+	ModelName() string
+}
@@ -43,6 +43,11 @@ func (c *Client) Complete(ctx context.Context, request llm.Request) (*llm.Respon
 	return c.fromOpenAIResponse(resp), nil
 }
 
+// ModelName returns the configured OpenAI model name
+func (c *Client) ModelName() string {
+	return c.model
+}
+
 // toOpenAIRequest converts our request format to OpenAI format
 func (c *Client) toOpenAIRequest(req llm.Request) openai.ChatCompletionRequest {
 	openaiReq := openai.ChatCompletionRequest{
@@ -148,6 +153,7 @@ func (c *Client) fromOpenAIResponse(resp openai.ChatCompletionResponse) *llm.Res
 	response := &llm.Response{
 		Content:      choice.Message.Content,
 		FinishReason: string(choice.FinishReason),
+		Model:        resp.Model,
 		Usage: llm.Usage{
 			PromptTokens:     resp.Usage.PromptTokens,
 			CompletionTokens: resp.Usage.CompletionTokens,
@@ -155,6 +161,10 @@ func (c *Client) fromOpenAIResponse(resp openai.ChatCompletionResponse) *llm.Res
 		},
 	}
 
+	if resp.Usage.PromptTokensDetails != nil {
+		response.Usage.CachedPromptTokens = resp.Usage.PromptTokensDetails.CachedTokens
+	}
+
 	// Convert tool calls if any
 	if len(choice.Message.ToolCalls) > 0 {
 		response.ToolCalls = make([]tool.Call, len(choice.Message.ToolCalls))
@@ -38,12 +38,14 @@ type Response struct {
 
 	// Basic metadata
 	Usage        Usage  `json:"usage"`
-	FinishReason string `json:"finish_reason"` // stop/length/tool_calls
+	FinishReason string `json:"finish_reason"`   // stop/length/tool_calls
+	Model        string `json:"model,omitempty"` // model that answered, if reported
 }
 
 // Usage tracks token consumption
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens       int `json:"prompt_tokens"`
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"` // part of PromptTokens served from cache
+	CompletionTokens   int `json:"completion_tokens"`
+	TotalTokens        int `json:"total_tokens"`
 }
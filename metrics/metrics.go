@@ -0,0 +1,82 @@
+// Package metrics defines the measurements the agent engine reports, so they
+// can be aggregated across requests for dashboards and alerts.
+//
+// The engine calls a Recorder; implementations adapt it to a metrics backend.
+// The prometheus subpackage provides a Prometheus adapter.
+package metrics
+
+import "time"
+
+// Status values of LLM calls, runs and store operations
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Run statuses in addition to StatusOK and StatusError
+const (
+	StatusMaxIterations = "max_iterations"
+)
+
+// Token types
+const (
+	TokenPrompt     = "prompt"
+	TokenCompletion = "completion"
+)
+
+// Tool call outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeVetoed  = "vetoed"
+)
+
+// Session store operations
+const (
+	StoreCreate = "create"
+	StoreGet    = "get"
+	StoreFork   = "fork"
+	StoreSave   = "save"
+)
+
+// Recorder receives measurements from the agent engine.
+// Implementations must be safe for concurrent use.
+type Recorder interface {
+	// RecordLLMCall records one LLM call. model is empty if the model
+	// does not implement llm.ModelNamer.
+	RecordLLMCall(model, status string, duration time.Duration)
+
+	// RecordTokens records tokens used by one LLM call
+	RecordTokens(model, tokenType string, count int)
+
+	// RecordToolCall records one tool call
+	RecordToolCall(tool, outcome string, duration time.Duration)
+
+	// RecordRun records the number of iterations of a finished run
+	RecordRun(status string, iterations int)
+
+	// RecordMaxIterationsExceeded records a run stopped by MaxIterations
+	RecordMaxIterationsExceeded()
+
+	// RecordStoreOperation records one session store operation
+	RecordStoreOperation(operation, status string, duration time.Duration)
+}
+
+// NopRecorder discards all measurements. Embed it to implement only some
+// Recorder methods.
+type NopRecorder struct{}
+
+func (NopRecorder) RecordLLMCall(model, status string, duration time.Duration)            {}
+func (NopRecorder) RecordTokens(model, tokenType string, count int)                       {}
+func (NopRecorder) RecordToolCall(tool, outcome string, duration time.Duration)           {}
+func (NopRecorder) RecordRun(status string, iterations int)                               {}
+func (NopRecorder) RecordMaxIterationsExceeded()                                          {}
+func (NopRecorder) RecordStoreOperation(operation, status string, duration time.Duration) {}
+
+// Status returns StatusOK if err is nil and StatusError otherwise
+func Status(err error) string {
+	if err != nil {
+		return StatusError
+	}
+	return StatusOK
+}
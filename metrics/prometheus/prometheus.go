@@ -0,0 +1,168 @@
+// Package prometheus adapts metrics.Recorder to Prometheus collectors
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace prefixes all metric names unless WithNamespace is used
+const DefaultNamespace = "go_agent"
+
+// Recorder records agent metrics into Prometheus collectors
+type Recorder struct {
+	llmCalls              *prometheus.CounterVec
+	llmDuration           *prometheus.HistogramVec
+	tokens                *prometheus.CounterVec
+	toolCalls             *prometheus.CounterVec
+	toolDuration          *prometheus.HistogramVec
+	runIterations         *prometheus.HistogramVec
+	maxIterationsExceeded prometheus.Counter
+	storeDuration         *prometheus.HistogramVec
+}
+
+type options struct {
+	namespace  string
+	registerer prometheus.Registerer
+	buckets    []float64
+}
+
+// Option configures a Recorder
+type Option func(*options)
+
+// WithNamespace sets the metric name prefix (default "go_agent")
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithRegisterer sets where the collectors are registered
+// (default prometheus.DefaultRegisterer)
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = registerer
+	}
+}
+
+// WithLatencyBuckets sets the histogram buckets in seconds for LLM, tool
+// and store latencies (default prometheus.DefBuckets)
+func WithLatencyBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// New creates a Recorder and registers its collectors
+func New(opts ...Option) (*Recorder, error) {
+	o := options{
+		namespace:  DefaultNamespace,
+		registerer: prometheus.DefaultRegisterer,
+		buckets:    prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Recorder{
+		llmCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "llm_calls_total",
+			Help:      "LLM calls by model and status.",
+		}, []string{"model", "status"}),
+		llmDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "llm_call_duration_seconds",
+			Help:      "LLM call latency by model and status.",
+			Buckets:   o.buckets,
+		}, []string{"model", "status"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "llm_tokens_total",
+			Help:      "LLM tokens by model and type (prompt or completion).",
+		}, []string{"model", "type"}),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "tool_calls_total",
+			Help:      "Tool calls by tool and outcome.",
+		}, []string{"tool", "outcome"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "tool_call_duration_seconds",
+			Help:      "Tool call latency by tool and outcome.",
+			Buckets:   o.buckets,
+		}, []string{"tool", "outcome"}),
+		runIterations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "run_iterations",
+			Help:      "Iterations per run by status.",
+			Buckets:   prometheus.LinearBuckets(1, 1, 10),
+		}, []string{"status"}),
+		maxIterationsExceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "max_iterations_exceeded_total",
+			Help:      "Runs stopped by the iteration limit.",
+		}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "session_store_operation_duration_seconds",
+			Help:      "Session store operation latency by operation and status.",
+			Buckets:   o.buckets,
+		}, []string{"operation", "status"}),
+	}
+
+	for _, collector := range r.Collectors() {
+		if err := o.registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register collector: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// Collectors returns all collectors of the recorder
+func (r *Recorder) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		r.llmCalls, r.llmDuration, r.tokens,
+		r.toolCalls, r.toolDuration,
+		r.runIterations, r.maxIterationsExceeded,
+		r.storeDuration,
+	}
+}
+
+// RecordLLMCall implements metrics.Recorder
+func (r *Recorder) RecordLLMCall(model, status string, duration time.Duration) {
+	r.llmCalls.WithLabelValues(model, status).Inc()
+	r.llmDuration.WithLabelValues(model, status).Observe(duration.Seconds())
+}
+
+// RecordTokens implements metrics.Recorder
+func (r *Recorder) RecordTokens(model, tokenType string, count int) {
+	r.tokens.WithLabelValues(model, tokenType).Add(float64(count))
+}
+
+// RecordToolCall implements metrics.Recorder
+func (r *Recorder) RecordToolCall(tool, outcome string, duration time.Duration) {
+	r.toolCalls.WithLabelValues(tool, outcome).Inc()
+	r.toolDuration.WithLabelValues(tool, outcome).Observe(duration.Seconds())
+}
+
+// RecordRun implements metrics.Recorder
+func (r *Recorder) RecordRun(status string, iterations int) {
+	r.runIterations.WithLabelValues(status).Observe(float64(iterations))
+}
+
+// RecordMaxIterationsExceeded implements metrics.Recorder
+func (r *Recorder) RecordMaxIterationsExceeded() {
+	r.maxIterationsExceeded.Inc()
+}
+
+// RecordStoreOperation implements metrics.Recorder
+func (r *Recorder) RecordStoreOperation(operation, status string, duration time.Duration) {
+	r.storeDuration.WithLabelValues(operation, status).Observe(duration.Seconds())
+}
+
+var _ metrics.Recorder = (*Recorder)(nil)
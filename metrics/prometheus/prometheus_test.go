@@ -0,0 +1,73 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	r, err := New(WithRegisterer(registry), WithNamespace("test"))
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	r.RecordLLMCall("gpt-4o", metrics.StatusOK, 100*time.Millisecond)
+	r.RecordLLMCall("gpt-4o", metrics.StatusError, time.Second)
+	r.RecordTokens("gpt-4o", metrics.TokenPrompt, 120)
+	r.RecordTokens("gpt-4o", metrics.TokenPrompt, 30)
+	r.RecordToolCall("search", metrics.OutcomeSuccess, 50*time.Millisecond)
+	r.RecordRun(metrics.StatusOK, 3)
+	r.RecordMaxIterationsExceeded()
+	r.RecordStoreOperation(metrics.StoreSave, metrics.StatusOK, time.Millisecond)
+
+	if got := testutil.ToFloat64(r.llmCalls.WithLabelValues("gpt-4o", metrics.StatusOK)); got != 1 {
+		t.Errorf("Expected 1 successful LLM call, got %v", got)
+	}
+	if got := testutil.ToFloat64(r.tokens.WithLabelValues("gpt-4o", metrics.TokenPrompt)); got != 150 {
+		t.Errorf("Expected 150 prompt tokens, got %v", got)
+	}
+	if got := testutil.ToFloat64(r.toolCalls.WithLabelValues("search", metrics.OutcomeSuccess)); got != 1 {
+		t.Errorf("Expected 1 tool call, got %v", got)
+	}
+	if got := testutil.ToFloat64(r.maxIterationsExceeded); got != 1 {
+		t.Errorf("Expected 1 max iterations exceeded, got %v", got)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	names := map[string]bool{}
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	for _, name := range []string{
+		"test_llm_calls_total",
+		"test_llm_call_duration_seconds",
+		"test_llm_tokens_total",
+		"test_tool_calls_total",
+		"test_tool_call_duration_seconds",
+		"test_run_iterations",
+		"test_max_iterations_exceeded_total",
+		"test_session_store_operation_duration_seconds",
+	} {
+		if !names[name] {
+			t.Errorf("Expected metric %s to be registered", name)
+		}
+	}
+}
+
+func TestNew_DuplicateRegistration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := New(WithRegisterer(registry)); err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	if _, err := New(WithRegisterer(registry)); err == nil {
+		t.Error("Expected error registering the same metrics twice")
+	}
+}
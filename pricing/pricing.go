@@ -0,0 +1,93 @@
+// Package pricing converts token usage into money using per-model rates.
+//
+// Rates are in USD per million tokens. The package ships no prices, since
+// they change over time; register the models you use:
+//
+//	prices := pricing.NewRegistry()
+//	prices.Set("gpt-4o", pricing.Price{Prompt: 2.50, CachedPrompt: 1.25, Completion: 10.00})
+package pricing
+
+import (
+	"sync"
+	"time"
+)
+
+// Price holds the rates of one model in USD per million tokens
+type Price struct {
+	// Prompt is the rate for input tokens
+	Prompt float64
+
+	// CachedPrompt is the rate for input tokens served from the provider's
+	// prompt cache (0 = same as Prompt)
+	CachedPrompt float64
+
+	// Completion is the rate for output tokens
+	Completion float64
+}
+
+// Cost returns the price in USD of a call. cachedPrompt is the part of
+// prompt that was served from cache.
+func (p Price) Cost(prompt, cachedPrompt, completion int) float64 {
+	cachedRate := p.CachedPrompt
+	if cachedRate == 0 {
+		cachedRate = p.Prompt
+	}
+	if cachedPrompt > prompt {
+		cachedPrompt = prompt
+	}
+
+	return (float64(prompt-cachedPrompt)*p.Prompt +
+		float64(cachedPrompt)*cachedRate +
+		float64(completion)*p.Completion) / 1_000_000
+}
+
+// Registry maps model names to prices. It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	prices map[string]Price
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		prices: make(map[string]Price),
+	}
+}
+
+// Set sets the price of a model, replacing any previous price
+func (r *Registry) Set(model string, price Price) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prices[model] = price
+}
+
+// Get returns the price of a model. A dated snapshot such as
+// "gpt-4o-2024-08-06" falls back to the price of its base name "gpt-4o";
+// other names must match exactly, so "gpt-4o" does not price "gpt-4o-mini".
+func (r *Registry) Get(model string) (Price, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if price, ok := r.prices[model]; ok {
+		return price, true
+	}
+
+	if base, ok := snapshotBase(model); ok {
+		price, ok := r.prices[base]
+		return price, ok
+	}
+	return Price{}, false
+}
+
+// snapshotBase strips a "-YYYY-MM-DD" snapshot suffix from a model name
+func snapshotBase(model string) (string, bool) {
+	const dateLayout = "2006-01-02"
+	i := len(model) - len(dateLayout) - 1
+	if i <= 0 || model[i] != '-' {
+		return "", false
+	}
+	if _, err := time.Parse(dateLayout, model[i+1:]); err != nil {
+		return "", false
+	}
+	return model[:i], true
+}
@@ -0,0 +1,70 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+func TestPrice_Cost(t *testing.T) {
+	price := Price{Prompt: 2.50, CachedPrompt: 1.25, Completion: 10.00}
+
+	tests := []struct {
+		name                       string
+		prompt, cached, completion int
+		want                       float64
+	}{
+		{"uncached", 1000, 0, 500, 0.0025 + 0.005},
+		{"half cached", 1000, 500, 0, 0.00125 + 0.000625},
+		{"cached capped at prompt", 100, 200, 0, 0.000125},
+		{"nothing", 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := price.Cost(tt.prompt, tt.cached, tt.completion); !almostEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrice_CachedDefaultsToPromptRate(t *testing.T) {
+	price := Price{Prompt: 1, Completion: 2}
+	if got := price.Cost(1_000_000, 400_000, 0); !almostEqual(got, 1) {
+		t.Errorf("Expected cached tokens at prompt rate, got %v", got)
+	}
+}
+
+func TestRegistry_Get(t *testing.T) {
+	r := NewRegistry()
+	r.Set("gpt-4o", Price{Prompt: 2.5})
+	r.Set("gpt-4o-mini", Price{Prompt: 0.15})
+	r.Set("o1", Price{Prompt: 15})
+
+	tests := []struct {
+		model string
+		want  float64
+		found bool
+	}{
+		{"gpt-4o", 2.5, true},
+		{"gpt-4o-2024-08-06", 2.5, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, true},
+		{"o1-2024-12-17", 15, true},
+		{"claude", 0, false},
+		// Other variants are not priced as their prefix
+		{"o1-mini", 0, false},
+		{"gpt-4o-audio-preview", 0, false},
+		{"gpt-4o-2024-13-45", 0, false},
+	}
+
+	for _, tt := range tests {
+		price, ok := r.Get(tt.model)
+		if ok != tt.found || price.Prompt != tt.want {
+			t.Errorf("Get(%q): expected %v/%v, got %v/%v", tt.model, tt.want, tt.found, price.Prompt, ok)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds reported by the built-in detectors
+const (
+	KindEmail      = "email"
+	KindPhone      = "phone"
+	KindCreditCard = "credit_card"
+	KindAPIKey     = "api_key"
+)
+
+// Match is a span of sensitive text found by a Detector, as byte offsets
+type Match struct {
+	Start int
+	End   int
+}
+
+// Detector finds one kind of sensitive data in text
+type Detector interface {
+	// Kind names the data found, e.g. "email"; it is used in masks and tokens
+	Kind() string
+
+	// Detect returns the spans of sensitive data in text
+	Detect(text string) []Match
+}
+
+// regexDetector reports every match of a regular expression, optionally
+// filtered by a validation function
+type regexDetector struct {
+	kind     string
+	pattern  *regexp.Regexp
+	validate func(string) bool
+}
+
+// NewRegexDetector creates a detector reporting every match of pattern as kind
+func NewRegexDetector(kind string, pattern *regexp.Regexp) Detector {
+	return &regexDetector{kind: kind, pattern: pattern}
+}
+
+func (d *regexDetector) Kind() string {
+	return d.kind
+}
+
+func (d *regexDetector) Detect(text string) []Match {
+	var matches []Match
+	for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+		if d.validate != nil && !d.validate(text[loc[0]:loc[1]]) {
+			continue
+		}
+		matches = append(matches, Match{Start: loc[0], End: loc[1]})
+	}
+	return matches
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{1,4}\)[\s.\-]?)?\b\d{2,4}[\s.\-]\d{3,4}[\s.\-]?\d{3,4}\b`)
+
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+
+	apiKeyPattern = regexp.MustCompile(`\b(?:` +
+		`sk-[A-Za-z0-9_\-]{20,}` + // OpenAI, Anthropic
+		`|(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}` + // Stripe
+		`|AKIA[0-9A-Z]{16}` + // AWS access key ID
+		`|gh[pousr]_[A-Za-z0-9]{36,}` + // GitHub
+		`|xox[abprs]-[A-Za-z0-9\-]{10,}` + // Slack
+		`|AIza[0-9A-Za-z_\-]{35}` + // Google
+		`)`)
+)
+
+// Email detects email addresses
+func Email() Detector {
+	return NewRegexDetector(KindEmail, emailPattern)
+}
+
+// Phone detects phone numbers written with separators, e.g. +1 555-123-4567
+func Phone() Detector {
+	return NewRegexDetector(KindPhone, phonePattern)
+}
+
+// CreditCard detects card numbers of 13 to 19 digits that pass the Luhn check
+func CreditCard() Detector {
+	return &regexDetector{kind: KindCreditCard, pattern: creditCardPattern, validate: luhnValid}
+}
+
+// APIKey detects API keys with well-known prefixes (OpenAI, Stripe, AWS,
+// GitHub, Slack, Google)
+func APIKey() Detector {
+	return NewRegexDetector(KindAPIKey, apiKeyPattern)
+}
+
+// DefaultDetectors returns all built-in detectors
+func DefaultDetectors() []Detector {
+	return []Detector{APIKey(), CreditCard(), Email(), Phone()}
+}
+
+// luhnValid reports whether the digits in number pass the Luhn checksum
+func luhnValid(number string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
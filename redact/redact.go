@@ -0,0 +1,242 @@
+// Package redact removes personally identifiable information and secrets
+// from text before it reaches a model or the session history.
+//
+// A Redactor either masks sensitive data ("[EMAIL]") or replaces it with
+// reversible tokens ("[EMAIL_1]"). Tokens are kept in the session state, so
+// the same value gets the same token for the whole conversation and Restore
+// can put the original values back, e.g. before calling a tool.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// StateKey is the session state key holding the token to value mapping
+const StateKey = "redaction_tokens"
+
+// Mode selects how sensitive data is replaced
+type Mode int
+
+const (
+	// Mask replaces sensitive data with its kind, e.g. "[EMAIL]"
+	Mask Mode = iota
+
+	// Tokenize replaces sensitive data with a numbered token, e.g. "[EMAIL_1]",
+	// and records the original value in the session
+	Tokenize
+)
+
+// Redactor finds and replaces sensitive data using a set of detectors
+type Redactor struct {
+	detectors []Detector
+	mode      Mode
+}
+
+// Option configures a Redactor
+type Option func(*Redactor)
+
+// WithDetectors replaces the default detectors
+func WithDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) {
+		r.detectors = detectors
+	}
+}
+
+// WithExtraDetectors adds detectors to the configured ones
+func WithExtraDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) {
+		r.detectors = append(r.detectors, detectors...)
+	}
+}
+
+// WithPattern adds a detector for a custom regular expression
+func WithPattern(kind string, pattern *regexp.Regexp) Option {
+	return WithExtraDetectors(NewRegexDetector(kind, pattern))
+}
+
+// WithMode sets how sensitive data is replaced (default Mask)
+func WithMode(mode Mode) Option {
+	return func(r *Redactor) {
+		r.mode = mode
+	}
+}
+
+// New creates a redactor using DefaultDetectors in Mask mode unless
+// configured otherwise
+func New(opts ...Option) *Redactor {
+	r := &Redactor{
+		detectors: DefaultDetectors(),
+		mode:      Mask,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Mode returns the replacement mode of the redactor
+func (r *Redactor) Mode() Mode {
+	return r.mode
+}
+
+// Redact replaces all sensitive data in text. In Tokenize mode the tokens
+// are recorded in sess; without a session, Tokenize falls back to masking.
+func (r *Redactor) Redact(sess session.Session, text string) string {
+	spans := r.find(text)
+	if len(spans) == 0 {
+		return text
+	}
+
+	var tokens map[string]string
+	if r.mode == Tokenize && sess != nil {
+		tokens = loadTokens(sess)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s.Start])
+		if tokens != nil {
+			b.WriteString(tokenFor(tokens, s.kind, text[s.Start:s.End]))
+		} else {
+			b.WriteString("[" + strings.ToUpper(s.kind) + "]")
+		}
+		last = s.End
+	}
+	b.WriteString(text[last:])
+
+	if tokens != nil {
+		saveTokens(sess, tokens)
+	}
+
+	return b.String()
+}
+
+// Restore replaces tokens recorded in sess with their original values
+func Restore(sess session.Session, text string) string {
+	return restore(sess, text, func(value string) string { return value })
+}
+
+// RestoreJSON is like Restore but escapes the original values for use
+// inside JSON strings, e.g. tool call arguments
+func RestoreJSON(sess session.Session, text string) string {
+	return restore(sess, text, func(value string) string {
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	})
+}
+
+func restore(sess session.Session, text string, escape func(string) string) string {
+	if sess == nil || !strings.Contains(text, "[") {
+		return text
+	}
+
+	tokens := loadTokens(sess)
+	if len(tokens) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, len(tokens)*2)
+	for token, value := range tokens {
+		pairs = append(pairs, token, escape(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// span is a match annotated with the kind of its detector
+type span struct {
+	Match
+	kind string
+}
+
+// find runs all detectors and resolves overlapping matches: the earliest
+// match wins, and of matches starting at the same place the longest wins
+func (r *Redactor) find(text string) []span {
+	var spans []span
+	for _, detector := range r.detectors {
+		for _, match := range detector.Detect(text) {
+			if match.Start < 0 || match.End > len(text) || match.Start >= match.End {
+				continue
+			}
+			spans = append(spans, span{Match: match, kind: detector.Kind()})
+		}
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].Start != spans[j].Start {
+			return spans[i].Start < spans[j].Start
+		}
+		return spans[i].End > spans[j].End
+	})
+
+	resolved := spans[:0]
+	end := 0
+	for _, s := range spans {
+		if s.Start < end {
+			continue
+		}
+		resolved = append(resolved, s)
+		end = s.End
+	}
+
+	return resolved
+}
+
+// tokenFor returns the token for value, creating the next numbered token of
+// its kind if the value has not been seen before
+func tokenFor(tokens map[string]string, kind, value string) string {
+	prefix := "[" + strings.ToUpper(kind) + "_"
+
+	count := 0
+	for token, original := range tokens {
+		if !strings.HasPrefix(token, prefix) {
+			continue
+		}
+		if original == value {
+			return token
+		}
+		count++
+	}
+
+	token := fmt.Sprintf("%s%d]", prefix, count+1)
+	tokens[token] = value
+	return token
+}
+
+// loadTokens reads the token mapping from the session state. Persistent
+// stores return it as map[string]any after a reload.
+func loadTokens(sess session.Session) map[string]string {
+	tokens := make(map[string]string)
+
+	value, exists := sess.Get(StateKey)
+	if !exists {
+		return tokens
+	}
+
+	switch stored := value.(type) {
+	case map[string]string:
+		for token, original := range stored {
+			tokens[token] = original
+		}
+	case map[string]any:
+		for token, original := range stored {
+			if s, ok := original.(string); ok {
+				tokens[token] = s
+			}
+		}
+	}
+
+	return tokens
+}
+
+func saveTokens(sess session.Session, tokens map[string]string) {
+	sess.Set(StateKey, tokens)
+}
@@ -0,0 +1,136 @@
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// envelopePrefix marks strings holding encrypted state or metadata values.
+// The full format is "enc:v1:<key id>:<base64 nonce+ciphertext>".
+const envelopePrefix = "enc:v1:"
+
+// ErrDecrypt indicates that a record could not be decrypted
+var ErrDecrypt = errors.New("failed to decrypt session data")
+
+// sealer encrypts and decrypts records with AES-GCM using keys from a provider.
+// The associated data binds each ciphertext to the session and record it was
+// written for, so encrypted values cannot be moved between sessions, keys or
+// entries, nor an entry's type changed.
+type sealer struct {
+	keys KeyProvider
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD // key ID -> cipher
+}
+
+func newSealer(keys KeyProvider) *sealer {
+	return &sealer{
+		keys:  keys,
+		aeads: make(map[string]cipher.AEAD),
+	}
+}
+
+// seal encrypts plaintext with the current key and returns its key ID
+func (s *sealer) seal(plaintext []byte, aad string) (string, []byte, error) {
+	id, key, err := s.keys.CurrentKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get current key: %w", err)
+	}
+
+	aead, err := s.aead(id, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return id, aead.Seal(nonce, nonce, plaintext, []byte(aad)), nil
+}
+
+// open decrypts data sealed with the key id
+func (s *sealer) open(id string, data []byte, aad string) ([]byte, error) {
+	key, err := s.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := s.aead(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(aad))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
+
+// sealString encrypts plaintext into a self-describing envelope string
+func (s *sealer) sealString(plaintext []byte, aad string) (string, error) {
+	id, data, err := s.seal(plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix + id + ":" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// openString decrypts an envelope string. ok is false if value is not an
+// envelope, e.g. because it was written before encryption was enabled.
+func (s *sealer) openString(value, aad string) (plaintext []byte, ok bool, err error) {
+	rest, isEnvelope := strings.CutPrefix(value, envelopePrefix)
+	if !isEnvelope {
+		return nil, false, nil
+	}
+
+	id, encoded, found := strings.Cut(rest, ":")
+	if !found {
+		return nil, true, ErrDecrypt
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, true, ErrDecrypt
+	}
+
+	plaintext, err = s.open(id, data, aad)
+	return plaintext, true, err
+}
+
+// aead returns the cached cipher for a key ID
+func (s *sealer) aead(id string, key []byte) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if aead, exists := s.aeads[id]; exists {
+		return aead, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %s: %w", id, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher for key %s: %w", id, err)
+	}
+
+	s.aeads[id] = aead
+	return aead, nil
+}
@@ -0,0 +1,98 @@
+package encrypted
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Key errors
+var (
+	ErrUnknownKey = errors.New("unknown encryption key")
+	ErrInvalidKey = errors.New("encryption key must be 16, 24 or 32 bytes")
+)
+
+// KeyProvider supplies the AES keys used by the store. Every record stores
+// the ID of the key it was encrypted with, so keys can be rotated by
+// changing the current key while older keys remain available to Key.
+type KeyProvider interface {
+	// CurrentKey returns the ID and key used to encrypt new data
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with the given ID, or ErrUnknownKey
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is an in-memory KeyProvider holding the current key and any
+// number of retired keys that are only used for decryption
+type KeyRing struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyRing creates a key ring that encrypts with the given key
+func NewKeyRing(id string, key []byte) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string][]byte)}
+	if err := ring.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// Add makes a key available for decryption without using it for new data
+func (r *KeyRing) Add(id string, key []byte) error {
+	if err := validateKey(id, key); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[id] = append([]byte(nil), key...)
+	return nil
+}
+
+// Rotate adds a key and makes it the current key. Previous keys are kept
+// so existing records can still be decrypted.
+func (r *KeyRing) Rotate(id string, key []byte) error {
+	if err := r.Add(id, key); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = id
+	return nil
+}
+
+// CurrentKey returns the ID and key used to encrypt new data
+func (r *KeyRing) CurrentKey() (string, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, r.keys[r.current], nil
+}
+
+// Key returns the key with the given ID
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+func validateKey(id string, key []byte) error {
+	if id == "" {
+		return errors.New("encryption key ID must not be empty")
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return ErrInvalidKey
+	}
+}
@@ -0,0 +1,289 @@
+package encrypted
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// EntryTypeEncrypted is the entry type under which encrypted entries are
+// stored in the wrapped store
+const EntryTypeEncrypted session.EntryType = "encrypted"
+
+// EncryptedContent is the stored content of an encrypted entry
+type EncryptedContent struct {
+	KeyID string            `json:"key_id"`
+	Type  session.EntryType `json:"type"`
+	Data  []byte            `json:"data"` // nonce + AES-GCM ciphertext
+}
+
+// entryPayload is the plaintext sealed into EncryptedContent.Data
+type entryPayload struct {
+	Content  json.RawMessage `json:"content"`
+	Metadata map[string]any  `json:"metadata"`
+}
+
+func init() {
+	session.RegisterEntryType(EntryTypeEncrypted, EncryptedContent{})
+}
+
+// encryptedSession decrypts and encrypts data on its way to the wrapped session.
+// Values that fail to decrypt are reported as missing, and history entries
+// that fail to decrypt are left out. So are values and entries that are not
+// encrypted, unless the store was created with AllowPlaintext.
+type encryptedSession struct {
+	store *Store
+	inner session.Session
+}
+
+// ID returns the session ID
+func (s *encryptedSession) ID() string {
+	return s.inner.ID()
+}
+
+// CreatedAt returns when the session was created
+func (s *encryptedSession) CreatedAt() time.Time {
+	return s.inner.CreatedAt()
+}
+
+// UpdatedAt returns when the session was last updated
+func (s *encryptedSession) UpdatedAt() time.Time {
+	return s.inner.UpdatedAt()
+}
+
+// Get retrieves and decrypts a value from the session state
+func (s *encryptedSession) Get(key string) (any, bool) {
+	value, exists := s.inner.Get(key)
+	if !exists {
+		return nil, false
+	}
+	return s.openValue(key, value)
+}
+
+// Set encrypts and stores a value in the session state.
+// Values that cannot be encoded or encrypted are not stored.
+func (s *encryptedSession) Set(key string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	sealed, err := s.store.sealer.sealString(data, stateAAD(s.ID(), key))
+	if err != nil {
+		return
+	}
+
+	s.inner.Set(key, sealed)
+}
+
+// Delete removes a value from the session state
+func (s *encryptedSession) Delete(key string) {
+	s.inner.Delete(key)
+}
+
+// State returns all state values that can be decrypted
+func (s *encryptedSession) State() map[string]any {
+	reader, ok := s.inner.(session.StateReader)
+	if !ok {
+		return map[string]any{}
+	}
+
+	state := make(map[string]any)
+	for key, value := range reader.State() {
+		if plain, ok := s.openValue(key, value); ok {
+			state[key] = plain
+		}
+	}
+	return state
+}
+
+// Metadata returns all metadata values that can be decrypted
+func (s *encryptedSession) Metadata() map[string]string {
+	metadata := make(map[string]string)
+	for key, value := range s.inner.Metadata() {
+		if plain, ok := s.openMetadata(key, value); ok {
+			metadata[key] = plain
+		}
+	}
+	return metadata
+}
+
+// GetMetadata retrieves and decrypts a metadata value
+func (s *encryptedSession) GetMetadata(key string) (string, bool) {
+	value, exists := s.inner.GetMetadata(key)
+	if !exists {
+		return "", false
+	}
+	return s.openMetadata(key, value)
+}
+
+// SetMetadata encrypts and stores a metadata value
+func (s *encryptedSession) SetMetadata(key, value string) {
+	sealed, err := s.store.sealer.sealString([]byte(value), metadataAAD(s.ID(), key))
+	if err != nil {
+		return
+	}
+	s.inner.SetMetadata(key, sealed)
+}
+
+// ExpiresAt returns when the session expires, or nil if it never does
+func (s *encryptedSession) ExpiresAt() *time.Time {
+	return s.inner.ExpiresAt()
+}
+
+// Touch restarts the session TTL from now
+func (s *encryptedSession) Touch() {
+	s.inner.Touch()
+}
+
+// Extend pushes the session expiry back by d
+func (s *encryptedSession) Extend(d time.Duration) {
+	s.inner.Extend(d)
+}
+
+// Version returns the version of the wrapped session, or 0 if it is not versioned
+func (s *encryptedSession) Version() int64 {
+	if versioned, ok := s.inner.(session.Versioned); ok {
+		return versioned.Version()
+	}
+	return 0
+}
+
+// AddEntry encrypts the entry content and metadata and appends it to the history
+func (s *encryptedSession) AddEntry(entry session.Entry) error {
+	content, err := json.Marshal(entry.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry content: %w", err)
+	}
+
+	payload, err := json.Marshal(entryPayload{Content: content, Metadata: entry.Metadata})
+	if err != nil {
+		return fmt.Errorf("failed to encode entry metadata: %w", err)
+	}
+
+	keyID, data, err := s.store.sealer.seal(payload, entryAAD(s.ID(), entry.ID, entry.Type))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt entry: %w", err)
+	}
+
+	return s.inner.AddEntry(session.Entry{
+		ID:        entry.ID,
+		Type:      EntryTypeEncrypted,
+		Timestamp: entry.Timestamp,
+		Content: EncryptedContent{
+			KeyID: keyID,
+			Type:  entry.Type,
+			Data:  data,
+		},
+		Metadata: make(map[string]any),
+	})
+}
+
+// GetHistory returns the decrypted session history, newest first
+func (s *encryptedSession) GetHistory(limit int) []session.Entry {
+	stored := s.inner.GetHistory(limit)
+
+	history := make([]session.Entry, 0, len(stored))
+	for _, entry := range stored {
+		if plain, ok := s.openEntry(entry); ok {
+			history = append(history, plain)
+		}
+	}
+	return history
+}
+
+// openEntry decrypts a stored entry. Entries that were not encrypted are
+// returned unchanged if the store allows plaintext.
+func (s *encryptedSession) openEntry(entry session.Entry) (session.Entry, bool) {
+	if entry.Type != EntryTypeEncrypted {
+		return entry, s.store.allowPlaintext
+	}
+
+	content, ok := entry.Content.(EncryptedContent)
+	if !ok {
+		return session.Entry{}, false
+	}
+
+	plaintext, err := s.store.sealer.open(content.KeyID, content.Data, entryAAD(s.ID(), entry.ID, content.Type))
+	if err != nil {
+		return session.Entry{}, false
+	}
+
+	var payload entryPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return session.Entry{}, false
+	}
+
+	restored, err := session.UnmarshalContent(content.Type, payload.Content)
+	if err != nil {
+		return session.Entry{}, false
+	}
+
+	if payload.Metadata == nil {
+		payload.Metadata = make(map[string]any)
+	}
+
+	return session.Entry{
+		ID:        entry.ID,
+		Type:      content.Type,
+		Timestamp: entry.Timestamp,
+		Content:   restored,
+		Metadata:  payload.Metadata,
+	}, true
+}
+
+// openValue decrypts a stored state value
+func (s *encryptedSession) openValue(key string, value any) (any, bool) {
+	sealed, ok := value.(string)
+	if !ok {
+		return value, s.store.allowPlaintext
+	}
+
+	plaintext, isEnvelope, err := s.store.sealer.openString(sealed, stateAAD(s.ID(), key))
+	if !isEnvelope {
+		return value, s.store.allowPlaintext
+	}
+	if err != nil {
+		return nil, false
+	}
+
+	var plain any
+	if err := json.Unmarshal(plaintext, &plain); err != nil {
+		return nil, false
+	}
+	return plain, true
+}
+
+// openMetadata decrypts a stored metadata value
+func (s *encryptedSession) openMetadata(key, value string) (string, bool) {
+	plaintext, isEnvelope, err := s.store.sealer.openString(value, metadataAAD(s.ID(), key))
+	if !isEnvelope {
+		return value, s.store.allowPlaintext
+	}
+	if err != nil {
+		return "", false
+	}
+	return string(plaintext), true
+}
+
+// Associated data binding each ciphertext to its session and the record it
+// belongs to; entries are also bound to their type
+func stateAAD(sessionID, key string) string    { return associatedData("state", sessionID, key) }
+func metadataAAD(sessionID, key string) string { return associatedData("metadata", sessionID, key) }
+func entryAAD(sessionID, id string, entryType session.EntryType) string {
+	return associatedData("entry", sessionID, id, string(entryType))
+}
+
+// associatedData joins fields with length prefixes, so that different
+// records never share associated data
+func associatedData(kind string, fields ...string) string {
+	var b strings.Builder
+	b.WriteString(kind)
+	for _, field := range fields {
+		fmt.Fprintf(&b, ":%d:%s", len(field), field)
+	}
+	return b.String()
+}
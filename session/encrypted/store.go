@@ -0,0 +1,161 @@
+// Package encrypted provides a SessionStore decorator that encrypts session
+// data at rest with AES-GCM. It wraps any session.SessionStore, so the wrapped
+// store only ever sees ciphertext for entry content, entry metadata, state
+// values and session metadata values.
+//
+// IDs, timestamps, state keys, metadata keys and entry types stay in plaintext.
+// Metadata filters of session.SessionQuerier therefore cannot match encrypted
+// values, and state values are restored through encoding/json, so numbers
+// come back as float64 even with the memory store.
+package encrypted
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/davidleitw/go-agent/session"
+	"github.com/google/uuid"
+)
+
+// Store is a SessionStore decorator that encrypts session data
+type Store struct {
+	inner          session.SessionStore
+	sealer         *sealer
+	allowPlaintext bool
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// AllowPlaintext returns state values, metadata values and entries that are
+// not encrypted, such as data written before encryption was enabled. Use it
+// only to migrate existing sessions: anyone who can write to the wrapped
+// store can then inject plaintext.
+func AllowPlaintext() Option {
+	return func(s *Store) {
+		s.allowPlaintext = true
+	}
+}
+
+// NewStore wraps inner so that all session data written through the
+// returned store is encrypted with keys from keys
+func NewStore(inner session.SessionStore, keys KeyProvider, opts ...Option) *Store {
+	store := &Store{
+		inner:  inner,
+		sealer: newSealer(keys),
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	return store
+}
+
+// Create creates a session in the wrapped store with encrypted metadata.
+// Metadata values that cannot be encrypted are dropped rather than stored
+// in plaintext.
+func (s *Store) Create(ctx context.Context, opts ...session.CreateOption) session.Session {
+	options := session.ApplyOptions(opts...)
+
+	// Ciphertext is bound to the session ID, so it must be known up front
+	if options.ID == "" {
+		options.ID = uuid.New().String()
+	}
+
+	innerOpts := []session.CreateOption{
+		session.WithID(options.ID),
+	}
+	if options.SlidingTTL {
+		innerOpts = append(innerOpts, session.WithSlidingTTL(options.TTL))
+	} else {
+		innerOpts = append(innerOpts, session.WithTTL(options.TTL))
+	}
+
+	for key, value := range options.Metadata {
+		sealed, err := s.sealer.sealString([]byte(value), metadataAAD(options.ID, key))
+		if err != nil {
+			continue
+		}
+		innerOpts = append(innerOpts, session.WithMetadata(key, sealed))
+	}
+
+	return s.wrap(s.inner.Create(ctx, innerOpts...))
+}
+
+// Get retrieves a session from the wrapped store
+func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
+	sess, err := s.inner.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.wrap(sess), nil
+}
+
+// Save saves a session created or loaded through this store
+func (s *Store) Save(ctx context.Context, sess session.Session) error {
+	es, ok := sess.(*encryptedSession)
+	if !ok || es.store != s {
+		return fmt.Errorf("session %s does not belong to this store", sess.ID())
+	}
+	return s.inner.Save(ctx, es.inner)
+}
+
+// Delete removes a session from the wrapped store
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.inner.Delete(ctx, id)
+}
+
+// DeleteExpired removes expired sessions from the wrapped store
+func (s *Store) DeleteExpired(ctx context.Context) error {
+	return s.inner.DeleteExpired(ctx)
+}
+
+// Close closes the wrapped store
+func (s *Store) Close() error {
+	return s.inner.Close()
+}
+
+// Fork branches a session. Ciphertext is bound to its session, so the
+// state, metadata and history up to atEntryID are decrypted and encrypted
+// again for the new session; records that fail to decrypt are not copied.
+func (s *Store) Fork(ctx context.Context, sourceID, atEntryID string, opts ...session.CreateOption) (session.Session, error) {
+	value, err := s.Get(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source := value.(*encryptedSession)
+
+	// GetHistory is newest first; the fork replays it oldest first
+	history := source.GetHistory(0)
+	slices.Reverse(history)
+	if atEntryID != "" {
+		i := slices.IndexFunc(history, func(entry session.Entry) bool { return entry.ID == atEntryID })
+		if i < 0 {
+			return nil, session.ErrEntryNotFound
+		}
+		history = history[:i+1]
+	}
+
+	forked := s.Create(ctx, session.ForkOptions(sourceID, atEntryID, source.Metadata(), opts...)...)
+	for key, value := range source.State() {
+		forked.Set(key, value)
+	}
+	for _, entry := range history {
+		if err := forked.AddEntry(entry); err != nil {
+			s.inner.Delete(ctx, forked.ID())
+			return nil, fmt.Errorf("failed to copy entry %s: %w", entry.ID, err)
+		}
+	}
+
+	if err := s.Save(ctx, forked); err != nil {
+		s.inner.Delete(ctx, forked.ID())
+		return nil, fmt.Errorf("failed to save forked session: %w", err)
+	}
+	return forked, nil
+}
+
+func (s *Store) wrap(sess session.Session) *encryptedSession {
+	return &encryptedSession{store: s, inner: sess}
+}
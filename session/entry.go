@@ -1,6 +1,10 @@
 package session
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,6 +29,78 @@ type Entry struct {
 	Metadata  map[string]any `json:"metadata"`
 }
 
+// contentTypes maps entry types to the concrete Go type of their content
+var (
+	contentTypesMu sync.RWMutex
+	contentTypes   = map[EntryType]reflect.Type{
+		EntryTypeMessage:    reflect.TypeOf(MessageContent{}),
+		EntryTypeToolCall:   reflect.TypeOf(ToolCallContent{}),
+		EntryTypeToolResult: reflect.TypeOf(ToolResultContent{}),
+	}
+)
+
+// RegisterEntryType registers the content type for a custom entry type so that
+// its content is restored as that type when an Entry is unmarshaled from JSON.
+// prototype is a value of the content type, e.g. RegisterEntryType("plan", PlanContent{}).
+// Registering an existing entry type replaces its content type. It panics
+// if prototype is nil.
+func RegisterEntryType(entryType EntryType, prototype any) {
+	if prototype == nil {
+		panic("session: RegisterEntryType prototype is nil for entry type " + string(entryType))
+	}
+	contentTypesMu.Lock()
+	defer contentTypesMu.Unlock()
+	contentTypes[entryType] = reflect.TypeOf(prototype)
+}
+
+// UnmarshalContent decodes JSON content into the type registered for entryType.
+// Content of unregistered entry types is decoded as plain encoding/json values.
+func UnmarshalContent(entryType EntryType, data []byte) (any, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	contentTypesMu.RLock()
+	contentType, ok := contentTypes[entryType]
+	contentTypesMu.RUnlock()
+
+	if !ok {
+		var content any
+		err := json.Unmarshal(data, &content)
+		return content, err
+	}
+
+	content := reflect.New(contentType)
+	if err := json.Unmarshal(data, content.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %s content: %w", entryType, err)
+	}
+	return content.Elem().Interface(), nil
+}
+
+// UnmarshalJSON decodes an entry and restores the concrete content type based on Type
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	// entryAlias has the same fields but none of the methods, avoiding recursion
+	type entryAlias Entry
+	aux := struct {
+		*entryAlias
+		Content json.RawMessage `json:"content"`
+	}{
+		entryAlias: (*entryAlias)(e),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	content, err := UnmarshalContent(e.Type, aux.Content)
+	if err != nil {
+		return err
+	}
+	e.Content = content
+
+	return nil
+}
+
 // MessageContent represents a message entry content
 type MessageContent struct {
 	Role string `json:"role"` // user/assistant/system
@@ -94,6 +170,18 @@ func NewToolResultEntry(tool string, result any, err error) Entry {
 	}
 }
 
+// NewThinkingEntry creates a new thinking entry, recording reasoning that
+// is not part of the conversation
+func NewThinkingEntry(text string) Entry {
+	return Entry{
+		ID:        uuid.New().String(),
+		Type:      EntryTypeThinking,
+		Timestamp: time.Now(),
+		Content:   text,
+		Metadata:  make(map[string]any),
+	}
+}
+
 // GetMessageContent extracts MessageContent from an entry
 func GetMessageContent(entry Entry) (MessageContent, bool) {
 	if entry.Type != EntryTypeMessage {
@@ -120,3 +208,12 @@ func GetToolResultContent(entry Entry) (ToolResultContent, bool) {
 	content, ok := entry.Content.(ToolResultContent)
 	return content, ok
 }
+
+// GetThinkingContent extracts the text of a thinking entry
+func GetThinkingContent(entry Entry) (string, bool) {
+	if entry.Type != EntryTypeThinking {
+		return "", false
+	}
+	text, ok := entry.Content.(string)
+	return text, ok
+}
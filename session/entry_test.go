@@ -32,8 +32,15 @@ func TestEntryJSONSerialization(t *testing.T) {
 		t.Errorf("Type mismatch: expected %s, got %s", msgEntry.Type, deserializedEntry.Type)
 	}
 
-	// Note: Content will be map[string]interface{} after JSON unmarshaling
-	// This is expected behavior with interface{} fields
+	// Content is restored as MessageContent based on the entry type
+	content, ok := GetMessageContent(deserializedEntry)
+	if !ok {
+		t.Fatalf("Expected MessageContent after unmarshal, got %T", deserializedEntry.Content)
+	}
+
+	if content.Role != "user" || content.Text != "Hello world" {
+		t.Errorf("Content mismatch: got role=%s, text=%s", content.Role, content.Text)
+	}
 }
 
 func TestMessageContentJSONSerialization(t *testing.T) {
@@ -199,6 +206,22 @@ func TestCompleteEntryJSONRoundtrip(t *testing.T) {
 				i, deserializedEntry.Metadata["test_key"])
 		}
 
+		// Verify typed content survives the round trip
+		switch deserializedEntry.Type {
+		case EntryTypeMessage:
+			if _, ok := GetMessageContent(deserializedEntry); !ok {
+				t.Errorf("Entry %d expected MessageContent, got %T", i, deserializedEntry.Content)
+			}
+		case EntryTypeToolCall:
+			if content, ok := GetToolCallContent(deserializedEntry); !ok || content.Parameters["query"] != "test" {
+				t.Errorf("Entry %d expected ToolCallContent, got %#v", i, deserializedEntry.Content)
+			}
+		case EntryTypeToolResult:
+			if content, ok := GetToolResultContent(deserializedEntry); !ok || content.Result != "result" {
+				t.Errorf("Entry %d expected ToolResultContent, got %#v", i, deserializedEntry.Content)
+			}
+		}
+
 		// Index will be float64 after JSON unmarshaling (JSON number handling)
 		if indexValue, ok := deserializedEntry.Metadata["index"].(float64); !ok || int(indexValue) != i {
 			t.Errorf("Entry %d index metadata mismatch: expected %d, got %v",
@@ -268,3 +291,92 @@ func TestTimestampJSONHandling(t *testing.T) {
 			originalTime, deserializedEntry.Timestamp, timeDiff)
 	}
 }
+
+// planContent is a custom entry content type used to test the registry
+type planContent struct {
+	Steps []string `json:"steps"`
+}
+
+func TestRegisterEntryType(t *testing.T) {
+	const entryTypePlan EntryType = "test_plan"
+	RegisterEntryType(entryTypePlan, planContent{})
+
+	entry := Entry{
+		ID:        "plan-1",
+		Type:      entryTypePlan,
+		Timestamp: time.Now(),
+		Content:   planContent{Steps: []string{"search", "summarize"}},
+		Metadata:  map[string]any{},
+	}
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to marshal entry: %v", err)
+	}
+
+	var deserializedEntry Entry
+	if err := json.Unmarshal(jsonData, &deserializedEntry); err != nil {
+		t.Fatalf("Failed to unmarshal entry: %v", err)
+	}
+
+	content, ok := deserializedEntry.Content.(planContent)
+	if !ok {
+		t.Fatalf("Expected planContent, got %T", deserializedEntry.Content)
+	}
+	if len(content.Steps) != 2 || content.Steps[1] != "summarize" {
+		t.Errorf("Steps mismatch: got %v", content.Steps)
+	}
+}
+
+func TestRegisterEntryType_NilPrototype(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected RegisterEntryType to panic on a nil prototype")
+		}
+	}()
+	RegisterEntryType("test_nil", nil)
+}
+
+func TestThinkingEntryJSONRoundtrip(t *testing.T) {
+	data, err := json.Marshal(NewThinkingEntry("check the dates"))
+	if err != nil {
+		t.Fatalf("Failed to marshal thinking entry: %v", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Failed to unmarshal thinking entry: %v", err)
+	}
+
+	text, ok := GetThinkingContent(entry)
+	if !ok || text != "check the dates" {
+		t.Errorf("Expected thinking text, got %#v", entry.Content)
+	}
+}
+
+func TestUnregisteredEntryType(t *testing.T) {
+	data := []byte(`{"id":"x","type":"unknown","timestamp":"2024-01-01T12:00:00Z","content":{"key":"value"},"metadata":{}}`)
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Failed to unmarshal entry: %v", err)
+	}
+
+	// Unknown types fall back to generic JSON values
+	content, ok := entry.Content.(map[string]any)
+	if !ok || content["key"] != "value" {
+		t.Errorf("Expected generic map content, got %#v", entry.Content)
+	}
+}
+
+func TestUnmarshalContent_NullAndInvalid(t *testing.T) {
+	content, err := UnmarshalContent(EntryTypeMessage, []byte("null"))
+	if err != nil || content != nil {
+		t.Errorf("Expected nil content for null, got %#v, %v", content, err)
+	}
+
+	_, err = UnmarshalContent(EntryTypeMessage, []byte(`{"role": 42}`))
+	if err == nil {
+		t.Error("Expected error for content that does not match the registered type")
+	}
+}
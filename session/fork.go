@@ -0,0 +1,49 @@
+package session
+
+import (
+	"context"
+	"errors"
+)
+
+// Metadata keys recorded on forked sessions
+const (
+	MetadataParentSessionID = "parent_session_id"
+	MetadataForkedAtEntryID = "forked_at_entry_id"
+)
+
+// ErrForkNotSupported indicates the store does not implement SessionForker
+var ErrForkNotSupported = errors.New("session store does not support forking")
+
+// SessionForker is an optional interface for stores that can branch a session.
+//
+// Fork creates a new session that copies the state and metadata of the source
+// session and its history up to and including the entry atEntryID (an empty
+// atEntryID copies the full history). The new session records its parent in
+// the MetadataParentSessionID and MetadataForkedAtEntryID metadata keys
+// (the latter is empty for a full copy).
+// opts apply to the new session as they would to Create; metadata given in
+// opts overrides metadata copied from the source. State values are copied
+// shallowly, so mutable values such as maps are shared until replaced.
+//
+// Fork returns ErrSessionNotFound if the source does not exist and
+// ErrEntryNotFound if atEntryID is not part of its history.
+type SessionForker interface {
+	Fork(ctx context.Context, sourceID, atEntryID string, opts ...CreateOption) (Session, error)
+}
+
+// ForkOptions prepends the source metadata and appends the parent link to opts,
+// so stores only need to apply the result when creating the forked session
+func ForkOptions(sourceID, atEntryID string, sourceMetadata map[string]string, opts ...CreateOption) []CreateOption {
+	forkOpts := make([]CreateOption, 0, len(sourceMetadata)+len(opts)+2)
+	for key, value := range sourceMetadata {
+		forkOpts = append(forkOpts, WithMetadata(key, value))
+	}
+
+	forkOpts = append(forkOpts, opts...)
+	forkOpts = append(forkOpts,
+		WithMetadata(MetadataParentSessionID, sourceID),
+		WithMetadata(MetadataForkedAtEntryID, atEntryID),
+	)
+
+	return forkOpts
+}
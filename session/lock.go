@@ -0,0 +1,72 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Concurrency errors
+var (
+	ErrSessionLocked   = errors.New("session is locked by another execution")
+	ErrVersionConflict = errors.New("session was modified concurrently")
+)
+
+// LockMode selects what happens when a session is already locked
+type LockMode int
+
+const (
+	// LockWait blocks until the lock is free, up to LockOptions.Timeout
+	LockWait LockMode = iota
+
+	// LockFailFast returns ErrSessionLocked immediately
+	LockFailFast
+
+	// LockQueue waits in arrival order without a timeout, but rejects the
+	// request with ErrSessionLocked when LockOptions.MaxQueue callers are
+	// already waiting
+	LockQueue
+)
+
+// String returns the name of the lock mode
+func (m LockMode) String() string {
+	switch m {
+	case LockWait:
+		return "wait"
+	case LockFailFast:
+		return "fail_fast"
+	case LockQueue:
+		return "queue"
+	default:
+		return "unknown"
+	}
+}
+
+// LockOptions configures how a lock is acquired
+type LockOptions struct {
+	Mode LockMode
+
+	// Timeout bounds the wait in LockWait mode (0 = until ctx is done)
+	Timeout time.Duration
+
+	// MaxQueue bounds the number of waiters in LockQueue mode (0 = unbounded)
+	MaxQueue int
+}
+
+// SessionLocker serializes work on a session. The in-process implementation
+// is memory.Locker; a store shared between processes can provide a
+// distributed implementation of the same interface.
+type SessionLocker interface {
+	// Lock acquires the lock for sessionID and returns a function that
+	// releases it. It returns ErrSessionLocked when the lock cannot be
+	// acquired under opts, or ctx.Err() when ctx is done first.
+	Lock(ctx context.Context, sessionID string, opts LockOptions) (unlock func(), err error)
+}
+
+// Versioned is implemented by sessions that support optimistic concurrency.
+// Version is the number of successful saves of the stored session at the
+// time this value was loaded or last saved. Save returns ErrVersionConflict
+// when the stored session has been saved by someone else in the meantime.
+type Versioned interface {
+	Version() int64
+}
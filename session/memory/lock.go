@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// Locker is an in-process implementation of session.SessionLocker.
+// Waiters are granted the lock in arrival order. It only serializes callers
+// within one process; services sharing a store across processes need a
+// distributed SessionLocker.
+type Locker struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+// sessionLock tracks the callers waiting for a locked session
+type sessionLock struct {
+	waiters []chan struct{}
+}
+
+// NewLocker creates a new in-process session locker
+func NewLocker() *Locker {
+	return &Locker{
+		locks: make(map[string]*sessionLock),
+	}
+}
+
+// Lock acquires the lock for sessionID. See session.SessionLocker.
+func (l *Locker) Lock(ctx context.Context, sessionID string, opts session.LockOptions) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	lock, held := l.locks[sessionID]
+	if !held {
+		// A session is locked exactly while it has an entry in the map
+		l.locks[sessionID] = &sessionLock{}
+		l.mu.Unlock()
+		return l.unlocker(sessionID), nil
+	}
+
+	switch opts.Mode {
+	case session.LockFailFast:
+		l.mu.Unlock()
+		return nil, session.ErrSessionLocked
+	case session.LockQueue:
+		if opts.MaxQueue > 0 && len(lock.waiters) >= opts.MaxQueue {
+			l.mu.Unlock()
+			return nil, session.ErrSessionLocked
+		}
+	}
+
+	ready := make(chan struct{})
+	lock.waiters = append(lock.waiters, ready)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if opts.Mode == session.LockWait && opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return l.unlocker(sessionID), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = session.ErrSessionLocked
+	}
+
+	// Give up our place in the queue. If the lock was handed to us in the
+	// meantime, pass it on to the next waiter instead.
+	l.mu.Lock()
+	for i, waiter := range lock.waiters {
+		if waiter == ready {
+			lock.waiters = append(lock.waiters[:i], lock.waiters[i+1:]...)
+			l.mu.Unlock()
+			return nil, err
+		}
+	}
+	l.mu.Unlock()
+	l.release(sessionID)
+
+	return nil, err
+}
+
+// unlocker returns a release function that is safe to call more than once
+func (l *Locker) unlocker(sessionID string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.release(sessionID) })
+	}
+}
+
+// release hands the lock to the oldest waiter, or frees it if there is none
+func (l *Locker) release(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, held := l.locks[sessionID]
+	if !held {
+		return
+	}
+
+	if len(lock.waiters) == 0 {
+		delete(l.locks, sessionID)
+		return
+	}
+
+	next := lock.waiters[0]
+	lock.waiters = lock.waiters[1:]
+	close(next)
+}
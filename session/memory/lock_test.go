@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+func TestLocker_Serializes(t *testing.T) {
+	locker := NewLocker()
+	ctx := context.Background()
+
+	var (
+		mu      sync.Mutex
+		running int
+		maxSeen int
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := locker.Lock(ctx, "s1", session.LockOptions{Mode: session.LockWait})
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			defer unlock()
+
+			mu.Lock()
+			running++
+			if running > maxSeen {
+				maxSeen = running
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if maxSeen != 1 {
+		t.Errorf("Expected at most 1 holder at a time, saw %d", maxSeen)
+	}
+	if len(locker.locks) != 0 {
+		t.Errorf("Expected all locks to be released, got %d", len(locker.locks))
+	}
+}
+
+func TestLocker_IndependentSessions(t *testing.T) {
+	locker := NewLocker()
+	ctx := context.Background()
+	failFast := session.LockOptions{Mode: session.LockFailFast}
+
+	unlock1, err := locker.Lock(ctx, "s1", failFast)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer unlock1()
+
+	unlock2, err := locker.Lock(ctx, "s2", failFast)
+	if err != nil {
+		t.Fatalf("Expected other session to be lockable, got %v", err)
+	}
+	unlock2()
+}
+
+func TestLocker_FailFast(t *testing.T) {
+	locker := NewLocker()
+	ctx := context.Background()
+	opts := session.LockOptions{Mode: session.LockFailFast}
+
+	unlock, err := locker.Lock(ctx, "s1", opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := locker.Lock(ctx, "s1", opts); !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Expected ErrSessionLocked, got %v", err)
+	}
+
+	// Unlocking twice must not release someone else's lock
+	unlock()
+	unlock()
+
+	unlock, err = locker.Lock(ctx, "s1", opts)
+	if err != nil {
+		t.Fatalf("Expected lock to be free after unlock, got %v", err)
+	}
+	if _, err := locker.Lock(ctx, "s1", opts); !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Expected ErrSessionLocked after double unlock, got %v", err)
+	}
+	unlock()
+}
+
+func TestLocker_WaitTimeout(t *testing.T) {
+	locker := NewLocker()
+	ctx := context.Background()
+
+	unlock, _ := locker.Lock(ctx, "s1", session.LockOptions{})
+	defer unlock()
+
+	start := time.Now()
+	_, err := locker.Lock(ctx, "s1", session.LockOptions{Mode: session.LockWait, Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Expected ErrSessionLocked after timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected to wait for the timeout, returned after %v", elapsed)
+	}
+
+	// The abandoned waiter must not hold up later callers
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := locker.Lock(cancelled, "s1", session.LockOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		unlock, err := locker.Lock(ctx, "s1", session.LockOptions{})
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			return
+		}
+		unlock()
+	}()
+
+	unlock()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected waiter to acquire the released lock")
+	}
+}
+
+func TestLocker_QueueOrderAndLimit(t *testing.T) {
+	locker := NewLocker()
+	ctx := context.Background()
+	opts := session.LockOptions{Mode: session.LockQueue, MaxQueue: 2}
+
+	unlock, err := locker.Lock(ctx, "s1", opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			unlock, err := locker.Lock(ctx, "s1", opts)
+			if err != nil {
+				t.Errorf("Expected queued caller %d to get the lock, got %v", i, err)
+				return
+			}
+			order <- i
+			unlock()
+		}(i)
+
+		// Wait until the caller is queued so arrival order is deterministic
+		for queued := 0; queued != i+1; {
+			time.Sleep(time.Millisecond)
+			locker.mu.Lock()
+			queued = len(locker.locks["s1"].waiters)
+			locker.mu.Unlock()
+		}
+	}
+
+	if _, err := locker.Lock(ctx, "s1", opts); !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Expected ErrSessionLocked with a full queue, got %v", err)
+	}
+
+	unlock()
+	for want := 0; want < 2; want++ {
+		if got := <-order; got != want {
+			t.Errorf("Expected caller %d to get the lock next, got %d", want, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// ListSessions returns sessions matching opts, newest first
+func (s *Store) ListSessions(ctx context.Context, opts ...session.ListOption) (session.SessionPage, error) {
+	options := session.ApplyListOptions(opts...)
+
+	var after *listCursor
+	if options.Cursor != "" {
+		cursor, err := decodeListCursor(options.Cursor)
+		if err != nil {
+			return session.SessionPage{}, err
+		}
+		after = &cursor
+	}
+
+	var infos []session.SessionInfo
+	s.sessions.Range(func(key, value any) bool {
+		sess := value.(*memorySession)
+		if sess.IsExpired() {
+			return true
+		}
+
+		info := sess.info()
+		if options.Matches(info) {
+			infos = append(infos, info)
+		}
+		return true
+	})
+
+	// Newest first, with the ID as tie-breaker so the order is total
+	sort.Slice(infos, func(i, j int) bool {
+		return listCursorOf(infos[i]).before(listCursorOf(infos[j]))
+	})
+
+	// Skip everything up to and including the cursor position
+	start := 0
+	if after != nil {
+		start = sort.Search(len(infos), func(i int) bool {
+			return after.before(listCursorOf(infos[i]))
+		})
+	}
+	infos = infos[start:]
+
+	page := session.SessionPage{Sessions: infos}
+	if len(infos) > options.Limit {
+		page.Sessions = infos[:options.Limit]
+		page.NextCursor = listCursorOf(page.Sessions[options.Limit-1]).encode()
+	}
+
+	if page.Sessions == nil {
+		page.Sessions = []session.SessionInfo{}
+	}
+
+	return page, nil
+}
+
+// QueryHistory returns a page of a session's history, newest first
+func (s *Store) QueryHistory(ctx context.Context, sessionID string, opts ...session.HistoryOption) (session.HistoryPage, error) {
+	sess, err := s.Get(ctx, sessionID)
+	if err != nil {
+		return session.HistoryPage{}, err
+	}
+
+	return session.PageHistory(sess.GetHistory(0), session.ApplyHistoryOptions(opts...))
+}
+
+// info returns a summary of the session
+func (s *memorySession) info() session.SessionInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metadata := make(map[string]string, len(s.metadata))
+	for k, v := range s.metadata {
+		metadata[k] = v
+	}
+
+	info := session.SessionInfo{
+		ID:        s.id,
+		CreatedAt: s.createdAt,
+		UpdatedAt: s.updatedAt,
+		Metadata:  metadata,
+	}
+
+	if s.expiresAt != nil {
+		expiresAt := *s.expiresAt
+		info.ExpiresAt = &expiresAt
+	}
+
+	return info
+}
+
+// listCursor identifies a position in the newest-first session ordering
+type listCursor struct {
+	createdAt int64
+	id        string
+}
+
+func listCursorOf(info session.SessionInfo) listCursor {
+	return listCursor{createdAt: info.CreatedAt.UnixNano(), id: info.ID}
+}
+
+// before reports whether c sorts ahead of other in the listing
+func (c listCursor) before(other listCursor) bool {
+	if c.createdAt != other.createdAt {
+		return c.createdAt > other.createdAt
+	}
+	return c.id > other.id
+}
+
+// encode returns the opaque cursor string handed out to callers
+func (c listCursor) encode() string {
+	raw := strconv.FormatInt(c.createdAt, 10) + ":" + c.id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeListCursor(cursor string) (listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return listCursor{}, session.ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return listCursor{}, session.ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil || nanos < 0 {
+		return listCursor{}, session.ErrInvalidCursor
+	}
+
+	return listCursor{createdAt: nanos, id: id}, nil
+}
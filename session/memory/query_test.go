@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+func TestStore_ListSessions(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		store.Create(ctx,
+			session.WithID(fmt.Sprintf("session-%d", i)),
+			session.WithMetadata("user_id", fmt.Sprintf("user-%d", i%2)))
+		time.Sleep(time.Millisecond) // Ensure different creation times
+	}
+	store.Create(ctx, session.WithID("expired"), session.WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	page, err := store.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Sessions) != 5 {
+		t.Fatalf("Expected 5 live sessions, got %d", len(page.Sessions))
+	}
+	if page.Sessions[0].ID != "session-4" || page.Sessions[4].ID != "session-0" {
+		t.Errorf("Expected newest first, got %s ... %s", page.Sessions[0].ID, page.Sessions[4].ID)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no cursor on last page, got %q", page.NextCursor)
+	}
+
+	// Metadata filter
+	page, err = store.ListSessions(ctx, session.WithMetadataFilter("user_id", "user-0"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Sessions) != 3 {
+		t.Errorf("Expected 3 sessions for user-0, got %d", len(page.Sessions))
+	}
+	for _, info := range page.Sessions {
+		if info.Metadata["user_id"] != "user-0" {
+			t.Errorf("Expected only user-0 sessions, got %v", info.Metadata)
+		}
+	}
+}
+
+func TestStore_ListSessions_Pagination(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		store.Create(ctx, session.WithID(fmt.Sprintf("session-%d", i)))
+	}
+
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination did not terminate")
+		}
+
+		page, err := store.ListSessions(ctx, session.WithListLimit(2), session.WithCursor(cursor))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, info := range page.Sessions {
+			seen = append(seen, info.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(seen) != 5 {
+		t.Fatalf("Expected to page through 5 sessions, got %v", seen)
+	}
+	unique := make(map[string]bool)
+	for _, id := range seen {
+		unique[id] = true
+	}
+	if len(unique) != 5 {
+		t.Errorf("Expected no duplicates across pages, got %v", seen)
+	}
+
+	_, err := store.ListSessions(ctx, session.WithCursor("not a cursor!"))
+	if !errors.Is(err, session.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestStore_ListSessions_TimeRange(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+	ctx := context.Background()
+
+	store.Create(ctx, session.WithID("old"))
+	time.Sleep(5 * time.Millisecond)
+	from := time.Now()
+	store.Create(ctx, session.WithID("middle"))
+	time.Sleep(5 * time.Millisecond)
+	to := time.Now()
+	store.Create(ctx, session.WithID("new"))
+
+	page, err := store.ListSessions(ctx, session.WithCreatedBetween(from, to))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "middle" {
+		t.Errorf("Expected only the middle session, got %v", page.Sessions)
+	}
+
+	page, _ = store.ListSessions(ctx, session.WithCreatedBetween(from, time.Time{}))
+	if len(page.Sessions) != 2 {
+		t.Errorf("Expected 2 sessions with open upper bound, got %d", len(page.Sessions))
+	}
+}
+
+func TestStore_QueryHistory(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+	ctx := context.Background()
+
+	sess := store.Create(ctx, session.WithID("history"))
+	base := time.Now()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		entry := session.NewMessageEntry("user", fmt.Sprintf("message %d", i))
+		entry.Timestamp = base.Add(time.Duration(i) * time.Second)
+		sess.AddEntry(entry)
+		ids = append(ids, entry.ID)
+	}
+
+	// Offset paging
+	page, err := store.QueryHistory(ctx, "history", session.WithHistoryLimit(2), session.WithHistoryOffset(1))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Entries) != 2 || page.Entries[0].ID != ids[3] || page.Entries[1].ID != ids[2] {
+		t.Errorf("Unexpected offset page: %v", page.Entries)
+	}
+	if !page.HasMore {
+		t.Error("Expected more entries after offset page")
+	}
+
+	// Before-ID paging
+	page, err = store.QueryHistory(ctx, "history", session.WithBeforeID(ids[2]))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Entries) != 2 || page.Entries[0].ID != ids[1] || page.Entries[1].ID != ids[0] {
+		t.Errorf("Unexpected before-ID page: %v", page.Entries)
+	}
+	if page.HasMore {
+		t.Error("Expected no more entries after the oldest one")
+	}
+
+	// Offset past the end
+	page, err = store.QueryHistory(ctx, "history", session.WithHistoryOffset(10))
+	if err != nil || len(page.Entries) != 0 {
+		t.Errorf("Expected empty page, got %v, %v", page.Entries, err)
+	}
+
+	// Unknown entry and session
+	_, err = store.QueryHistory(ctx, "history", session.WithBeforeID("missing"))
+	if !errors.Is(err, session.ErrEntryNotFound) {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+	_, err = store.QueryHistory(ctx, "missing")
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestStore_ImplementsSessionQuerier(t *testing.T) {
+	var store session.SessionStore = NewStore()
+	defer store.Close()
+
+	if _, ok := store.(session.SessionQuerier); !ok {
+		t.Error("Expected memory.Store to implement session.SessionQuerier")
+	}
+}
@@ -14,6 +14,9 @@ type memorySession struct {
 	createdAt time.Time
 	updatedAt time.Time
 	expiresAt *time.Time
+	ttl       time.Duration
+	sliding   bool
+	version   int64
 	state     map[string]any
 	history   []session.Entry
 	metadata  map[string]string
@@ -39,6 +42,13 @@ func (s *memorySession) UpdatedAt() time.Time {
 	return s.updatedAt
 }
 
+// Version returns the number of times the session has been saved
+func (s *memorySession) Version() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
 // Get retrieves a value from the session state
 func (s *memorySession) Get(key string) (any, bool) {
 	s.mu.RLock()
@@ -63,6 +73,82 @@ func (s *memorySession) Delete(key string) {
 	s.updatedAt = time.Now()
 }
 
+// State returns a shallow copy of all state values
+func (s *memorySession) State() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := make(map[string]any, len(s.state))
+	for key, value := range s.state {
+		state[key] = value
+	}
+	return state
+}
+
+// Metadata returns a copy of the session metadata
+func (s *memorySession) Metadata() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metadata := make(map[string]string, len(s.metadata))
+	for key, value := range s.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// GetMetadata retrieves a metadata value
+func (s *memorySession) GetMetadata(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.metadata[key]
+	return value, exists
+}
+
+// SetMetadata stores a metadata value
+func (s *memorySession) SetMetadata(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[key] = value
+	s.updatedAt = time.Now()
+}
+
+// ExpiresAt returns when the session expires, or nil if it never does
+func (s *memorySession) ExpiresAt() *time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.expiresAt == nil {
+		return nil
+	}
+	expiresAt := *s.expiresAt
+	return &expiresAt
+}
+
+// Touch restarts the session TTL from now
+func (s *memorySession) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(s.ttl)
+	s.expiresAt = &expiresAt
+}
+
+// Extend pushes the session expiry back by d
+func (s *memorySession) Extend(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expiresAt == nil {
+		return
+	}
+	expiresAt := s.expiresAt.Add(d)
+	s.expiresAt = &expiresAt
+}
+
 // AddEntry adds an entry to the session history
 func (s *memorySession) AddEntry(entry session.Entry) error {
 	s.mu.Lock()
@@ -77,12 +163,15 @@ func (s *memorySession) GetHistory(limit int) []session.Entry {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Create a copy of the history slice
+	// Copy the history newest-added first, so entries of the same time
+	// keep reverse insertion order
 	history := make([]session.Entry, len(s.history))
-	copy(history, s.history)
+	for i, entry := range s.history {
+		history[len(s.history)-1-i] = entry
+	}
 
 	// Sort by timestamp (newest first)
-	sort.Slice(history, func(i, j int) bool {
+	sort.SliceStable(history, func(i, j int) bool {
 		return history[i].Timestamp.After(history[j].Timestamp)
 	})
 
@@ -11,8 +11,9 @@ import (
 
 // Store is an in-memory implementation of SessionStore
 type Store struct {
-	sessions sync.Map // map[string]*memorySession
-	done     chan struct{}
+	sessions  sync.Map // map[string]*memorySession
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewStore creates a new in-memory session store
@@ -28,8 +29,13 @@ func NewStore() *Store {
 
 // Create creates a new session with the given options
 func (s *Store) Create(ctx context.Context, opts ...session.CreateOption) session.Session {
-	options := session.ApplyOptions(opts...)
+	sess := newSession(session.ApplyOptions(opts...))
+	s.sessions.Store(sess.id, sess)
+	return sess
+}
 
+// newSession builds an empty session from resolved create options
+func newSession(options session.CreateOptions) *memorySession {
 	id := options.ID
 	if id == "" {
 		id = uuid.New().String()
@@ -49,12 +55,57 @@ func (s *Store) Create(ctx context.Context, opts ...session.CreateOption) sessio
 	if options.TTL > 0 {
 		expiresAt := now.Add(options.TTL)
 		sess.expiresAt = &expiresAt
+		sess.ttl = options.TTL
+		sess.sliding = options.SlidingTTL
 	}
 
-	s.sessions.Store(id, sess)
 	return sess
 }
 
+// Fork creates a new session branched from sourceID at atEntryID.
+// See session.SessionForker for the semantics.
+func (s *Store) Fork(ctx context.Context, sourceID, atEntryID string, opts ...session.CreateOption) (session.Session, error) {
+	value, err := s.Get(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source := value.(*memorySession)
+
+	source.mu.RLock()
+	state := make(map[string]any, len(source.state))
+	for key, value := range source.state {
+		state[key] = value
+	}
+
+	// History is kept in insertion order, so the fork point is a prefix
+	end := len(source.history)
+	if atEntryID != "" {
+		end = -1
+		for i, entry := range source.history {
+			if entry.ID == atEntryID {
+				end = i + 1
+				break
+			}
+		}
+	}
+	if end < 0 {
+		source.mu.RUnlock()
+		return nil, session.ErrEntryNotFound
+	}
+	history := make([]session.Entry, end)
+	copy(history, source.history[:end])
+
+	forkOpts := session.ForkOptions(sourceID, atEntryID, source.metadata, opts...)
+	source.mu.RUnlock()
+
+	forked := newSession(session.ApplyOptions(forkOpts...))
+	forked.state = state
+	forked.history = history
+
+	s.sessions.Store(forked.id, forked)
+	return forked, nil
+}
+
 // Get retrieves a session by ID
 func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
 	value, ok := s.sessions.Load(id)
@@ -70,13 +121,34 @@ func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
 		return nil, session.ErrSessionNotFound
 	}
 
+	if sess.sliding {
+		sess.Touch()
+	}
+
 	return sess, nil
 }
 
-// Save saves a session (no-op for memory store as changes are immediate)
+// Save saves a session. Changes to memory sessions are immediate, so Save
+// only checks that sess is still the stored session: saving a session that
+// was deleted and recreated in the meantime returns ErrVersionConflict, and
+// saving one that was deleted returns ErrSessionNotFound.
 func (s *Store) Save(ctx context.Context, sess session.Session) error {
-	// For memory store, sessions are saved immediately when modified
-	// This method exists to satisfy the interface
+	ms, ok := sess.(*memorySession)
+	if !ok {
+		return nil
+	}
+
+	stored, exists := s.sessions.Load(ms.id)
+	if !exists {
+		return session.ErrSessionNotFound
+	}
+	if stored != ms {
+		return session.ErrVersionConflict
+	}
+
+	ms.mu.Lock()
+	ms.version++
+	ms.mu.Unlock()
 	return nil
 }
 
@@ -124,8 +196,11 @@ func (s *Store) cleanupExpired() {
 	}
 }
 
-// Close stops the background cleanup routine and releases resources
+// Close stops the background cleanup routine and releases resources.
+// It is safe to call Close more than once.
 func (s *Store) Close() error {
-	close(s.done)
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
 	return nil
 }
@@ -9,8 +9,17 @@ import (
 	"time"
 
 	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/sessiontest"
 )
 
+func TestConformance(t *testing.T) {
+	sessiontest.Run(t, sessiontest.Harness{
+		NewStore: func(t *testing.T) session.SessionStore {
+			return NewStore()
+		},
+	})
+}
+
 func TestStore_Create(t *testing.T) {
 	store := NewStore()
 
@@ -363,3 +372,33 @@ func TestConcurrency(t *testing.T) {
 		t.Errorf("Expected %d history entries, got %d", expectedHistoryLength, len(history))
 	}
 }
+
+func TestStore_ForkRecordsParent(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+
+	source := store.Create(context.Background(), session.WithID("parent"), session.WithMetadata("user_id", "123"))
+	entry := session.NewMessageEntry("user", "Hello")
+	source.AddEntry(entry)
+
+	forked, err := store.Fork(context.Background(), "parent", entry.ID, session.WithMetadata("branch", "b"))
+	if err != nil {
+		t.Fatalf("Expected no error from Fork, got %v", err)
+	}
+
+	metadata := forked.(*memorySession).metadata
+	if metadata[session.MetadataParentSessionID] != "parent" {
+		t.Errorf("Expected parent link, got %v", metadata)
+	}
+	if metadata[session.MetadataForkedAtEntryID] != entry.ID {
+		t.Errorf("Expected fork entry %s, got %v", entry.ID, metadata)
+	}
+	if metadata["user_id"] != "123" || metadata["branch"] != "b" {
+		t.Errorf("Expected source and option metadata to be kept, got %v", metadata)
+	}
+
+	// The source metadata map must not be shared with the fork
+	if _, ok := source.(*memorySession).metadata[session.MetadataParentSessionID]; ok {
+		t.Error("Expected source metadata to be unchanged")
+	}
+}
@@ -7,6 +7,9 @@ type CreateOptions struct {
 	ID       string
 	TTL      time.Duration
 	Metadata map[string]string
+
+	// SlidingTTL restarts the TTL every time the session is loaded
+	SlidingTTL bool
 }
 
 // CreateOption is a function that configures CreateOptions
@@ -26,6 +29,15 @@ func WithTTL(ttl time.Duration) CreateOption {
 	}
 }
 
+// WithSlidingTTL sets a time-to-live that is refreshed on every access,
+// so the session only expires after ttl of inactivity
+func WithSlidingTTL(ttl time.Duration) CreateOption {
+	return func(opts *CreateOptions) {
+		opts.TTL = ttl
+		opts.SlidingTTL = true
+	}
+}
+
 // WithMetadata adds metadata to the session
 func WithMetadata(key, value string) CreateOption {
 	return func(opts *CreateOptions) {
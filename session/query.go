@@ -0,0 +1,221 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Query errors
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrEntryNotFound = errors.New("entry not found")
+)
+
+// DefaultListLimit is the page size used when ListOptions.Limit is not set
+const DefaultListLimit = 50
+
+// SessionQuerier is an optional interface for stores that can list sessions
+// and page through history. Check for it with a type assertion:
+//
+//	if querier, ok := store.(session.SessionQuerier); ok { ... }
+type SessionQuerier interface {
+	// ListSessions returns sessions matching opts, newest first
+	ListSessions(ctx context.Context, opts ...ListOption) (SessionPage, error)
+
+	// QueryHistory returns a page of a session's history, newest first
+	QueryHistory(ctx context.Context, sessionID string, opts ...HistoryOption) (HistoryPage, error)
+}
+
+// SessionInfo summarizes a session without loading its state or history
+type SessionInfo struct {
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// SessionPage is one page of ListSessions results
+type SessionPage struct {
+	Sessions []SessionInfo `json:"sessions"`
+
+	// NextCursor continues the listing; it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// HistoryPage is one page of QueryHistory results
+type HistoryPage struct {
+	Entries []Entry `json:"entries"`
+
+	// HasMore reports whether older entries exist beyond this page
+	HasMore bool `json:"has_more"`
+}
+
+// ListOptions holds options for listing sessions
+type ListOptions struct {
+	// Metadata filters sessions whose metadata contains every key/value pair
+	Metadata map[string]string
+
+	// CreatedAfter and CreatedBefore bound the creation time (zero = unbounded)
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// Limit is the maximum number of sessions per page
+	Limit int
+
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+}
+
+// ListOption is a function that configures ListOptions
+type ListOption func(*ListOptions)
+
+// WithMetadataFilter only lists sessions whose metadata has key set to value
+func WithMetadataFilter(key, value string) ListOption {
+	return func(opts *ListOptions) {
+		if opts.Metadata == nil {
+			opts.Metadata = make(map[string]string)
+		}
+		opts.Metadata[key] = value
+	}
+}
+
+// WithCreatedBetween only lists sessions created within [from, to).
+// A zero time leaves that side of the range open.
+func WithCreatedBetween(from, to time.Time) ListOption {
+	return func(opts *ListOptions) {
+		opts.CreatedAfter = from
+		opts.CreatedBefore = to
+	}
+}
+
+// WithListLimit sets the page size
+func WithListLimit(limit int) ListOption {
+	return func(opts *ListOptions) {
+		opts.Limit = limit
+	}
+}
+
+// WithCursor continues a listing from a previous page
+func WithCursor(cursor string) ListOption {
+	return func(opts *ListOptions) {
+		opts.Cursor = cursor
+	}
+}
+
+// ApplyListOptions applies the given options to ListOptions
+func ApplyListOptions(opts ...ListOption) ListOptions {
+	options := ListOptions{
+		Metadata: make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Limit <= 0 {
+		options.Limit = DefaultListLimit
+	}
+
+	return options
+}
+
+// Matches reports whether a session passes the metadata and time filters
+func (o ListOptions) Matches(info SessionInfo) bool {
+	for key, value := range o.Metadata {
+		if info.Metadata[key] != value {
+			return false
+		}
+	}
+
+	if !o.CreatedAfter.IsZero() && info.CreatedAt.Before(o.CreatedAfter) {
+		return false
+	}
+
+	if !o.CreatedBefore.IsZero() && !info.CreatedAt.Before(o.CreatedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// HistoryOptions holds options for paging through history
+type HistoryOptions struct {
+	// Limit is the maximum number of entries per page (0 = no limit)
+	Limit int
+
+	// Offset skips this many of the newest matching entries
+	Offset int
+
+	// BeforeID only returns entries older than the entry with this ID
+	BeforeID string
+}
+
+// HistoryOption is a function that configures HistoryOptions
+type HistoryOption func(*HistoryOptions)
+
+// WithHistoryLimit sets the maximum number of entries per page
+func WithHistoryLimit(limit int) HistoryOption {
+	return func(opts *HistoryOptions) {
+		opts.Limit = limit
+	}
+}
+
+// WithHistoryOffset skips the given number of newest entries
+func WithHistoryOffset(offset int) HistoryOption {
+	return func(opts *HistoryOptions) {
+		opts.Offset = offset
+	}
+}
+
+// WithBeforeID only returns entries older than the given entry
+func WithBeforeID(id string) HistoryOption {
+	return func(opts *HistoryOptions) {
+		opts.BeforeID = id
+	}
+}
+
+// ApplyHistoryOptions applies the given options to HistoryOptions
+func ApplyHistoryOptions(opts ...HistoryOption) HistoryOptions {
+	var options HistoryOptions
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// PageHistory applies history options to entries sorted newest first.
+// Stores without native paging can use it on the result of GetHistory(0).
+func PageHistory(entries []Entry, opts HistoryOptions) (HistoryPage, error) {
+	if opts.BeforeID != "" {
+		index := -1
+		for i, entry := range entries {
+			if entry.ID == opts.BeforeID {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return HistoryPage{}, ErrEntryNotFound
+		}
+		entries = entries[index+1:]
+	}
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(entries) {
+			return HistoryPage{Entries: []Entry{}}, nil
+		}
+		entries = entries[opts.Offset:]
+	}
+
+	page := HistoryPage{Entries: entries}
+	if opts.Limit > 0 && opts.Limit < len(entries) {
+		page.Entries = entries[:opts.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
@@ -0,0 +1,254 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// redisSession is a Redis-backed implementation of Session.
+// State is cached in memory and written through to a hash on every change,
+// while history is always read from the Redis list.
+type redisSession struct {
+	store     *Store
+	id        string
+	createdAt time.Time
+	updatedAt time.Time
+	expiresAt *time.Time
+	ttl       time.Duration
+	sliding   bool
+	version   int64
+	stored    bool // the session hash was written at least once
+	state     map[string]any
+	metadata  map[string]string
+	mu        sync.RWMutex
+}
+
+// ID returns the session ID
+func (s *redisSession) ID() string {
+	return s.id
+}
+
+// CreatedAt returns when the session was created
+func (s *redisSession) CreatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.createdAt
+}
+
+// UpdatedAt returns when the session was last updated
+func (s *redisSession) UpdatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+// Version returns the stored save count this session is based on
+func (s *redisSession) Version() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Get retrieves a value from the session state
+func (s *redisSession) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.state[key]
+	return value, exists
+}
+
+// Set stores a value in the session state.
+// The value is written through to Redis; if that write fails the
+// in-memory value is kept and persisted by the next Save.
+func (s *redisSession) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[key] = value
+	s.updatedAt = time.Now()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	s.write(context.Background(), func(pipe goredis.Pipeliner) {
+		pipe.HSet(context.Background(), s.store.stateKey(s.id), key, data)
+	})
+}
+
+// Delete removes a value from the session state
+func (s *redisSession) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state, key)
+	s.updatedAt = time.Now()
+
+	s.write(context.Background(), func(pipe goredis.Pipeliner) {
+		pipe.HDel(context.Background(), s.store.stateKey(s.id), key)
+	})
+}
+
+// State returns a shallow copy of all state values
+func (s *redisSession) State() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := make(map[string]any, len(s.state))
+	for key, value := range s.state {
+		state[key] = value
+	}
+	return state
+}
+
+// Metadata returns a copy of the session metadata
+func (s *redisSession) Metadata() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metadata := make(map[string]string, len(s.metadata))
+	for key, value := range s.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// GetMetadata retrieves a metadata value
+func (s *redisSession) GetMetadata(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.metadata[key]
+	return value, exists
+}
+
+// SetMetadata stores a metadata value.
+// Like Set, the change is written through and reconciled by Save.
+func (s *redisSession) SetMetadata(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[key] = value
+	s.updatedAt = time.Now()
+
+	data, err := json.Marshal(s.metadata)
+	if err != nil {
+		return
+	}
+
+	s.write(context.Background(), func(pipe goredis.Pipeliner) {
+		pipe.HSet(context.Background(), s.store.sessionKey(s.id), fieldMetadata, data)
+	})
+}
+
+// ExpiresAt returns when the session expires, or nil if it never does
+func (s *redisSession) ExpiresAt() *time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.expiresAt == nil {
+		return nil
+	}
+	expiresAt := *s.expiresAt
+	return &expiresAt
+}
+
+// Touch restarts the session TTL from now
+func (s *redisSession) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(s.ttl)
+	s.setExpiresAt(expiresAt)
+}
+
+// Extend pushes the session expiry back by d
+func (s *redisSession) Extend(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expiresAt == nil {
+		return
+	}
+	s.setExpiresAt(s.expiresAt.Add(d))
+}
+
+// setExpiresAt records a new expiry and applies it to the Redis keys.
+// The caller must hold the write lock.
+func (s *redisSession) setExpiresAt(expiresAt time.Time) {
+	s.expiresAt = &expiresAt
+
+	s.write(context.Background(), func(pipe goredis.Pipeliner) {
+		pipe.HSet(context.Background(), s.store.sessionKey(s.id), fieldExpiresAt, expiresAt.UnixNano())
+	})
+}
+
+// AddEntry appends an entry to the session history
+func (s *redisSession) AddEntry(entry session.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updatedAt = time.Now()
+
+	err = s.write(context.Background(), func(pipe goredis.Pipeliner) {
+		pipe.RPush(context.Background(), s.store.historyKey(s.id), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetHistory returns the session history, sorted by timestamp (newest first)
+func (s *redisSession) GetHistory(limit int) []session.Entry {
+	// Entries may be added with any timestamp, so the whole list is sorted
+	values, err := s.store.client.LRange(context.Background(), s.store.historyKey(s.id), 0, -1).Result()
+	if err != nil {
+		return []session.Entry{}
+	}
+
+	// Newest first, with entries of the same time in reverse insertion order
+	history := make([]session.Entry, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		var entry session.Entry
+		if err := json.Unmarshal([]byte(values[i]), &entry); err != nil {
+			continue
+		}
+		history = append(history, entry)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.After(history[j].Timestamp)
+	})
+
+	// Apply limit if specified
+	if limit > 0 && limit < len(history) {
+		history = history[:limit]
+	}
+
+	return history
+}
+
+// write runs fn in a transaction together with the bookkeeping every change
+// needs: bumping updated_at and re-applying the session expiry to all keys.
+// The caller must hold the write lock.
+func (s *redisSession) write(ctx context.Context, fn func(pipe goredis.Pipeliner)) error {
+	_, err := s.store.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		fn(pipe)
+		pipe.HSet(ctx, s.store.sessionKey(s.id), fieldUpdatedAt, s.updatedAt.UnixNano())
+		s.store.expire(ctx, pipe, s.id, s.expiresAt)
+		return nil
+	})
+	return err
+}
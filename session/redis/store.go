@@ -0,0 +1,457 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Hash fields of the session key
+const (
+	fieldCreatedAt = "created_at"
+	fieldUpdatedAt = "updated_at"
+	fieldExpiresAt = "expires_at"
+	fieldMetadata  = "metadata"
+	fieldTTL       = "ttl"
+	fieldSliding   = "sliding"
+	fieldVersion   = "version"
+)
+
+// maxSaveAttempts bounds the retries of a Save whose watched key was
+// modified by a concurrent write
+const maxSaveAttempts = 5
+
+// DefaultKeyPrefix is prepended to every key written by the store
+const DefaultKeyPrefix = "go-agent:session:"
+
+// Store is a Redis implementation of SessionStore.
+//
+// Each session is stored under three keys sharing the same expiry:
+//   - {prefix}{id}          hash with timestamps and metadata
+//   - {prefix}{id}:state    hash of JSON-encoded state values
+//   - {prefix}{id}:history  list of JSON-encoded entries, oldest first
+//
+// Session TTL is mapped to Redis key expiry, so there is no cleanup routine
+// and DeleteExpired is a no-op.
+type Store struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithKeyPrefix sets the prefix used for all session keys
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// NewStore creates a session store on top of an existing Redis client.
+// The caller keeps ownership of client; Close will not close it.
+func NewStore(client goredis.UniversalClient, opts ...Option) *Store {
+	store := &Store{
+		client: client,
+		prefix: DefaultKeyPrefix,
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	return store
+}
+
+// Create creates a new session with the given options, replacing any
+// session stored under the same ID along with its state and history.
+// If the session cannot be written, the error is logged and the session is
+// still returned; a later Save will retry persisting it.
+func (s *Store) Create(ctx context.Context, opts ...session.CreateOption) session.Session {
+	sess := s.newSession(session.ApplyOptions(opts...))
+
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, s.sessionKey(sess.id), s.stateKey(sess.id), s.historyKey(sess.id))
+		return s.writeSession(ctx, pipe, sess)
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to create session", slog.String("session_id", sess.id), slog.Any("error", err))
+		return sess
+	}
+
+	sess.stored = true
+	return sess
+}
+
+// newSession builds an empty, unsaved session from resolved create options
+func (s *Store) newSession(options session.CreateOptions) *redisSession {
+	id := options.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	now := time.Now()
+	sess := &redisSession{
+		store:     s,
+		id:        id,
+		createdAt: now,
+		updatedAt: now,
+		state:     make(map[string]any),
+		metadata:  options.Metadata,
+	}
+
+	if options.TTL > 0 {
+		expiresAt := now.Add(options.TTL)
+		sess.expiresAt = &expiresAt
+		sess.ttl = options.TTL
+		sess.sliding = options.SlidingTTL
+	}
+
+	return sess
+}
+
+// Fork creates a new session branched from sourceID at atEntryID.
+// See session.SessionForker for the semantics.
+func (s *Store) Fork(ctx context.Context, sourceID, atEntryID string, opts ...session.CreateOption) (session.Session, error) {
+	value, err := s.Get(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source := value.(*redisSession)
+
+	entries, err := s.client.LRange(ctx, s.historyKey(sourceID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session history: %w", err)
+	}
+
+	// History is kept in insertion order, so the fork point is a prefix
+	if atEntryID != "" {
+		end := -1
+		for i, data := range entries {
+			var entry struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal([]byte(data), &entry) == nil && entry.ID == atEntryID {
+				end = i + 1
+				break
+			}
+		}
+		if end < 0 {
+			return nil, session.ErrEntryNotFound
+		}
+		entries = entries[:end]
+	}
+
+	forked := s.newSession(session.ApplyOptions(
+		session.ForkOptions(sourceID, atEntryID, source.metadata, opts...)...))
+	for key, value := range source.state {
+		forked.state[key] = value
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		if err := s.writeSession(ctx, pipe, forked); err != nil {
+			return err
+		}
+
+		if len(forked.state) > 0 {
+			values := make(map[string]any, len(forked.state))
+			for key, value := range forked.state {
+				data, err := json.Marshal(value)
+				if err != nil {
+					return fmt.Errorf("failed to encode state value %q: %w", key, err)
+				}
+				values[key] = data
+			}
+			pipe.HSet(ctx, s.stateKey(forked.id), values)
+		}
+
+		if len(entries) > 0 {
+			values := make([]any, len(entries))
+			for i, data := range entries {
+				values[i] = data
+			}
+			pipe.RPush(ctx, s.historyKey(forked.id), values...)
+		}
+
+		s.expire(ctx, pipe, forked.id, forked.expiresAt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fork session: %w", err)
+	}
+
+	forked.stored = true
+	return forked, nil
+}
+
+// Get retrieves a session by ID
+func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
+	var (
+		fields *goredis.MapStringStringCmd
+		state  *goredis.MapStringStringCmd
+	)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		fields = pipe.HGetAll(ctx, s.sessionKey(id))
+		state = pipe.HGetAll(ctx, s.stateKey(id))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	// Expired keys are removed by Redis, so a missing hash covers both cases.
+	// A hash without created_at is a leftover write to a deleted session.
+	values := fields.Val()
+	if _, ok := values[fieldCreatedAt]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+
+	sess := &redisSession{
+		store:    s,
+		id:       id,
+		stored:   true,
+		state:    make(map[string]any),
+		metadata: make(map[string]string),
+	}
+
+	sess.createdAt, err = parseTime(values[fieldCreatedAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", fieldCreatedAt, err)
+	}
+
+	sess.updatedAt, err = parseTime(values[fieldUpdatedAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", fieldUpdatedAt, err)
+	}
+
+	if raw, ok := values[fieldExpiresAt]; ok {
+		expiresAt, err := parseTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", fieldExpiresAt, err)
+		}
+		sess.expiresAt = &expiresAt
+	}
+
+	if raw, ok := values[fieldTTL]; ok {
+		ttl, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", fieldTTL, err)
+		}
+		sess.ttl = time.Duration(ttl)
+	}
+	sess.sliding = values[fieldSliding] == "1"
+
+	if raw, ok := values[fieldVersion]; ok {
+		sess.version, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", fieldVersion, err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(values[fieldMetadata]), &sess.metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode session metadata: %w", err)
+	}
+
+	// Values are decoded by encoding/json, so numbers come back as float64
+	for key, data := range state.Val() {
+		var value any
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			return nil, fmt.Errorf("failed to decode state value %q: %w", key, err)
+		}
+		sess.state[key] = value
+	}
+
+	if sess.sliding {
+		sess.Touch()
+	}
+
+	return sess, nil
+}
+
+// Save persists the session hash and a full snapshot of its state.
+// Changes are already written through as they happen; Save makes sure
+// anything that failed to write earlier ends up in Redis.
+// It returns session.ErrVersionConflict if the stored session was saved
+// through another session value, or deleted and recreated, since rs was loaded,
+// and session.ErrSessionNotFound if it was deleted or has expired.
+func (s *Store) Save(ctx context.Context, sess session.Session) error {
+	rs, ok := sess.(*redisSession)
+	if !ok || rs.store != s {
+		return fmt.Errorf("session %s does not belong to this store", sess.ID())
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	// Write-throughs from other session values touch the watched hash too,
+	// so a failed transaction is retried before giving up
+	var err error
+	for attempt := 0; attempt < maxSaveAttempts; attempt++ {
+		err = s.client.Watch(ctx, func(tx *goredis.Tx) error {
+			return s.saveWatched(ctx, tx, rs)
+		}, s.sessionKey(rs.id))
+		if !errors.Is(err, goredis.TxFailedErr) {
+			break
+		}
+	}
+
+	if errors.Is(err, session.ErrVersionConflict) || errors.Is(err, session.ErrSessionNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	rs.version++
+	rs.stored = true
+	return nil
+}
+
+// saveWatched checks the stored version of rs and writes it in one transaction.
+// The caller must hold the write lock on rs.
+func (s *Store) saveWatched(ctx context.Context, tx *goredis.Tx, rs *redisSession) error {
+	values, err := tx.HMGet(ctx, s.sessionKey(rs.id), fieldCreatedAt, fieldVersion).Result()
+	if err != nil {
+		return err
+	}
+
+	// A missing hash was deleted, has expired or, if Create failed to
+	// write it, is written from scratch
+	createdAt, ok := values[0].(string)
+	if !ok && rs.stored {
+		return session.ErrSessionNotFound
+	}
+	if ok {
+		version, _ := values[1].(string)
+		if version == "" {
+			version = "0"
+		}
+		if createdAt != strconv.FormatInt(rs.createdAt.UnixNano(), 10) ||
+			version != strconv.FormatInt(rs.version, 10) {
+			return session.ErrVersionConflict
+		}
+	}
+
+	_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		if err := s.writeSession(ctx, pipe, rs); err != nil {
+			return err
+		}
+		pipe.HSet(ctx, s.sessionKey(rs.id), fieldVersion, rs.version+1)
+
+		pipe.Del(ctx, s.stateKey(rs.id))
+		if len(rs.state) > 0 {
+			values := make(map[string]any, len(rs.state))
+			for key, value := range rs.state {
+				data, err := json.Marshal(value)
+				if err != nil {
+					return fmt.Errorf("failed to encode state value %q: %w", key, err)
+				}
+				values[key] = data
+			}
+			pipe.HSet(ctx, s.stateKey(rs.id), values)
+		}
+
+		s.expire(ctx, pipe, rs.id, rs.expiresAt)
+		return nil
+	})
+	return err
+}
+
+// Delete removes a session by ID together with its state and history
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.sessionKey(id), s.stateKey(id), s.historyKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired is a no-op because Redis expires session keys itself
+func (s *Store) DeleteExpired(ctx context.Context) error {
+	return nil
+}
+
+// Close releases store resources. The Redis client is owned by the caller
+// and is left open.
+func (s *Store) Close() error {
+	return nil
+}
+
+// writeSession queues the session hash and its expiry on pipe.
+// The caller must hold at least a read lock on sess, or own it exclusively.
+func (s *Store) writeSession(ctx context.Context, pipe goredis.Pipeliner, sess *redisSession) error {
+	metadata, err := json.Marshal(sess.metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode session metadata: %w", err)
+	}
+
+	fields := map[string]any{
+		fieldCreatedAt: sess.createdAt.UnixNano(),
+		fieldUpdatedAt: sess.updatedAt.UnixNano(),
+		fieldMetadata:  metadata,
+	}
+	if sess.expiresAt != nil {
+		fields[fieldExpiresAt] = sess.expiresAt.UnixNano()
+	}
+	if sess.ttl > 0 {
+		fields[fieldTTL] = int64(sess.ttl)
+	}
+	if sess.sliding {
+		fields[fieldSliding] = 1
+	}
+	if sess.version == 0 {
+		// Later versions are only ever written by Save
+		fields[fieldVersion] = 0
+	}
+
+	pipe.HSet(ctx, s.sessionKey(sess.id), fields)
+	s.expire(ctx, pipe, sess.id, sess.expiresAt)
+	return nil
+}
+
+// expire queues the session expiry for all keys of a session.
+// Keys that do not exist yet are unaffected, which is why every write
+// re-applies the expiry after touching its key.
+func (s *Store) expire(ctx context.Context, pipe goredis.Pipeliner, id string, expiresAt *time.Time) {
+	if expiresAt == nil {
+		return
+	}
+
+	for _, key := range []string{s.sessionKey(id), s.stateKey(id), s.historyKey(id)} {
+		pipe.PExpireAt(ctx, key, *expiresAt)
+	}
+}
+
+func (s *Store) sessionKey(id string) string {
+	return s.prefix + id
+}
+
+func (s *Store) stateKey(id string) string {
+	return s.prefix + id + ":state"
+}
+
+func (s *Store) historyKey(id string) string {
+	return s.prefix + id + ":history"
+}
+
+// parseTime decodes a timestamp stored as Unix nanoseconds
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing timestamp")
+	}
+
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, nanos), nil
+}
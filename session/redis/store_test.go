@@ -0,0 +1,322 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/sessiontest"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewStore(client), mr
+}
+
+func TestConformance(t *testing.T) {
+	// Subtests run sequentially, so Advance always targets the latest server
+	var mr *miniredis.Miniredis
+	sessiontest.Run(t, sessiontest.Harness{
+		NewStore: func(t *testing.T) session.SessionStore {
+			var store *Store
+			store, mr = newTestStore(t)
+			return store
+		},
+		Advance: func(d time.Duration) {
+			mr.FastForward(d)
+		},
+	})
+}
+
+func TestStore_Create(t *testing.T) {
+	store, mr := newTestStore(t)
+
+	sess := store.Create(context.Background())
+	if sess.ID() == "" {
+		t.Error("Expected session to have an ID")
+	}
+
+	customID := "custom-session-id"
+	sess2 := store.Create(context.Background(), session.WithID(customID))
+	if sess2.ID() != customID {
+		t.Errorf("Expected session ID to be %s, got %s", customID, sess2.ID())
+	}
+
+	if !mr.Exists(DefaultKeyPrefix + customID) {
+		t.Error("Expected session hash to be written")
+	}
+}
+
+func TestStore_KeyPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := NewStore(client, WithKeyPrefix("test:"))
+	sess := store.Create(context.Background(), session.WithID("prefixed"))
+	sess.Set("key", "value")
+
+	if !mr.Exists("test:prefixed") || !mr.Exists("test:prefixed:state") {
+		t.Errorf("Expected keys to use custom prefix, got %v", mr.Keys())
+	}
+}
+
+func TestStore_GetAndSave(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	_, err := store.Get(context.Background(), "non-existent")
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	sess := store.Create(context.Background(),
+		session.WithID("test-session"),
+		session.WithMetadata("user_id", "123"))
+	sess.Set("key", "value")
+
+	if err := store.Save(context.Background(), sess); err != nil {
+		t.Errorf("Expected no error from Save, got %v", err)
+	}
+
+	retrieved, err := store.Get(context.Background(), "test-session")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, ok := retrieved.Get("key"); !ok || value != "value" {
+		t.Errorf("Expected key=value, got exists=%v, value=%v", ok, value)
+	}
+	if metadata := retrieved.(*redisSession).metadata; metadata["user_id"] != "123" {
+		t.Errorf("Expected metadata user_id=123, got %v", metadata)
+	}
+	if !retrieved.CreatedAt().Equal(sess.CreatedAt()) {
+		t.Errorf("Expected CreatedAt %v, got %v", sess.CreatedAt(), retrieved.CreatedAt())
+	}
+}
+
+func TestStore_SaveRecoversState(t *testing.T) {
+	store, mr := newTestStore(t)
+
+	sess := store.Create(context.Background(), session.WithID("recover"))
+	sess.Set("key", "value")
+
+	// Simulate a lost write-through
+	mr.Del(DefaultKeyPrefix + "recover:state")
+
+	if err := store.Save(context.Background(), sess); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	retrieved, err := store.Get(context.Background(), "recover")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, _ := retrieved.Get("key"); value != "value" {
+		t.Errorf("Expected Save to restore state, got %v", value)
+	}
+}
+
+func TestStore_SaveForeignSession(t *testing.T) {
+	store, _ := newTestStore(t)
+	other, _ := newTestStore(t)
+
+	sess := other.Create(context.Background())
+	if err := store.Save(context.Background(), sess); err == nil {
+		t.Error("Expected error when saving a session from another store")
+	}
+}
+
+func TestStore_Delete(t *testing.T) {
+	store, mr := newTestStore(t)
+
+	sess := store.Create(context.Background(), session.WithID("delete-test"))
+	sess.Set("key", "value")
+	sess.AddEntry(session.NewMessageEntry("user", "Hello"))
+
+	if err := store.Delete(context.Background(), sess.ID()); err != nil {
+		t.Errorf("Expected no error from Delete, got %v", err)
+	}
+
+	_, err := store.Get(context.Background(), sess.ID())
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after deletion, got %v", err)
+	}
+
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("Expected all session keys to be deleted, got %v", keys)
+	}
+
+	// Writes through a stale handle must not resurrect the session
+	sess.Set("late", "write")
+	_, err = store.Get(context.Background(), sess.ID())
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after late write, got %v", err)
+	}
+}
+
+func TestStore_TTLMapsToExpiry(t *testing.T) {
+	store, mr := newTestStore(t)
+
+	sess := store.Create(context.Background(), session.WithID("ttl"), session.WithTTL(time.Hour))
+	sess.Set("key", "value")
+	sess.AddEntry(session.NewMessageEntry("user", "Hello"))
+
+	for _, key := range []string{"ttl", "ttl:state", "ttl:history"} {
+		ttl := mr.TTL(DefaultKeyPrefix + key)
+		if ttl <= 0 || ttl > time.Hour {
+			t.Errorf("Expected %s to expire within an hour, got TTL %v", key, ttl)
+		}
+	}
+
+	mr.FastForward(2 * time.Hour)
+
+	_, err := store.Get(context.Background(), sess.ID())
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for expired session, got %v", err)
+	}
+
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("Expected Redis to expire all session keys, got %v", keys)
+	}
+}
+
+func TestStore_NoTTL(t *testing.T) {
+	store, mr := newTestStore(t)
+
+	store.Create(context.Background(), session.WithID("forever"))
+	if ttl := mr.TTL(DefaultKeyPrefix + "forever"); ttl != 0 {
+		t.Errorf("Expected no expiry, got %v", ttl)
+	}
+
+	if err := store.DeleteExpired(context.Background()); err != nil {
+		t.Errorf("Expected no error from DeleteExpired, got %v", err)
+	}
+}
+
+func TestSession_StateManagement(t *testing.T) {
+	store, _ := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	sess.Set("key1", "value1")
+	sess.Set("key2", 42)
+
+	value1, exists1 := sess.Get("key1")
+	if !exists1 || value1 != "value1" {
+		t.Errorf("Expected key1=value1, got exists=%v, value=%v", exists1, value1)
+	}
+
+	value2, exists2 := sess.Get("key2")
+	if !exists2 || value2 != 42 {
+		t.Errorf("Expected key2=42, got exists=%v, value=%v", exists2, value2)
+	}
+
+	sess.Delete("key1")
+	if _, exists := sess.Get("key1"); exists {
+		t.Error("Expected deleted key to not exist")
+	}
+
+	// Reloaded state goes through JSON, so numbers come back as float64
+	reloaded, err := store.Get(context.Background(), sess.ID())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, exists := reloaded.Get("key1"); exists {
+		t.Error("Expected deleted key to not exist after reload")
+	}
+	if value, _ := reloaded.Get("key2"); value != float64(42) {
+		t.Errorf("Expected key2=42 after reload, got %v", value)
+	}
+}
+
+func TestSession_HistoryManagement(t *testing.T) {
+	store, _ := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	entry1 := session.NewMessageEntry("user", "Hello")
+	entry2 := session.NewToolCallEntry("search", map[string]any{"query": "test"})
+	entry3 := session.NewToolResultEntry("search", "found", nil)
+
+	for _, entry := range []session.Entry{entry1, entry2, entry3} {
+		if err := sess.AddEntry(entry); err != nil {
+			t.Errorf("Expected no error adding entry, got %v", err)
+		}
+	}
+
+	history := sess.GetHistory(0)
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(history))
+	}
+
+	// Verify newest first ordering
+	if history[0].ID != entry3.ID || history[1].ID != entry2.ID || history[2].ID != entry1.ID {
+		t.Error("Expected history to be sorted newest first")
+	}
+
+	if content, ok := session.GetToolResultContent(history[0]); !ok || content.Result != "found" {
+		t.Errorf("Expected tool result content to round-trip, got %#v", history[0].Content)
+	}
+	if content, ok := session.GetToolCallContent(history[1]); !ok || content.Parameters["query"] != "test" {
+		t.Errorf("Expected tool call content to round-trip, got %#v", history[1].Content)
+	}
+	if content, ok := session.GetMessageContent(history[2]); !ok || content.Text != "Hello" {
+		t.Errorf("Expected message content to round-trip, got %#v", history[2].Content)
+	}
+
+	limitedHistory := sess.GetHistory(2)
+	if len(limitedHistory) != 2 {
+		t.Fatalf("Expected 2 entries with limit, got %d", len(limitedHistory))
+	}
+	if limitedHistory[0].ID != entry3.ID || limitedHistory[1].ID != entry2.ID {
+		t.Error("Expected limited history to keep the newest entries")
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	store, _ := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	var wg sync.WaitGroup
+	numGoroutines := 10
+	numOperations := 20
+
+	wg.Add(numGoroutines * 2)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				key := fmt.Sprintf("key_%d_%d", id, j)
+				value := fmt.Sprintf("value_%d_%d", id, j)
+
+				sess.Set(key, value)
+				if retrieved, exists := sess.Get(key); exists && retrieved != value {
+					t.Errorf("Concurrent access issue: expected %s, got %v", value, retrieved)
+				}
+			}
+		}(i)
+
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				entry := session.NewMessageEntry("user", fmt.Sprintf("msg_%d_%d", id, j))
+				if err := sess.AddEntry(entry); err != nil {
+					t.Errorf("Error adding entry: %v", err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	history := sess.GetHistory(0)
+	if len(history) != numGoroutines*numOperations {
+		t.Errorf("Expected %d history entries, got %d", numGoroutines*numOperations, len(history))
+	}
+}
@@ -14,7 +14,27 @@ type Session interface {
 	Set(key string, value any)
 	Delete(key string)
 
+	// Metadata management
+	Metadata() map[string]string
+	GetMetadata(key string) (string, bool)
+	SetMetadata(key, value string)
+
+	// Expiry management.
+	// ExpiresAt returns nil for sessions without a TTL. Touch restarts the
+	// TTL from now and Extend pushes the current expiry back by d; both are
+	// no-ops for sessions without a TTL.
+	ExpiresAt() *time.Time
+	Touch()
+	Extend(d time.Duration)
+
 	// History management
 	AddEntry(entry Entry) error
 	GetHistory(limit int) []Entry
 }
+
+// StateReader is an optional interface for sessions that can return
+// their complete state, e.g. for exporting a session
+type StateReader interface {
+	// State returns a shallow copy of all state values
+	State() map[string]any
+}
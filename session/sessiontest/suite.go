@@ -0,0 +1,742 @@
+// Package sessiontest provides a behavioral test suite for session.SessionStore
+// implementations. A custom backend passes the suite when it behaves like
+// memory.Store from the point of view of the agent engine.
+//
+//	func TestConformance(t *testing.T) {
+//		sessiontest.Run(t, sessiontest.Harness{
+//			NewStore: func(t *testing.T) session.SessionStore {
+//				return mystore.New()
+//			},
+//		})
+//	}
+package sessiontest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// Harness describes how to construct the store under test
+type Harness struct {
+	// NewStore returns a new, empty store. It is called once per subtest,
+	// and the suite closes the store when the subtest finishes.
+	NewStore func(t *testing.T) session.SessionStore
+
+	// Advance moves the store's clock forward by d so that TTLs can elapse.
+	// Stores that expire sessions by wall-clock time can leave it nil,
+	// in which case the suite sleeps for d.
+	Advance func(d time.Duration)
+}
+
+// Run runs the full suite against the stores produced by h
+func Run(t *testing.T, h Harness) {
+	if h.NewStore == nil {
+		t.Fatal("sessiontest: Harness.NewStore is required")
+	}
+	if h.Advance == nil {
+		h.Advance = time.Sleep
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, h Harness, store session.SessionStore)
+	}{
+		{"CreateOptions", testCreateOptions},
+		{"GetMissing", testGetMissing},
+		{"GetExpired", testGetExpired},
+		{"SavePersists", testSavePersists},
+		{"CreateReplaces", testCreateReplaces},
+		{"VersionConflict", testVersionConflict},
+		{"Delete", testDelete},
+		{"SaveAfterDelete", testSaveAfterDelete},
+		{"DeleteExpired", testDeleteExpired},
+		{"StateManagement", testStateManagement},
+		{"Metadata", testMetadata},
+		{"Expiry", testExpiry},
+		{"SlidingTTL", testSlidingTTL},
+		{"HistoryOrdering", testHistoryOrdering},
+		{"HistoryTimestamps", testHistoryTimestamps},
+		{"HistoryLimit", testHistoryLimit},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"Fork", testFork},
+		{"Close", testClose},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := h.NewStore(t)
+			t.Cleanup(func() { store.Close() })
+			tt.fn(t, h, store)
+		})
+	}
+}
+
+func testCreateOptions(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	// Default options generate unique IDs
+	sess1 := store.Create(ctx)
+	sess2 := store.Create(ctx)
+	if sess1.ID() == "" {
+		t.Error("Expected session to have a generated ID")
+	}
+	if sess1.ID() == sess2.ID() {
+		t.Errorf("Expected unique session IDs, got %s twice", sess1.ID())
+	}
+
+	// Custom ID, TTL and metadata
+	before := time.Now()
+	sess := store.Create(ctx,
+		session.WithID("custom-id"),
+		session.WithTTL(time.Hour),
+		session.WithMetadata("user_id", "123"))
+	after := time.Now()
+
+	if sess.ID() != "custom-id" {
+		t.Errorf("Expected session ID custom-id, got %s", sess.ID())
+	}
+
+	createdAt := sess.CreatedAt()
+	if createdAt.Before(before) || createdAt.After(after) {
+		t.Errorf("Expected CreatedAt between %v and %v, got %v", before, after, createdAt)
+	}
+	if !sess.UpdatedAt().Equal(createdAt) {
+		t.Errorf("Expected initial UpdatedAt to equal CreatedAt, got %v", sess.UpdatedAt())
+	}
+
+	// Created sessions are retrievable without an explicit Save
+	retrieved, err := store.Get(ctx, "custom-id")
+	if err != nil {
+		t.Fatalf("Expected created session to be retrievable, got %v", err)
+	}
+	if retrieved.ID() != "custom-id" {
+		t.Errorf("Expected retrieved session ID custom-id, got %s", retrieved.ID())
+	}
+	if !retrieved.CreatedAt().Equal(createdAt) {
+		t.Errorf("Expected retrieved CreatedAt %v, got %v", createdAt, retrieved.CreatedAt())
+	}
+}
+
+func testGetMissing(t *testing.T, h Harness, store session.SessionStore) {
+	_, err := store.Get(context.Background(), "non-existent")
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func testGetExpired(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	store.Create(ctx, session.WithID("short"), session.WithTTL(50*time.Millisecond))
+	store.Create(ctx, session.WithID("long"), session.WithTTL(time.Hour))
+	store.Create(ctx, session.WithID("forever"))
+
+	h.Advance(100 * time.Millisecond)
+
+	if _, err := store.Get(ctx, "short"); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for expired session, got %v", err)
+	}
+	if _, err := store.Get(ctx, "long"); err != nil {
+		t.Errorf("Expected unexpired session to be retrievable, got %v", err)
+	}
+	if _, err := store.Get(ctx, "forever"); err != nil {
+		t.Errorf("Expected session without TTL to be retrievable, got %v", err)
+	}
+}
+
+func testSavePersists(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	sess := store.Create(ctx, session.WithID("save-test"))
+	sess.Set("task", "booking")
+	sess.Set("removed", "value")
+	sess.Delete("removed")
+	if err := sess.AddEntry(session.NewMessageEntry("user", "Hello")); err != nil {
+		t.Fatalf("Expected no error adding entry, got %v", err)
+	}
+
+	if err := store.Save(ctx, sess); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	retrieved, err := store.Get(ctx, "save-test")
+	if err != nil {
+		t.Fatalf("Expected saved session to be retrievable, got %v", err)
+	}
+
+	if value, ok := retrieved.Get("task"); !ok || value != "booking" {
+		t.Errorf("Expected task=booking, got exists=%v, value=%v", ok, value)
+	}
+	if _, ok := retrieved.Get("removed"); ok {
+		t.Error("Expected deleted key to stay deleted after Save")
+	}
+
+	history := retrieved.GetHistory(0)
+	if len(history) != 1 {
+		t.Fatalf("Expected 1 history entry, got %d", len(history))
+	}
+	if content, ok := session.GetMessageContent(history[0]); !ok || content.Text != "Hello" {
+		t.Errorf("Expected typed message content, got %#v", history[0].Content)
+	}
+}
+
+func testCreateReplaces(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	old := store.Create(ctx, session.WithID("replace-test"), session.WithMetadata("user_id", "123"))
+	old.Set("task", "booking")
+	if err := old.AddEntry(session.NewMessageEntry("user", "Hello")); err != nil {
+		t.Fatalf("Expected no error adding entry, got %v", err)
+	}
+	if err := store.Save(ctx, old); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	// Creating with an existing ID starts the session over
+	sess := store.Create(ctx, session.WithID("replace-test"))
+	sess.Set("step", "one")
+	if err := store.Save(ctx, sess); err != nil {
+		t.Fatalf("Expected the replacing session to save, got %v", err)
+	}
+
+	retrieved, err := store.Get(ctx, "replace-test")
+	if err != nil {
+		t.Fatalf("Expected replaced session to be retrievable, got %v", err)
+	}
+	if _, ok := retrieved.Get("task"); ok {
+		t.Error("Expected state of the replaced session to be gone")
+	}
+	if value, ok := retrieved.Get("step"); !ok || value != "one" {
+		t.Errorf("Expected step=one, got exists=%v, value=%v", ok, value)
+	}
+	if history := retrieved.GetHistory(0); len(history) != 0 {
+		t.Errorf("Expected history of the replaced session to be gone, got %d entries", len(history))
+	}
+	if _, ok := retrieved.Metadata()["user_id"]; ok {
+		t.Error("Expected metadata of the replaced session to be gone")
+	}
+	if !retrieved.CreatedAt().Equal(sess.CreatedAt()) {
+		t.Errorf("Expected CreatedAt %v, got %v", sess.CreatedAt(), retrieved.CreatedAt())
+	}
+}
+
+func testMetadata(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	sess := store.Create(ctx, session.WithID("metadata-test"), session.WithMetadata("user_id", "123"))
+
+	if value, ok := sess.GetMetadata("user_id"); !ok || value != "123" {
+		t.Errorf("Expected user_id=123, got exists=%v, value=%v", ok, value)
+	}
+	if _, ok := sess.GetMetadata("missing"); ok {
+		t.Error("Expected missing metadata key to not exist")
+	}
+
+	sess.SetMetadata("channel", "web")
+
+	// Metadata returns a copy
+	metadata := sess.Metadata()
+	metadata["user_id"] = "changed"
+	if value, _ := sess.GetMetadata("user_id"); value != "123" {
+		t.Errorf("Expected Metadata to return a copy, session now has user_id=%s", value)
+	}
+
+	if err := store.Save(ctx, sess); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	retrieved, err := store.Get(ctx, "metadata-test")
+	if err != nil {
+		t.Fatalf("Expected saved session to be retrievable, got %v", err)
+	}
+
+	metadata = retrieved.Metadata()
+	if len(metadata) != 2 || metadata["user_id"] != "123" || metadata["channel"] != "web" {
+		t.Errorf("Expected metadata user_id=123 and channel=web, got %v", metadata)
+	}
+}
+
+func testExpiry(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	forever := store.Create(ctx)
+	if forever.ExpiresAt() != nil {
+		t.Errorf("Expected no expiry without TTL, got %v", forever.ExpiresAt())
+	}
+	forever.Touch()
+	forever.Extend(time.Hour)
+	if forever.ExpiresAt() != nil {
+		t.Errorf("Expected Touch and Extend to keep a session without TTL unexpiring, got %v", forever.ExpiresAt())
+	}
+
+	before := time.Now()
+	sess := store.Create(ctx, session.WithID("expiry-test"), session.WithTTL(time.Hour))
+	after := time.Now()
+
+	expiresAt := sess.ExpiresAt()
+	if expiresAt == nil {
+		t.Fatal("Expected session with TTL to have an expiry")
+	}
+	if expiresAt.Before(before.Add(time.Hour)) || expiresAt.After(after.Add(time.Hour)) {
+		t.Errorf("Expected expiry about an hour from creation, got %v", *expiresAt)
+	}
+
+	sess.Extend(time.Hour)
+	extended := sess.ExpiresAt()
+	if !extended.Equal(expiresAt.Add(time.Hour)) {
+		t.Errorf("Expected Extend to push expiry to %v, got %v", expiresAt.Add(time.Hour), *extended)
+	}
+
+	// Touch restarts the TTL from now, undoing the extension
+	h.Advance(10 * time.Millisecond)
+	sess.Touch()
+	touched := sess.ExpiresAt()
+	if !touched.After(*expiresAt) || !touched.Before(*extended) {
+		t.Errorf("Expected Touch to restart the TTL, got %v", *touched)
+	}
+
+	if err := store.Save(ctx, sess); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	retrieved, err := store.Get(ctx, "expiry-test")
+	if err != nil {
+		t.Fatalf("Expected saved session to be retrievable, got %v", err)
+	}
+	if got := retrieved.ExpiresAt(); got == nil || !got.Equal(*touched) {
+		t.Errorf("Expected retrieved expiry %v, got %v", *touched, got)
+	}
+}
+
+func testSlidingTTL(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	store.Create(ctx, session.WithID("sliding"), session.WithSlidingTTL(200*time.Millisecond))
+	store.Create(ctx, session.WithID("fixed"), session.WithTTL(200*time.Millisecond))
+
+	// Each access restarts the sliding TTL, so the session outlives its TTL
+	for i := 0; i < 2; i++ {
+		h.Advance(120 * time.Millisecond)
+		if _, err := store.Get(ctx, "sliding"); err != nil {
+			t.Fatalf("Expected accessed sliding session to be retrievable, got %v", err)
+		}
+	}
+
+	if _, err := store.Get(ctx, "fixed"); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for fixed TTL session, got %v", err)
+	}
+
+	// Without access the sliding session expires as usual
+	h.Advance(250 * time.Millisecond)
+	if _, err := store.Get(ctx, "sliding"); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for idle sliding session, got %v", err)
+	}
+}
+
+func testVersionConflict(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	stale := store.Create(ctx, session.WithID("version-test"))
+	if _, ok := stale.(session.Versioned); !ok {
+		t.Skip("store does not implement optimistic versioning")
+	}
+
+	if err := store.Save(ctx, stale); err != nil {
+		t.Fatalf("Expected no error from first Save, got %v", err)
+	}
+	if version := stale.(session.Versioned).Version(); version != 1 {
+		t.Errorf("Expected version 1 after Save, got %d", version)
+	}
+
+	// Two values loaded from the same version: the first save wins
+	first, err := store.Get(ctx, "version-test")
+	if err != nil {
+		t.Fatalf("Expected session to be retrievable, got %v", err)
+	}
+	second, err := store.Get(ctx, "version-test")
+	if err != nil {
+		t.Fatalf("Expected session to be retrievable, got %v", err)
+	}
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	// Stores that hand out shared session values cannot have stale copies
+	if second.(session.Versioned).Version() != first.(session.Versioned).Version() {
+		if err := store.Save(ctx, second); !errors.Is(err, session.ErrVersionConflict) {
+			t.Errorf("Expected ErrVersionConflict saving a stale copy, got %v", err)
+		}
+	}
+
+	// A session that was deleted and recreated is a different session
+	if err := store.Delete(ctx, "version-test"); err != nil {
+		t.Fatalf("Expected no error from Delete, got %v", err)
+	}
+	recreated := store.Create(ctx, session.WithID("version-test"))
+	if err := store.Save(ctx, recreated); err != nil {
+		t.Fatalf("Expected no error saving recreated session, got %v", err)
+	}
+	if err := store.Save(ctx, stale); !errors.Is(err, session.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict saving a deleted session, got %v", err)
+	}
+}
+
+func testDelete(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	sess := store.Create(ctx, session.WithID("delete-test"))
+	sess.Set("key", "value")
+	sess.AddEntry(session.NewMessageEntry("user", "Hello"))
+
+	if err := store.Delete(ctx, sess.ID()); err != nil {
+		t.Fatalf("Expected no error from Delete, got %v", err)
+	}
+
+	if _, err := store.Get(ctx, sess.ID()); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after Delete, got %v", err)
+	}
+
+	// Deleting a missing session is not an error
+	if err := store.Delete(ctx, "non-existent"); err != nil {
+		t.Errorf("Expected no error deleting missing session, got %v", err)
+	}
+
+	// A recreated session with the same ID starts empty
+	recreated := store.Create(ctx, session.WithID("delete-test"))
+	if _, ok := recreated.Get("key"); ok {
+		t.Error("Expected recreated session to have no state")
+	}
+	if history := recreated.GetHistory(0); len(history) != 0 {
+		t.Errorf("Expected recreated session to have no history, got %d entries", len(history))
+	}
+}
+
+func testSaveAfterDelete(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	sess := store.Create(ctx, session.WithID("save-deleted"))
+	sess.Set("key", "value")
+
+	if err := store.Delete(ctx, sess.ID()); err != nil {
+		t.Fatalf("Expected no error from Delete, got %v", err)
+	}
+
+	// Saving a deleted session does not bring it back
+	if err := store.Save(ctx, sess); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound from Save, got %v", err)
+	}
+	if _, err := store.Get(ctx, sess.ID()); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after Save, got %v", err)
+	}
+}
+
+func testDeleteExpired(t *testing.T, h Harness, store session.SessionStore) {
+	ctx := context.Background()
+
+	store.Create(ctx, session.WithID("expired"), session.WithTTL(50*time.Millisecond))
+	store.Create(ctx, session.WithID("valid"), session.WithTTL(time.Hour))
+	store.Create(ctx, session.WithID("forever"))
+
+	h.Advance(100 * time.Millisecond)
+
+	if err := store.DeleteExpired(ctx); err != nil {
+		t.Fatalf("Expected no error from DeleteExpired, got %v", err)
+	}
+
+	if _, err := store.Get(ctx, "expired"); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected expired session to be deleted, got %v", err)
+	}
+	if _, err := store.Get(ctx, "valid"); err != nil {
+		t.Errorf("Expected valid session to still exist, got %v", err)
+	}
+	if _, err := store.Get(ctx, "forever"); err != nil {
+		t.Errorf("Expected session without TTL to still exist, got %v", err)
+	}
+}
+
+func testStateManagement(t *testing.T, h Harness, store session.SessionStore) {
+	sess := store.Create(context.Background())
+
+	sess.Set("key1", "value1")
+	sess.Set("key2", "value2")
+	sess.Set("key2", "updated")
+
+	if value, ok := sess.Get("key1"); !ok || value != "value1" {
+		t.Errorf("Expected key1=value1, got exists=%v, value=%v", ok, value)
+	}
+	if value, ok := sess.Get("key2"); !ok || value != "updated" {
+		t.Errorf("Expected key2=updated, got exists=%v, value=%v", ok, value)
+	}
+	if _, ok := sess.Get("non-existent"); ok {
+		t.Error("Expected non-existent key to return false")
+	}
+
+	sess.Delete("key1")
+	if _, ok := sess.Get("key1"); ok {
+		t.Error("Expected deleted key to not exist")
+	}
+
+	// Deleting a missing key is harmless
+	sess.Delete("non-existent")
+
+	updatedAt := sess.UpdatedAt()
+	time.Sleep(time.Millisecond)
+	sess.Set("key3", "value3")
+	if !sess.UpdatedAt().After(updatedAt) {
+		t.Error("Expected UpdatedAt to advance after Set")
+	}
+}
+
+func testHistoryOrdering(t *testing.T, h Harness, store session.SessionStore) {
+	sess := store.Create(context.Background())
+
+	if history := sess.GetHistory(0); len(history) != 0 {
+		t.Errorf("Expected empty history for new session, got %d entries", len(history))
+	}
+
+	base := time.Now()
+	entries := []session.Entry{
+		session.NewMessageEntry("user", "Find flights"),
+		session.NewToolCallEntry("search", map[string]any{"query": "flights"}),
+		session.NewToolResultEntry("search", "found", nil),
+		session.NewMessageEntry("assistant", "Here are your flights"),
+	}
+
+	for i := range entries {
+		entries[i].Timestamp = base.Add(time.Duration(i) * time.Second)
+		if err := sess.AddEntry(entries[i]); err != nil {
+			t.Fatalf("Expected no error adding entry %d, got %v", i, err)
+		}
+	}
+
+	history := sess.GetHistory(0)
+	if len(history) != len(entries) {
+		t.Fatalf("Expected %d entries, got %d", len(entries), len(history))
+	}
+
+	// Newest first
+	for i, entry := range history {
+		expected := entries[len(entries)-1-i]
+		if entry.ID != expected.ID {
+			t.Errorf("Position %d: expected entry %s, got %s", i, expected.ID, entry.ID)
+		}
+		if entry.Type != expected.Type {
+			t.Errorf("Position %d: expected type %s, got %s", i, expected.Type, entry.Type)
+		}
+	}
+
+	// Content keeps its concrete type
+	if _, ok := session.GetMessageContent(history[0]); !ok {
+		t.Errorf("Expected MessageContent, got %T", history[0].Content)
+	}
+	if _, ok := session.GetToolResultContent(history[1]); !ok {
+		t.Errorf("Expected ToolResultContent, got %T", history[1].Content)
+	}
+	if _, ok := session.GetToolCallContent(history[2]); !ok {
+		t.Errorf("Expected ToolCallContent, got %T", history[2].Content)
+	}
+}
+
+func testHistoryTimestamps(t *testing.T, h Harness, store session.SessionStore) {
+	sess := store.Create(context.Background())
+
+	// Entries are added out of order; history follows their timestamps
+	base := time.Now()
+	entries := make([]session.Entry, 4)
+	for i, offset := range []int{2, 0, 3, 1} {
+		entries[i] = session.NewMessageEntry("user", fmt.Sprintf("message %d", offset))
+		entries[i].Timestamp = base.Add(time.Duration(offset) * time.Second)
+		if err := sess.AddEntry(entries[i]); err != nil {
+			t.Fatalf("Expected no error adding entry %d, got %v", i, err)
+		}
+	}
+
+	history := sess.GetHistory(0)
+	if len(history) != len(entries) {
+		t.Fatalf("Expected %d entries, got %d", len(entries), len(history))
+	}
+	for i, expected := range []session.Entry{entries[2], entries[0], entries[3], entries[1]} {
+		if history[i].ID != expected.ID {
+			t.Errorf("Position %d: expected entry %s, got %s", i, expected.ID, history[i].ID)
+		}
+	}
+
+	// The limit keeps the newest entries by timestamp, not the last added
+	limited := sess.GetHistory(2)
+	if len(limited) != 2 || limited[0].ID != entries[2].ID || limited[1].ID != entries[0].ID {
+		t.Errorf("Expected the 2 newest entries, got %+v", limited)
+	}
+}
+
+func testHistoryLimit(t *testing.T, h Harness, store session.SessionStore) {
+	sess := store.Create(context.Background())
+
+	base := time.Now()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		entry := session.NewMessageEntry("user", fmt.Sprintf("message %d", i))
+		entry.Timestamp = base.Add(time.Duration(i) * time.Second)
+		sess.AddEntry(entry)
+		ids = append(ids, entry.ID)
+	}
+
+	limited := sess.GetHistory(2)
+	if len(limited) != 2 {
+		t.Fatalf("Expected 2 entries with limit, got %d", len(limited))
+	}
+	if limited[0].ID != ids[4] || limited[1].ID != ids[3] {
+		t.Error("Expected limit to keep the newest entries")
+	}
+
+	// A limit above the history size returns everything
+	if history := sess.GetHistory(10); len(history) != 5 {
+		t.Errorf("Expected 5 entries with large limit, got %d", len(history))
+	}
+
+	// Negative limits behave like no limit
+	if history := sess.GetHistory(-1); len(history) != 5 {
+		t.Errorf("Expected 5 entries with negative limit, got %d", len(history))
+	}
+}
+
+func testConcurrentAccess(t *testing.T, h Harness, store session.SessionStore) {
+	sess := store.Create(context.Background())
+
+	var wg sync.WaitGroup
+	numGoroutines := 10
+	numOperations := 20
+
+	wg.Add(numGoroutines * 2)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				key := fmt.Sprintf("key_%d_%d", id, j)
+				value := fmt.Sprintf("value_%d_%d", id, j)
+
+				sess.Set(key, value)
+				if retrieved, ok := sess.Get(key); !ok || retrieved != value {
+					t.Errorf("Concurrent access issue: expected %s, got %v", value, retrieved)
+				}
+			}
+		}(i)
+
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				entry := session.NewMessageEntry("user", fmt.Sprintf("msg_%d_%d", id, j))
+				if err := sess.AddEntry(entry); err != nil {
+					t.Errorf("Error adding entry: %v", err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if history := sess.GetHistory(0); len(history) != numGoroutines*numOperations {
+		t.Errorf("Expected %d history entries, got %d", numGoroutines*numOperations, len(history))
+	}
+
+	for i := 0; i < numGoroutines; i++ {
+		key := fmt.Sprintf("key_%d_%d", i, numOperations-1)
+		if _, ok := sess.Get(key); !ok {
+			t.Errorf("Expected %s to be set", key)
+		}
+	}
+}
+
+func testFork(t *testing.T, h Harness, store session.SessionStore) {
+	forker, ok := store.(session.SessionForker)
+	if !ok {
+		t.Skip("store does not implement session.SessionForker")
+	}
+	ctx := context.Background()
+
+	source := store.Create(ctx, session.WithID("source"))
+	source.Set("task", "booking")
+
+	base := time.Now()
+	var ids []string
+	for i := 0; i < 4; i++ {
+		entry := session.NewMessageEntry("user", fmt.Sprintf("message %d", i))
+		entry.Timestamp = base.Add(time.Duration(i) * time.Second)
+		source.AddEntry(entry)
+		ids = append(ids, entry.ID)
+	}
+	store.Save(ctx, source)
+
+	forked, err := forker.Fork(ctx, "source", ids[1])
+	if err != nil {
+		t.Fatalf("Expected no error from Fork, got %v", err)
+	}
+	if forked.ID() == "source" || forked.ID() == "" {
+		t.Errorf("Expected fork to get a new ID, got %q", forked.ID())
+	}
+
+	// History up to and including the fork point, state copied
+	history := forked.GetHistory(0)
+	if len(history) != 2 || history[0].ID != ids[1] || history[1].ID != ids[0] {
+		t.Errorf("Expected forked history [%s %s], got %v", ids[1], ids[0], history)
+	}
+	if value, ok := forked.Get("task"); !ok || value != "booking" {
+		t.Errorf("Expected forked state task=booking, got exists=%v, value=%v", ok, value)
+	}
+
+	// The fork is retrievable and independent of its source
+	forked.Set("task", "changed")
+	forked.AddEntry(session.NewMessageEntry("user", "edited"))
+	store.Save(ctx, forked)
+
+	if value, _ := source.Get("task"); value != "booking" {
+		t.Errorf("Expected source state to be unchanged, got %v", value)
+	}
+	if history := source.GetHistory(0); len(history) != 4 {
+		t.Errorf("Expected source history to be unchanged, got %d entries", len(history))
+	}
+	retrieved, err := store.Get(ctx, forked.ID())
+	if err != nil {
+		t.Fatalf("Expected forked session to be retrievable, got %v", err)
+	}
+	if history := retrieved.GetHistory(0); len(history) != 3 {
+		t.Errorf("Expected 3 entries in retrieved fork, got %d", len(history))
+	}
+
+	// Empty entry ID copies the full history; options apply to the fork
+	full, err := forker.Fork(ctx, "source", "", session.WithID("full-copy"))
+	if err != nil {
+		t.Fatalf("Expected no error from full Fork, got %v", err)
+	}
+	if full.ID() != "full-copy" {
+		t.Errorf("Expected fork ID full-copy, got %s", full.ID())
+	}
+	if history := full.GetHistory(0); len(history) != 4 {
+		t.Errorf("Expected full history in fork, got %d entries", len(history))
+	}
+
+	if _, err := forker.Fork(ctx, "source", "missing"); !errors.Is(err, session.ErrEntryNotFound) {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+	if _, err := forker.Fork(ctx, "missing", ""); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func testClose(t *testing.T, h Harness, store session.SessionStore) {
+	store.Create(context.Background())
+
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected no error from Close, got %v", err)
+	}
+
+	// Close must be safe to call more than once
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected no error from second Close, got %v", err)
+	}
+}
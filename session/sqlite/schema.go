@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations holds the schema changes in the order they must be applied.
+// Migration i is recorded as applied by setting PRAGMA user_version to i+1,
+// so new migrations must only ever be appended to this list.
+var migrations = []string{
+	// 1: sessions, state and history tables
+	`CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		expires_at INTEGER,
+		metadata   TEXT NOT NULL DEFAULT '{}'
+	);
+	CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+	CREATE TABLE session_state (
+		session_id TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+		key        TEXT NOT NULL,
+		value      TEXT NOT NULL,
+		PRIMARY KEY (session_id, key)
+	);
+
+	CREATE TABLE session_entries (
+		seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+		id         TEXT NOT NULL,
+		session_id TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+		type       TEXT NOT NULL,
+		timestamp  INTEGER NOT NULL,
+		content    TEXT NOT NULL,
+		metadata   TEXT NOT NULL DEFAULT '{}'
+	);
+	CREATE INDEX idx_session_entries_history ON session_entries(session_id, timestamp DESC, seq DESC);`,
+
+	// 2: TTL settings, so Touch and sliding expiry survive a reload
+	`ALTER TABLE sessions ADD COLUMN ttl INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sessions ADD COLUMN sliding INTEGER NOT NULL DEFAULT 0;`,
+
+	// 3: save counter for optimistic concurrency checks
+	`ALTER TABLE sessions ADD COLUMN version INTEGER NOT NULL DEFAULT 0;`,
+}
+
+// migrate brings the database schema up to date
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than supported version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// sqliteSession is a SQLite-backed implementation of Session.
+// State and metadata are kept in memory and written by the versioned Save,
+// while history is written as it is added and always read from the
+// database so GetHistory can use the index.
+type sqliteSession struct {
+	store     *Store
+	id        string
+	createdAt time.Time
+	updatedAt time.Time
+	expiresAt *time.Time
+	ttl       time.Duration
+	sliding   bool
+	version   int64
+	stored    bool // the session row was written at least once
+	state     map[string]any
+	metadata  map[string]string
+	mu        sync.RWMutex
+}
+
+// ID returns the session ID
+func (s *sqliteSession) ID() string {
+	return s.id
+}
+
+// CreatedAt returns when the session was created
+func (s *sqliteSession) CreatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.createdAt
+}
+
+// UpdatedAt returns when the session was last updated
+func (s *sqliteSession) UpdatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+// Version returns the stored save count this session is based on
+func (s *sqliteSession) Version() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Get retrieves a value from the session state
+func (s *sqliteSession) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.state[key]
+	return value, exists
+}
+
+// Set stores a value in the session state until the next Save
+func (s *sqliteSession) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[key] = value
+	s.updatedAt = time.Now()
+}
+
+// Delete removes a value from the session state until the next Save
+func (s *sqliteSession) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state, key)
+	s.updatedAt = time.Now()
+}
+
+// State returns a shallow copy of all state values
+func (s *sqliteSession) State() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := make(map[string]any, len(s.state))
+	for key, value := range s.state {
+		state[key] = value
+	}
+	return state
+}
+
+// Metadata returns a copy of the session metadata
+func (s *sqliteSession) Metadata() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metadata := make(map[string]string, len(s.metadata))
+	for key, value := range s.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// GetMetadata retrieves a metadata value
+func (s *sqliteSession) GetMetadata(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.metadata[key]
+	return value, exists
+}
+
+// SetMetadata stores a metadata value until the next Save
+func (s *sqliteSession) SetMetadata(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[key] = value
+	s.updatedAt = time.Now()
+}
+
+// ExpiresAt returns when the session expires, or nil if it never does
+func (s *sqliteSession) ExpiresAt() *time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.expiresAt == nil {
+		return nil
+	}
+	expiresAt := *s.expiresAt
+	return &expiresAt
+}
+
+// Touch restarts the session TTL from now
+func (s *sqliteSession) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(s.ttl)
+	s.expiresAt = &expiresAt
+
+	s.store.expire(context.Background(), s.id, s.expiresAt)
+}
+
+// Extend pushes the session expiry back by d
+func (s *sqliteSession) Extend(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expiresAt == nil {
+		return
+	}
+	expiresAt := s.expiresAt.Add(d)
+	s.expiresAt = &expiresAt
+
+	s.store.expire(context.Background(), s.id, s.expiresAt)
+}
+
+// AddEntry adds an entry to the session history
+func (s *sqliteSession) AddEntry(entry session.Entry) error {
+	content, err := json.Marshal(entry.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry content: %w", err)
+	}
+
+	metadata, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry metadata: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := context.Background()
+	_, err = s.store.db.ExecContext(ctx,
+		`INSERT INTO session_entries (id, session_id, type, timestamp, content, metadata)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.ID, s.id, string(entry.Type), entry.Timestamp.UnixNano(), string(content), string(metadata))
+	if err != nil {
+		return fmt.Errorf("failed to insert entry: %w", err)
+	}
+
+	s.updatedAt = time.Now()
+	s.store.touch(ctx, s.id, s.updatedAt)
+	return nil
+}
+
+// GetHistory returns the session history, sorted by timestamp (newest first)
+func (s *sqliteSession) GetHistory(limit int) []session.Entry {
+	query := `SELECT id, type, timestamp, content, metadata FROM session_entries
+		WHERE session_id = ? ORDER BY timestamp DESC, seq DESC`
+	args := []any{s.id}
+
+	// Apply limit if specified
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.store.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return []session.Entry{}
+	}
+	defer rows.Close()
+
+	history := make([]session.Entry, 0)
+	for rows.Next() {
+		var (
+			entry     session.Entry
+			entryType string
+			timestamp int64
+			content   string
+			metadata  string
+		)
+
+		if err := rows.Scan(&entry.ID, &entryType, &timestamp, &content, &metadata); err != nil {
+			continue
+		}
+
+		entry.Type = session.EntryType(entryType)
+		entry.Timestamp = time.Unix(0, timestamp)
+
+		entry.Content, err = session.UnmarshalContent(entry.Type, []byte(content))
+		if err != nil {
+			continue
+		}
+
+		entry.Metadata = make(map[string]any)
+		if err := json.Unmarshal([]byte(metadata), &entry.Metadata); err != nil {
+			continue
+		}
+
+		history = append(history, entry)
+	}
+
+	return history
+}
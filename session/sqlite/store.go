@@ -0,0 +1,452 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+	"github.com/google/uuid"
+
+	// Pure-Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// Store is a SQLite implementation of SessionStore
+type Store struct {
+	db        *sql.DB
+	ownsDB    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStore opens (or creates) the SQLite database at dsn and prepares it for use.
+// Use ":memory:" for a private in-memory database.
+func NewStore(dsn string) (*Store, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	store, err := newStore(db, true)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// NewStoreWithDB creates a store on top of an existing database handle.
+// The caller keeps ownership of db; Close will not close it.
+func NewStoreWithDB(db *sql.DB) (*Store, error) {
+	return newStore(db, false)
+}
+
+func newStore(db *sql.DB, ownsDB bool) (*Store, error) {
+	// SQLite serializes writers anyway, and a single connection keeps
+	// ":memory:" databases from being split across connections
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	store := &Store{
+		db:     db,
+		ownsDB: ownsDB,
+		done:   make(chan struct{}),
+	}
+
+	// Start background cleanup routine
+	go store.cleanupExpired()
+	return store, nil
+}
+
+// Create creates a new session with the given options, replacing any
+// session stored under the same ID along with its state and history.
+// If the session row cannot be written, the error is logged and the session
+// is still returned; a later Save will retry persisting it.
+func (s *Store) Create(ctx context.Context, opts ...session.CreateOption) session.Session {
+	sess := s.newSession(session.ApplyOptions(opts...))
+	if err := s.createSession(ctx, sess); err != nil {
+		slog.WarnContext(ctx, "failed to create session", slog.String("session_id", sess.id), slog.Any("error", err))
+		return sess
+	}
+	sess.stored = true
+	return sess
+}
+
+// createSession writes a new session row, dropping an existing session
+// with the same ID
+func (s *Store) createSession(ctx context.Context, sess *sqliteSession) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin create: %w", err)
+	}
+	defer tx.Rollback()
+
+	// State and history go with the row
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, sess.id); err != nil {
+		return fmt.Errorf("failed to replace session: %w", err)
+	}
+	if err := s.upsertSession(ctx, tx, sess); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit create: %w", err)
+	}
+	return nil
+}
+
+// newSession builds an empty, unsaved session from resolved create options
+func (s *Store) newSession(options session.CreateOptions) *sqliteSession {
+	id := options.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	now := time.Now()
+	sess := &sqliteSession{
+		store:     s,
+		id:        id,
+		createdAt: now,
+		updatedAt: now,
+		state:     make(map[string]any),
+		metadata:  options.Metadata,
+	}
+
+	if options.TTL > 0 {
+		expiresAt := now.Add(options.TTL)
+		sess.expiresAt = &expiresAt
+		sess.ttl = options.TTL
+		sess.sliding = options.SlidingTTL
+	}
+
+	return sess
+}
+
+// Fork creates a new session branched from sourceID at atEntryID.
+// See session.SessionForker for the semantics.
+func (s *Store) Fork(ctx context.Context, sourceID, atEntryID string, opts ...session.CreateOption) (session.Session, error) {
+	value, err := s.Get(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source := value.(*sqliteSession)
+
+	// Entries are copied by insertion order, up to the fork point
+	var lastSeq int64 = math.MaxInt64
+	if atEntryID != "" {
+		err := s.db.QueryRowContext(ctx,
+			`SELECT seq FROM session_entries WHERE session_id = ? AND id = ?`, sourceID, atEntryID).
+			Scan(&lastSeq)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, session.ErrEntryNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find fork entry: %w", err)
+		}
+	}
+
+	forked := s.newSession(session.ApplyOptions(
+		session.ForkOptions(sourceID, atEntryID, source.metadata, opts...)...))
+	for key, value := range source.state {
+		forked.state[key] = value
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin fork: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.upsertSession(ctx, tx, forked); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO session_state (session_id, key, value)
+		 SELECT ?, key, value FROM session_state WHERE session_id = ?`,
+		forked.id, sourceID); err != nil {
+		return nil, fmt.Errorf("failed to copy session state: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO session_entries (id, session_id, type, timestamp, content, metadata)
+		 SELECT id, ?, type, timestamp, content, metadata FROM session_entries
+		 WHERE session_id = ? AND seq <= ? ORDER BY seq`,
+		forked.id, sourceID, lastSeq); err != nil {
+		return nil, fmt.Errorf("failed to copy session history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit fork: %w", err)
+	}
+
+	forked.stored = true
+	return forked, nil
+}
+
+// Get retrieves a session by ID
+func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
+	var (
+		createdAt, updatedAt, ttl, version int64
+		expiresAt                          sql.NullInt64
+		metadata                           string
+		sliding                            bool
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT created_at, updated_at, expires_at, metadata, ttl, sliding, version FROM sessions WHERE id = ?`, id).
+		Scan(&createdAt, &updatedAt, &expiresAt, &metadata, &ttl, &sliding, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, session.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	sess := &sqliteSession{
+		store:     s,
+		id:        id,
+		createdAt: time.Unix(0, createdAt),
+		updatedAt: time.Unix(0, updatedAt),
+		ttl:       time.Duration(ttl),
+		sliding:   sliding,
+		version:   version,
+		stored:    true,
+		state:     make(map[string]any),
+		metadata:  make(map[string]string),
+	}
+
+	// Check if session has expired
+	if expiresAt.Valid {
+		t := time.Unix(0, expiresAt.Int64)
+		if time.Now().After(t) {
+			s.Delete(ctx, id)
+			return nil, session.ErrSessionNotFound
+		}
+		sess.expiresAt = &t
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &sess.metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode session metadata: %w", err)
+	}
+
+	if err := s.loadState(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	if sess.sliding {
+		sess.Touch()
+	}
+
+	return sess, nil
+}
+
+// loadState reads all state values of a session into its cache.
+// Values are decoded by encoding/json, so numbers come back as float64.
+func (s *Store) loadState(ctx context.Context, sess *sqliteSession) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, value FROM session_state WHERE session_id = ?`, sess.id)
+	if err != nil {
+		return fmt.Errorf("failed to load session state: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return fmt.Errorf("failed to read session state: %w", err)
+		}
+
+		var value any
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			return fmt.Errorf("failed to decode state value %q: %w", key, err)
+		}
+		sess.state[key] = value
+	}
+
+	return rows.Err()
+}
+
+// Save persists the session row, its metadata and a full snapshot of its state.
+// It returns session.ErrVersionConflict if the stored session was saved
+// through another session value, or deleted and recreated, since ss was loaded,
+// and session.ErrSessionNotFound if it was deleted or has expired.
+func (s *Store) Save(ctx context.Context, sess session.Session) error {
+	ss, ok := sess.(*sqliteSession)
+	if !ok || ss.store != s {
+		return fmt.Errorf("session %s does not belong to this store", sess.ID())
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin save: %w", err)
+	}
+	defer tx.Rollback()
+
+	var createdAt, version int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT created_at, version FROM sessions WHERE id = ?`, ss.id).Scan(&createdAt, &version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && ss.stored:
+		return session.ErrSessionNotFound
+	case errors.Is(err, sql.ErrNoRows):
+		// Create failed to write it; Save writes it from scratch
+	case err != nil:
+		return fmt.Errorf("failed to read session version: %w", err)
+	case createdAt != ss.createdAt.UnixNano() || version != ss.version:
+		return session.ErrVersionConflict
+	}
+
+	if err := s.upsertSession(ctx, tx, ss); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET version = ? WHERE id = ?`, ss.version+1, ss.id); err != nil {
+		return fmt.Errorf("failed to update session version: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM session_state WHERE session_id = ?`, ss.id); err != nil {
+		return fmt.Errorf("failed to clear session state: %w", err)
+	}
+
+	for key, value := range ss.state {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode state value %q: %w", key, err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO session_state (session_id, key, value) VALUES (?, ?, ?)`,
+			ss.id, key, string(data)); err != nil {
+			return fmt.Errorf("failed to save state value %q: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit save: %w", err)
+	}
+
+	ss.version++
+	ss.stored = true
+	return nil
+}
+
+// Delete removes a session by ID together with its state and history
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes all expired sessions
+func (s *Store) DeleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM sessions WHERE expires_at IS NOT NULL AND expires_at < ?`, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
+
+// cleanupExpired runs a background cleanup routine
+func (s *Store) cleanupExpired() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Use background context for cleanup
+			s.DeleteExpired(context.Background())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops the background cleanup routine and closes the database
+// if it was opened by NewStore
+func (s *Store) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if s.ownsDB {
+			err = s.db.Close()
+		}
+	})
+	return err
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// upsertSession writes the session row, keeping existing state and history.
+// The caller must hold at least a read lock on sess, or own it exclusively.
+func (s *Store) upsertSession(ctx context.Context, db execer, sess *sqliteSession) error {
+	metadata, err := json.Marshal(sess.metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode session metadata: %w", err)
+	}
+
+	var expiresAt sql.NullInt64
+	if sess.expiresAt != nil {
+		expiresAt = sql.NullInt64{Int64: sess.expiresAt.UnixNano(), Valid: true}
+	}
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO sessions (id, created_at, updated_at, expires_at, metadata, ttl, sliding)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+			updated_at = excluded.updated_at,
+			expires_at = excluded.expires_at,
+			metadata   = excluded.metadata,
+			ttl        = excluded.ttl,
+			sliding    = excluded.sliding`,
+		sess.id, sess.createdAt.UnixNano(), sess.updatedAt.UnixNano(), expiresAt, string(metadata),
+		int64(sess.ttl), sess.sliding)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// touch records a new update time for a session
+func (s *Store) touch(ctx context.Context, id string, updatedAt time.Time) {
+	s.db.ExecContext(ctx, `UPDATE sessions SET updated_at = ? WHERE id = ?`, updatedAt.UnixNano(), id)
+}
+
+// expire records a new expiry for a stored session. A failed write is
+// logged; the next Save writes the expiry again.
+func (s *Store) expire(ctx context.Context, id string, expiresAt *time.Time) {
+	var value sql.NullInt64
+	if expiresAt != nil {
+		value = sql.NullInt64{Int64: expiresAt.UnixNano(), Valid: true}
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE sessions SET expires_at = ? WHERE id = ?`, value, id); err != nil {
+		slog.WarnContext(ctx, "failed to update session expiry", slog.String("session_id", id), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,397 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/sessiontest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	sessiontest.Run(t, sessiontest.Harness{
+		NewStore: func(t *testing.T) session.SessionStore {
+			return newTestStore(t)
+		},
+	})
+}
+
+func TestStore_Create(t *testing.T) {
+	store := newTestStore(t)
+
+	// Test creating session with default options
+	sess := store.Create(context.Background())
+	if sess.ID() == "" {
+		t.Error("Expected session to have an ID")
+	}
+
+	// Test creating session with custom ID
+	customID := "custom-session-id"
+	sess2 := store.Create(context.Background(), session.WithID(customID))
+	if sess2.ID() != customID {
+		t.Errorf("Expected session ID to be %s, got %s", customID, sess2.ID())
+	}
+
+	// Created sessions must be retrievable right away
+	if _, err := store.Get(context.Background(), customID); err != nil {
+		t.Errorf("Expected created session to be persisted, got %v", err)
+	}
+}
+
+func TestStore_GetAndSave(t *testing.T) {
+	store := newTestStore(t)
+
+	// Test getting non-existent session
+	_, err := store.Get(context.Background(), "non-existent")
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	sess := store.Create(context.Background(), session.WithID("test-session"))
+	sess.Set("key", "value")
+
+	err = store.Save(context.Background(), sess)
+	if err != nil {
+		t.Errorf("Expected no error from Save, got %v", err)
+	}
+
+	retrieved, err := store.Get(context.Background(), "test-session")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, ok := retrieved.Get("key"); !ok || value != "value" {
+		t.Errorf("Expected key=value, got exists=%v, value=%v", ok, value)
+	}
+}
+
+func TestStore_SaveForeignSession(t *testing.T) {
+	store := newTestStore(t)
+	other := newTestStore(t)
+
+	sess := other.Create(context.Background())
+	if err := store.Save(context.Background(), sess); err == nil {
+		t.Error("Expected error when saving a session from another store")
+	}
+}
+
+func TestStore_Delete(t *testing.T) {
+	store := newTestStore(t)
+
+	sess := store.Create(context.Background(), session.WithID("delete-test"))
+	sess.Set("key", "value")
+	sess.AddEntry(session.NewMessageEntry("user", "Hello"))
+
+	err := store.Delete(context.Background(), sess.ID())
+	if err != nil {
+		t.Errorf("Expected no error from Delete, got %v", err)
+	}
+
+	_, err = store.Get(context.Background(), sess.ID())
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after deletion, got %v", err)
+	}
+
+	// State and history are removed with the session
+	var count int
+	store.db.QueryRow(`SELECT COUNT(*) FROM session_entries WHERE session_id = ?`, sess.ID()).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected history to be deleted, found %d entries", count)
+	}
+}
+
+func TestStore_ExpiredSessions(t *testing.T) {
+	store := newTestStore(t)
+
+	sess := store.Create(context.Background(), session.WithID("expired-test"), session.WithTTL(1*time.Millisecond))
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err := store.Get(context.Background(), sess.ID())
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for expired session, got %v", err)
+	}
+}
+
+func TestStore_DeleteExpired(t *testing.T) {
+	store := newTestStore(t)
+
+	store.Create(context.Background(), session.WithID("expired"), session.WithTTL(1*time.Millisecond))
+	store.Create(context.Background(), session.WithID("valid"), session.WithTTL(1*time.Hour))
+	store.Create(context.Background(), session.WithID("no-ttl"))
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := store.DeleteExpired(context.Background()); err != nil {
+		t.Errorf("Expected no error from DeleteExpired, got %v", err)
+	}
+
+	var count int
+	store.db.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 sessions after cleanup, got %d", count)
+	}
+
+	if _, err := store.Get(context.Background(), "valid"); err != nil {
+		t.Errorf("Expected valid session to still exist, got %v", err)
+	}
+}
+
+func TestSession_StateManagement(t *testing.T) {
+	store := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	sess.Set("key1", "value1")
+	sess.Set("key2", 42)
+
+	value1, exists1 := sess.Get("key1")
+	if !exists1 || value1 != "value1" {
+		t.Errorf("Expected key1=value1, got exists=%v, value=%v", exists1, value1)
+	}
+
+	value2, exists2 := sess.Get("key2")
+	if !exists2 || value2 != 42 {
+		t.Errorf("Expected key2=42, got exists=%v, value=%v", exists2, value2)
+	}
+
+	sess.Delete("key1")
+	if _, exists := sess.Get("key1"); exists {
+		t.Error("Expected deleted key to not exist")
+	}
+
+	// Changes are kept in memory until Save
+	reloaded, err := store.Get(context.Background(), sess.ID())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, exists := reloaded.Get("key2"); exists {
+		t.Error("Expected unsaved key to not be stored")
+	}
+	if err := store.Save(context.Background(), sess); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	// Reloaded state goes through JSON, so numbers come back as float64
+	reloaded, err = store.Get(context.Background(), sess.ID())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, exists := reloaded.Get("key1"); exists {
+		t.Error("Expected deleted key to not exist after reload")
+	}
+	if value, _ := reloaded.Get("key2"); value != float64(42) {
+		t.Errorf("Expected key2=42 after reload, got %v", value)
+	}
+}
+
+func TestSession_StaleChangesAreNotWritten(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	sess := store.Create(ctx, session.WithID("stale"))
+
+	stale, _ := store.Get(ctx, "stale")
+	current, _ := store.Get(ctx, "stale")
+
+	current.Set("owner", "current")
+	if err := store.Save(ctx, current); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	// A stale value cannot overwrite the saved state or metadata
+	stale.Set("owner", "stale")
+	stale.SetMetadata("owner", "stale")
+	if err := store.Save(ctx, stale); !errors.Is(err, session.ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	retrieved, err := store.Get(ctx, sess.ID())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, _ := retrieved.Get("owner"); value != "current" {
+		t.Errorf("Expected owner=current, got %v", value)
+	}
+	if _, exists := retrieved.GetMetadata("owner"); exists {
+		t.Error("Expected stale metadata to not be stored")
+	}
+}
+
+func TestSession_HistoryManagement(t *testing.T) {
+	store := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	entry1 := session.NewMessageEntry("user", "Hello")
+	time.Sleep(1 * time.Millisecond)
+	entry2 := session.NewMessageEntry("assistant", "Hi there")
+	time.Sleep(1 * time.Millisecond)
+	entry3 := session.NewToolCallEntry("search", map[string]any{"query": "test"})
+
+	for _, entry := range []session.Entry{entry1, entry2, entry3} {
+		if err := sess.AddEntry(entry); err != nil {
+			t.Errorf("Expected no error adding entry, got %v", err)
+		}
+	}
+
+	history := sess.GetHistory(0)
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(history))
+	}
+
+	// Verify newest first ordering
+	if history[0].ID != entry3.ID || history[1].ID != entry2.ID || history[2].ID != entry1.ID {
+		t.Error("Expected history to be sorted newest first")
+	}
+
+	limitedHistory := sess.GetHistory(2)
+	if len(limitedHistory) != 2 {
+		t.Errorf("Expected 2 entries with limit, got %d", len(limitedHistory))
+	}
+	if limitedHistory[0].ID != entry3.ID {
+		t.Error("Expected limited history to keep the newest entries")
+	}
+}
+
+func TestSession_HistoryContentTypes(t *testing.T) {
+	store := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	sess.AddEntry(session.NewToolResultEntry("search", "found", nil))
+	sess.AddEntry(session.NewToolCallEntry("search", map[string]any{"query": "test"}))
+	sess.AddEntry(session.NewMessageEntry("user", "Hello"))
+
+	history := sess.GetHistory(0)
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(history))
+	}
+
+	if content, ok := session.GetMessageContent(history[0]); !ok || content.Text != "Hello" {
+		t.Errorf("Expected message content to round-trip, got %#v", history[0].Content)
+	}
+	if content, ok := session.GetToolCallContent(history[1]); !ok || content.Parameters["query"] != "test" {
+		t.Errorf("Expected tool call content to round-trip, got %#v", history[1].Content)
+	}
+	if content, ok := session.GetToolResultContent(history[2]); !ok || content.Result != "found" {
+		t.Errorf("Expected tool result content to round-trip, got %#v", history[2].Content)
+	}
+}
+
+func TestStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	sess := store.Create(context.Background(),
+		session.WithID("persistent"),
+		session.WithTTL(time.Hour),
+		session.WithMetadata("user_id", "123"))
+	sess.Set("task", "booking")
+	sess.AddEntry(session.NewMessageEntry("user", "Book a flight"))
+	if err := store.Save(context.Background(), sess); err != nil {
+		t.Fatalf("Expected no error from Save, got %v", err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Expected no error from Close, got %v", err)
+	}
+
+	// Reopening runs the migrations again, which must be a no-op
+	store, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+
+	retrieved, err := store.Get(context.Background(), "persistent")
+	if err != nil {
+		t.Fatalf("Expected session to survive reopen, got %v", err)
+	}
+
+	if value, _ := retrieved.Get("task"); value != "booking" {
+		t.Errorf("Expected task=booking, got %v", value)
+	}
+
+	if metadata := retrieved.(*sqliteSession).metadata; metadata["user_id"] != "123" {
+		t.Errorf("Expected metadata user_id=123, got %v", metadata)
+	}
+
+	history := retrieved.GetHistory(0)
+	if len(history) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(history))
+	}
+	if content, ok := session.GetMessageContent(history[0]); !ok || content.Text != "Book a flight" {
+		t.Errorf("Expected persisted message, got %#v", history[0].Content)
+	}
+}
+
+func TestMigrate_Idempotent(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := migrate(context.Background(), store.db); err != nil {
+		t.Fatalf("Expected re-running migrations to succeed, got %v", err)
+	}
+
+	var version int
+	store.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version != len(migrations) {
+		t.Errorf("Expected schema version %d, got %d", len(migrations), version)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	store := newTestStore(t)
+	sess := store.Create(context.Background())
+
+	var wg sync.WaitGroup
+	numGoroutines := 10
+	numOperations := 20
+
+	wg.Add(numGoroutines * 2)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				key := fmt.Sprintf("key_%d_%d", id, j)
+				value := fmt.Sprintf("value_%d_%d", id, j)
+
+				sess.Set(key, value)
+				if retrieved, exists := sess.Get(key); exists && retrieved != value {
+					t.Errorf("Concurrent access issue: expected %s, got %v", value, retrieved)
+				}
+			}
+		}(i)
+
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				entry := session.NewMessageEntry("user", fmt.Sprintf("msg_%d_%d", id, j))
+				if err := sess.AddEntry(entry); err != nil {
+					t.Errorf("Error adding entry: %v", err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	history := sess.GetHistory(0)
+	if len(history) != numGoroutines*numOperations {
+		t.Errorf("Expected %d history entries, got %d", numGoroutines*numOperations, len(history))
+	}
+}
@@ -10,7 +10,12 @@ var (
 	ErrSessionNotFound = errors.New("session not found")
 )
 
-// SessionStore manages session persistence
+// SessionStore manages session persistence.
+//
+// Changes made through a Session (state, metadata, history, Touch and Extend)
+// are visible on that value immediately. Persistent stores may write them
+// through as they happen, but only guarantee they are durable once Save
+// returns. Get refreshes the expiry of sessions created with a sliding TTL.
 type SessionStore interface {
 	Create(ctx context.Context, opts ...CreateOption) Session
 	Get(ctx context.Context, id string) (Session, error)
@@ -0,0 +1,199 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// RenderMarkdown renders the transcript as a human-readable Markdown document
+func (t *Transcript) RenderMarkdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Session %s\n\n", t.SessionID)
+	fmt.Fprintf(&b, "- Created: %s\n", t.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Updated: %s\n", t.UpdatedAt.Format(time.RFC3339))
+
+	if len(t.Metadata) > 0 {
+		b.WriteString("\n## Metadata\n\n")
+		for _, key := range sortedKeys(t.Metadata) {
+			fmt.Fprintf(&b, "- %s: %s\n", key, t.Metadata[key])
+		}
+	}
+
+	b.WriteString("\n## Conversation\n")
+
+	for _, entry := range t.History {
+		switch entry.Type {
+		case session.EntryTypeMessage:
+			if content, ok := session.GetMessageContent(entry); ok {
+				fmt.Fprintf(&b, "\n### %s\n\n%s\n", roleTitle(content.Role), content.Text)
+			}
+
+		case session.EntryTypeToolCall:
+			if content, ok := session.GetToolCallContent(entry); ok {
+				fmt.Fprintf(&b, "\n**Tool call:** `%s`\n\n```json\n%s\n```\n", content.Tool, toJSON(content.Parameters))
+			}
+
+		case session.EntryTypeToolResult:
+			if content, ok := session.GetToolResultContent(entry); ok {
+				if content.Success {
+					fmt.Fprintf(&b, "\n**Tool result:** `%s`\n\n```json\n%s\n```\n", content.Tool, toJSON(content.Result))
+				} else {
+					fmt.Fprintf(&b, "\n**Tool error:** `%s`\n\n> %s\n", content.Tool, content.Error)
+				}
+			}
+
+		case session.EntryTypeThinking:
+			fmt.Fprintf(&b, "\n<details><summary>Thinking</summary>\n\n%s\n\n</details>\n", contentText(entry.Content))
+
+		default:
+			fmt.Fprintf(&b, "\n**%s:**\n\n```json\n%s\n```\n", entry.Type, toJSON(entry.Content))
+		}
+	}
+
+	return b.String()
+}
+
+// Message is a chat message in the OpenAI chat completions format
+type Message struct {
+	Role       string     `json:"role"`
+	Content    *string    `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is a tool invocation requested by an assistant message
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names a function and carries its JSON-encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// OpenAIMessages converts the transcript history into OpenAI-style chat messages.
+// Consecutive tool calls are grouped into one assistant message and each tool
+// result is linked to the earliest unanswered call of the same tool.
+// Thinking entries and custom entry types are omitted.
+func (t *Transcript) OpenAIMessages() []Message {
+	messages := make([]Message, 0, len(t.History))
+	pending := make(map[string][]string) // tool name -> unanswered call IDs
+
+	for _, entry := range t.History {
+		switch entry.Type {
+		case session.EntryTypeMessage:
+			content, ok := session.GetMessageContent(entry)
+			if !ok {
+				continue
+			}
+			text := content.Text
+			messages = append(messages, Message{Role: content.Role, Content: &text})
+
+		case session.EntryTypeToolCall:
+			content, ok := session.GetToolCallContent(entry)
+			if !ok {
+				continue
+			}
+			arguments := "{}"
+			if content.Parameters != nil {
+				arguments = toCompactJSON(content.Parameters)
+			}
+			call := ToolCall{
+				ID:   "call_" + entry.ID,
+				Type: "function",
+				Function: FunctionCall{
+					Name:      content.Tool,
+					Arguments: arguments,
+				},
+			}
+			pending[content.Tool] = append(pending[content.Tool], call.ID)
+
+			// Group with the previous assistant message if it only carries tool calls
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "assistant" && messages[last].Content == nil {
+				messages[last].ToolCalls = append(messages[last].ToolCalls, call)
+				continue
+			}
+			messages = append(messages, Message{Role: "assistant", ToolCalls: []ToolCall{call}})
+
+		case session.EntryTypeToolResult:
+			content, ok := session.GetToolResultContent(entry)
+			if !ok {
+				continue
+			}
+			ids := pending[content.Tool]
+			if len(ids) == 0 {
+				// A result without a matching call cannot be expressed as a tool message
+				continue
+			}
+			pending[content.Tool] = ids[1:]
+
+			text := toCompactJSON(content.Result)
+			if !content.Success {
+				text = "Error: " + content.Error
+			}
+			messages = append(messages, Message{Role: "tool", Content: &text, ToolCallID: ids[0]})
+		}
+	}
+
+	return messages
+}
+
+// RenderOpenAIMessages renders OpenAIMessages as indented JSON
+func (t *Transcript) RenderOpenAIMessages() ([]byte, error) {
+	data, err := json.MarshalIndent(t.OpenAIMessages(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode messages: %w", err)
+	}
+	return data, nil
+}
+
+func roleTitle(role string) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+func contentText(content any) string {
+	if text, ok := content.(string); ok {
+		return text
+	}
+	return toJSON(content)
+}
+
+func toJSON(value any) string {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func toCompactJSON(value any) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
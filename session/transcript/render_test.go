@@ -0,0 +1,109 @@
+package transcript
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+func testTranscript() *Transcript {
+	thinking := session.Entry{ID: "t1", Type: session.EntryTypeThinking, Content: "check the weather first"}
+	search := session.NewToolCallEntry("search", map[string]any{"q": "taipei"})
+	search.ID = "c2"
+	weather := session.NewToolCallEntry("weather", map[string]any{"city": "Taipei"})
+	weather.ID = "c1"
+
+	return &Transcript{
+		Version:   Version,
+		SessionID: "s1",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC),
+		Metadata:  map[string]string{"user": "alice"},
+		History: []session.Entry{
+			session.NewMessageEntry("user", "Weather in Taipei?"),
+			thinking,
+			weather,
+			search,
+			session.NewToolResultEntry("search", "sunny", nil),
+			session.NewToolResultEntry("weather", nil, errors.New("timeout")),
+			session.NewMessageEntry("assistant", "Probably sunny."),
+		},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	md := testTranscript().RenderMarkdown()
+
+	for _, want := range []string{
+		"# Session s1",
+		"- user: alice",
+		"### User\n\nWeather in Taipei?",
+		"<summary>Thinking</summary>",
+		"**Tool call:** `weather`",
+		"\"city\": \"Taipei\"",
+		"**Tool result:** `search`",
+		"**Tool error:** `weather`\n\n> timeout",
+		"### Assistant\n\nProbably sunny.",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestOpenAIMessages(t *testing.T) {
+	messages := testTranscript().OpenAIMessages()
+
+	if len(messages) != 5 {
+		t.Fatalf("Expected 5 messages, got %d: %+v", len(messages), messages)
+	}
+
+	if messages[0].Role != "user" || *messages[0].Content != "Weather in Taipei?" {
+		t.Errorf("Unexpected first message: %+v", messages[0])
+	}
+
+	// Consecutive tool calls are grouped into one assistant message
+	calls := messages[1]
+	if calls.Role != "assistant" || calls.Content != nil || len(calls.ToolCalls) != 2 {
+		t.Fatalf("Expected assistant message with 2 tool calls, got %+v", calls)
+	}
+	if calls.ToolCalls[0].Function.Name != "weather" || calls.ToolCalls[0].Function.Arguments != `{"city":"Taipei"}` {
+		t.Errorf("Unexpected tool call: %+v", calls.ToolCalls[0])
+	}
+
+	// Results are linked to their calls by tool name, not by position
+	if messages[2].Role != "tool" || messages[2].ToolCallID != "call_c2" || *messages[2].Content != "sunny" {
+		t.Errorf("Unexpected search result: %+v", messages[2])
+	}
+	if messages[3].ToolCallID != "call_c1" || *messages[3].Content != "Error: timeout" {
+		t.Errorf("Unexpected weather result: %+v", messages[3])
+	}
+
+	if messages[4].Role != "assistant" || *messages[4].Content != "Probably sunny." {
+		t.Errorf("Unexpected final message: %+v", messages[4])
+	}
+}
+
+func TestRenderOpenAIMessages(t *testing.T) {
+	data, err := testTranscript().RenderOpenAIMessages()
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+
+	var raw []map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to parse rendered JSON: %v", err)
+	}
+
+	// Assistant tool-call messages carry an explicit null content
+	if content, exists := raw[1]["content"]; !exists || content != nil {
+		t.Errorf("Expected null content, got %v (present=%v)", content, exists)
+	}
+	if raw[1]["tool_calls"].([]any)[0].(map[string]any)["type"] != "function" {
+		t.Errorf("Expected function tool call, got %v", raw[1]["tool_calls"])
+	}
+}
@@ -0,0 +1,136 @@
+// Package transcript exports sessions to a portable, versioned JSON document
+// and imports them into any session.SessionStore. Transcripts can also be
+// rendered as Markdown or as OpenAI-style chat messages.
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/davidleitw/go-agent/session"
+)
+
+// Version is the transcript format version written by Export
+const Version = 1
+
+// Metadata key recorded on imported sessions
+const MetadataImportedFrom = "imported_from_session_id"
+
+// Common errors
+var (
+	ErrUnsupportedVersion = errors.New("unsupported transcript version")
+	ErrStateNotReadable   = errors.New("session does not expose its state")
+)
+
+// Transcript is the portable representation of a session
+type Transcript struct {
+	Version    int               `json:"version"`
+	SessionID  string            `json:"session_id"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Metadata   map[string]string `json:"metadata"`
+	State      map[string]any    `json:"state"`
+
+	// History is ordered oldest first, the order in which it is replayed
+	History []session.Entry `json:"history"`
+}
+
+// Export captures the state, metadata and full history of a session.
+// The session must implement session.StateReader.
+func Export(sess session.Session) (*Transcript, error) {
+	stateReader, ok := sess.(session.StateReader)
+	if !ok {
+		return nil, ErrStateNotReadable
+	}
+
+	t := &Transcript{
+		Version:    Version,
+		SessionID:  sess.ID(),
+		CreatedAt:  sess.CreatedAt(),
+		UpdatedAt:  sess.UpdatedAt(),
+		ExportedAt: time.Now(),
+		Metadata:   sess.Metadata(),
+		State:      stateReader.State(),
+	}
+
+	// GetHistory is newest first; transcripts are oldest first
+	history := sess.GetHistory(0)
+	t.History = make([]session.Entry, len(history))
+	for i, entry := range history {
+		t.History[len(history)-1-i] = entry
+	}
+
+	return t, nil
+}
+
+// Import creates a new session in store from a transcript, replaying its
+// state and history. The original metadata is copied and the original
+// session ID is recorded under MetadataImportedFrom; opts apply as they
+// would to Create, e.g. session.WithID to keep the original ID. If the
+// import fails, the new session is deleted from store.
+func Import(ctx context.Context, store session.SessionStore, t *Transcript, opts ...session.CreateOption) (session.Session, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+
+	createOpts := make([]session.CreateOption, 0, len(t.Metadata)+len(opts)+1)
+	for key, value := range t.Metadata {
+		createOpts = append(createOpts, session.WithMetadata(key, value))
+	}
+	createOpts = append(createOpts, session.WithMetadata(MetadataImportedFrom, t.SessionID))
+	createOpts = append(createOpts, opts...)
+
+	sess := store.Create(ctx, createOpts...)
+
+	for key, value := range t.State {
+		sess.Set(key, value)
+	}
+
+	for i, entry := range t.History {
+		if err := sess.AddEntry(entry); err != nil {
+			store.Delete(ctx, sess.ID())
+			return nil, fmt.Errorf("failed to import entry %d: %w", i, err)
+		}
+	}
+
+	if err := store.Save(ctx, sess); err != nil {
+		store.Delete(ctx, sess.ID())
+		return nil, fmt.Errorf("failed to save imported session: %w", err)
+	}
+
+	return sess, nil
+}
+
+// Encode writes the transcript as indented JSON
+func (t *Transcript) Encode(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// Decode reads a transcript written by Encode and checks its version
+func Decode(r io.Reader) (*Transcript, error) {
+	var t Transcript
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to decode transcript: %w", err)
+	}
+
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// validate checks that the transcript can be read by this version
+func (t *Transcript) validate() error {
+	if t.Version < 1 || t.Version > Version {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, t.Version)
+	}
+	return nil
+}
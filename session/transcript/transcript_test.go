@@ -0,0 +1,216 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidleitw/go-agent/session"
+	"github.com/davidleitw/go-agent/session/memory"
+)
+
+func newTestSession(t *testing.T, store session.SessionStore) session.Session {
+	t.Helper()
+
+	sess := store.Create(context.Background(), session.WithMetadata("user", "alice"))
+	sess.Set("counter", 3)
+	sess.Set("topic", "weather")
+
+	entries := []session.Entry{
+		session.NewMessageEntry("user", "What's the weather in Taipei?"),
+		session.NewToolCallEntry("weather", map[string]any{"city": "Taipei"}),
+		session.NewToolResultEntry("weather", map[string]any{"temp": 28}, nil),
+		session.NewMessageEntry("assistant", "It is 28°C in Taipei."),
+	}
+	for _, entry := range entries {
+		if err := sess.AddEntry(entry); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	return sess
+}
+
+func TestExport(t *testing.T) {
+	store := memory.NewStore()
+	defer store.Close()
+
+	sess := newTestSession(t, store)
+
+	tr, err := Export(sess)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	if tr.Version != Version {
+		t.Errorf("Expected version %d, got %d", Version, tr.Version)
+	}
+	if tr.SessionID != sess.ID() {
+		t.Errorf("Expected session ID %s, got %s", sess.ID(), tr.SessionID)
+	}
+	if tr.Metadata["user"] != "alice" {
+		t.Errorf("Expected metadata user=alice, got %v", tr.Metadata)
+	}
+	if tr.State["topic"] != "weather" {
+		t.Errorf("Expected state topic=weather, got %v", tr.State["topic"])
+	}
+	if len(tr.History) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(tr.History))
+	}
+
+	// History is oldest first
+	first, _ := session.GetMessageContent(tr.History[0])
+	if first.Text != "What's the weather in Taipei?" {
+		t.Errorf("Expected first entry to be the user message, got %q", first.Text)
+	}
+}
+
+func TestEncodeDecodeImport(t *testing.T) {
+	source := memory.NewStore()
+	defer source.Close()
+
+	sess := newTestSession(t, source)
+	tr, err := Export(sess)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tr.Encode(&buf); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	target := memory.NewStore()
+	defer target.Close()
+
+	imported, err := Import(context.Background(), target, decoded)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	if imported.ID() == sess.ID() {
+		t.Error("Expected imported session to get a new ID")
+	}
+
+	// JSON numbers come back as float64
+	if value, _ := imported.Get("counter"); value != float64(3) {
+		t.Errorf("Expected counter 3, got %v (%T)", value, value)
+	}
+
+	history := imported.GetHistory(0)
+	if len(history) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(history))
+	}
+
+	// Newest first again once replayed into a session
+	last, ok := session.GetMessageContent(history[0])
+	if !ok || last.Text != "It is 28°C in Taipei." {
+		t.Errorf("Expected newest entry to be the assistant message, got %+v", history[0].Content)
+	}
+
+	call, ok := session.GetToolCallContent(history[2])
+	if !ok || call.Parameters["city"] != "Taipei" {
+		t.Errorf("Expected typed tool call content, got %+v", history[2].Content)
+	}
+
+	reloaded, err := target.Get(context.Background(), imported.ID())
+	if err != nil {
+		t.Fatalf("Failed to get imported session: %v", err)
+	}
+	exported, _ := Export(reloaded)
+	if exported.Metadata["user"] != "alice" {
+		t.Errorf("Expected metadata to be copied, got %v", exported.Metadata)
+	}
+	if exported.Metadata[MetadataImportedFrom] != sess.ID() {
+		t.Errorf("Expected %s=%s, got %v", MetadataImportedFrom, sess.ID(), exported.Metadata)
+	}
+}
+
+func TestImport_KeepID(t *testing.T) {
+	store := memory.NewStore()
+	defer store.Close()
+
+	tr := &Transcript{Version: Version, SessionID: "original"}
+
+	imported, err := Import(context.Background(), store, tr, session.WithID("original"))
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	if imported.ID() != "original" {
+		t.Errorf("Expected ID original, got %s", imported.ID())
+	}
+}
+
+// failingStore creates sessions that reject the second entry added
+type failingStore struct {
+	*memory.Store
+}
+
+func (s failingStore) Create(ctx context.Context, opts ...session.CreateOption) session.Session {
+	return &failingSession{Session: s.Store.Create(ctx, opts...)}
+}
+
+type failingSession struct {
+	session.Session
+	added int
+}
+
+func (s *failingSession) AddEntry(entry session.Entry) error {
+	if s.added++; s.added > 1 {
+		return errors.New("disk full")
+	}
+	return s.Session.AddEntry(entry)
+}
+
+func TestImport_FailureDeletesSession(t *testing.T) {
+	store := memory.NewStore()
+	defer store.Close()
+
+	tr, err := Export(newTestSession(t, store))
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	_, err = Import(context.Background(), failingStore{store}, tr, session.WithID("partial"))
+	if err == nil {
+		t.Fatal("Expected import to fail")
+	}
+	if _, err := store.Get(context.Background(), "partial"); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Expected the partial session to be deleted, got %v", err)
+	}
+}
+
+func TestDecode_UnsupportedVersion(t *testing.T) {
+	_, err := Decode(strings.NewReader(`{"version": 99}`))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	_, err = Decode(strings.NewReader(`{}`))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion for missing version, got %v", err)
+	}
+}
+
+// opaqueSession hides the StateReader implementation of the wrapped session
+type opaqueSession struct {
+	session.Session
+}
+
+func TestExport_StateNotReadable(t *testing.T) {
+	store := memory.NewStore()
+	defer store.Close()
+
+	_, err := Export(opaqueSession{store.Create(context.Background())})
+	if !errors.Is(err, ErrStateNotReadable) {
+		t.Errorf("Expected ErrStateNotReadable, got %v", err)
+	}
+}
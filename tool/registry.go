@@ -55,7 +55,7 @@ func (r *Registry) Execute(ctx context.Context, call Call) (any, error) {
 	}
 
 	// Execute the tool
-	result, err := tool.Execute(ctx, params)
+	result, err := tool.Execute(WithCallID(ctx, call.ID), params)
 	if err != nil {
 		return nil, fmt.Errorf("tool execution failed: %w", err)
 	}
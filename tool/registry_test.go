@@ -64,11 +64,13 @@ func TestRegistry_Execute(t *testing.T) {
 
 	// Register a test tool
 	executed := false
+	var callID string
 	tool := &mockTool{
 		name:        "calculator",
 		description: "Performs calculations",
 		execute: func(ctx context.Context, params map[string]any) (any, error) {
 			executed = true
+			callID, _ = CallID(ctx)
 			if op, ok := params["operation"].(string); ok && op == "add" {
 				return 42, nil
 			}
@@ -96,6 +98,9 @@ func TestRegistry_Execute(t *testing.T) {
 	if result != 42 {
 		t.Errorf("Expected result 42, got %v", result)
 	}
+	if callID != "test-call-1" {
+		t.Errorf("Expected call ID test-call-1 in context, got %q", callID)
+	}
 
 	// Test tool not found
 	notFoundCall := Call{
@@ -11,6 +11,23 @@ type Tool interface {
 	Execute(ctx context.Context, params map[string]any) (any, error)
 }
 
+// callIDKey is the context key of the ID of the tool call being executed
+type callIDKey struct{}
+
+// WithCallID returns a context carrying the ID of the tool call being executed
+func WithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+// CallID returns the ID of the tool call being executed, as set by
+// Registry.Execute. A call that is run again, for example when an agent
+// resumes from a checkpoint, has the same ID, so tools with side effects
+// can use it to deduplicate.
+func CallID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(callIDKey{}).(string)
+	return id, ok && id != ""
+}
+
 // TODO: Future enhancements
 // - Add validation for input parameters
 // - Support for output schema validation (optional)